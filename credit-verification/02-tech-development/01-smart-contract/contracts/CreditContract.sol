@@ -24,6 +24,20 @@ contract CreditContract {
     mapping(string => uint256[]) public studentCreditIds;
     uint256 public nextCreditId;
 
+    // 驳回状态（独立映射，不改 Credit 结构体以保持 getCreditById 返回值兼容）
+    mapping(uint256 => bool) public isRejected;
+    mapping(uint256 => bytes32) public rejectReasonHash; // 驳回原因原文的 keccak256，原文存后端数据库
+
+    // 撤销状态：已审核学分事后发现造假或录入错误时撤销，isApproved 保持为 true 以保留审核历史
+    mapping(uint256 => bool) public isRevoked;
+    mapping(uint256 => bytes32) public revokeReasonHash; // 撤销理由原文的 keccak256
+
+    // 更正关系：原记录被新记录取代后不再参与审核、驳回、撤销，getStudentCredits 只返回当前版本
+    mapping(uint256 => bool) public isSuperseded;
+    mapping(uint256 => uint256) public supersededBy; // 原记录 -> 更正后的新记录
+    mapping(uint256 => bool) public isCorrection;
+    mapping(uint256 => uint256) public correctionOf; // 新记录 -> 被更正的原记录
+
     // 事件（保持原有）
     event CreditRecorded(
         uint256 indexed creditId, 
@@ -36,7 +50,23 @@ contract CreditContract {
         uint256 indexed creditId, 
         address indexed adminAddress
     );
+    event CreditRejected(
+        uint256 indexed creditId,
+        address indexed adminAddress,
+        bytes32 reasonHash
+    );
+    event CreditRevoked(
+        uint256 indexed creditId,
+        address indexed adminAddress,
+        bytes32 reasonHash
+    );
+    event CreditCorrected(
+        uint256 indexed originalId,
+        uint256 indexed newCreditId,
+        address indexed adminAddress
+    );
     event RoleAssigned(address indexed user, string indexed role);
+    event RoleRevoked(address indexed user, string indexed role);
 
     // 构造函数：部署者默认拥有所有权限
     constructor() {
@@ -74,6 +104,21 @@ contract CreditContract {
         }
     }
 
+    // 撤销角色（仅Owner可操作，只接受 teacher/admin，且该地址须已拥有该角色）
+    function revokeRole(address user, string calldata role) external onlyOwner {
+        if (keccak256(bytes(role)) == keccak256(bytes("teacher"))) {
+            require(isTeacher[user], "CreditContract: not a teacher");
+            isTeacher[user] = false;
+            emit RoleRevoked(user, "teacher");
+        } else if (keccak256(bytes(role)) == keccak256(bytes("admin"))) {
+            require(isAdmin[user], "CreditContract: not a admin");
+            isAdmin[user] = false;
+            emit RoleRevoked(user, "admin");
+        } else {
+            revert("CreditContract: invalid role");
+        }
+    }
+
     // 查询角色（适配你的原有接口）
     function getRole(address user) external view returns (string memory) {
         if (isTeacher[user]) return "teacher";
@@ -87,6 +132,15 @@ contract CreditContract {
         string calldata courseName,
         uint8 score
     ) external onlyTeacher {
+        _recordCredit(studentId, courseName, score, msg.sender);
+    }
+
+    function _recordCredit(
+        string memory studentId,
+        string memory courseName,
+        uint8 score,
+        address teacherAddress
+    ) internal returns (uint256) {
         require(score <= 100, "CreditContract: invalid score(0-100)");
         require(bytes(studentId).length > 0, "CreditContract: empty studentId");
         require(bytes(courseName).length > 0, "CreditContract: courseName empty");
@@ -97,38 +151,120 @@ contract CreditContract {
             studentId: studentId,
             courseName: courseName,
             score: score,
-            teacherAddress: msg.sender,
+            teacherAddress: teacherAddress,
             isApproved: false,
             exists: true 
         });
         nextCreditId++;
         studentCreditIds[studentId].push(creditId);
 
-        emit CreditRecorded(creditId, studentId, courseName, score, msg.sender);
+        emit CreditRecorded(creditId, studentId, courseName, score, teacherAddress);
+        return creditId;
     }
 
     // 审核学分（保留原有逻辑）
     function approveCredit(uint256 creditId) external onlyAdmin {
         require(credits[creditId].exists, "CreditContract: credit not exist");
         require(!credits[creditId].isApproved, "CreditContract: credit already approved");
+        require(!isRejected[creditId], "CreditContract: credit already rejected");
+        require(!isSuperseded[creditId], "CreditContract: credit superseded");
         credits[creditId].isApproved = true;
         emit CreditApproved(creditId, msg.sender);
     }
 
-    // 查询学生学分（保留原有逻辑）
+    // 驳回学分（与审核互斥，驳回后不可再审核）
+    function rejectCredit(uint256 creditId, bytes32 reasonHash) external onlyAdmin {
+        require(credits[creditId].exists, "CreditContract: credit not exist");
+        require(!credits[creditId].isApproved, "CreditContract: credit already approved");
+        require(!isRejected[creditId], "CreditContract: credit already rejected");
+        require(!isSuperseded[creditId], "CreditContract: credit superseded");
+        require(reasonHash != bytes32(0), "CreditContract: empty reason");
+        isRejected[creditId] = true;
+        rejectReasonHash[creditId] = reasonHash;
+        emit CreditRejected(creditId, msg.sender, reasonHash);
+    }
+
+    // 批量审核：不满足条件（不存在/已审核/已驳回）的学分跳过而不回滚整批，逐条触发 CreditApproved
+    function approveCredits(uint256[] calldata creditIds) external onlyAdmin {
+        require(creditIds.length > 0, "CreditContract: empty batch");
+        for (uint256 i = 0; i < creditIds.length; i++) {
+            uint256 creditId = creditIds[i];
+            if (!_isPending(creditId)) continue;
+            credits[creditId].isApproved = true;
+            emit CreditApproved(creditId, msg.sender);
+        }
+    }
+
+    // 批量驳回：整批共用同一原因哈希，跳过规则同 approveCredits，逐条触发 CreditRejected
+    function rejectCredits(uint256[] calldata creditIds, bytes32 reasonHash) external onlyAdmin {
+        require(creditIds.length > 0, "CreditContract: empty batch");
+        require(reasonHash != bytes32(0), "CreditContract: empty reason");
+        for (uint256 i = 0; i < creditIds.length; i++) {
+            uint256 creditId = creditIds[i];
+            if (!_isPending(creditId)) continue;
+            isRejected[creditId] = true;
+            rejectReasonHash[creditId] = reasonHash;
+            emit CreditRejected(creditId, msg.sender, reasonHash);
+        }
+    }
+
+    // 撤销已审核的学分（须附理由哈希，撤销后不可恢复）
+    function revokeCredit(uint256 creditId, bytes32 reasonHash) external onlyAdmin {
+        require(credits[creditId].exists, "CreditContract: credit not exist");
+        require(credits[creditId].isApproved, "CreditContract: credit not approved");
+        require(!isRevoked[creditId], "CreditContract: credit already revoked");
+        require(!isSuperseded[creditId], "CreditContract: credit superseded");
+        require(reasonHash != bytes32(0), "CreditContract: empty reason");
+        isRevoked[creditId] = true;
+        revokeReasonHash[creditId] = reasonHash;
+        emit CreditRevoked(creditId, msg.sender, reasonHash);
+    }
+
+    // 更正学分：以新的课程名/成绩生成一条新记录取代原记录（学生与录入教师沿用原记录），原记录已审核则新记录同样视为已审核
+    // 依次触发新记录的 CreditRecorded、（已审核时）CreditApproved 与 CreditCorrected
+    function supersedeCredit(
+        uint256 originalId,
+        string calldata courseName,
+        uint8 score
+    ) external onlyAdmin returns (uint256) {
+        Credit storage original = credits[originalId];
+        require(original.exists, "CreditContract: credit not exist");
+        require(!isSuperseded[originalId], "CreditContract: credit superseded");
+        require(!isRejected[originalId], "CreditContract: credit already rejected");
+        require(!isRevoked[originalId], "CreditContract: credit already revoked");
+
+        uint256 newId = _recordCredit(original.studentId, courseName, score, original.teacherAddress);
+        if (original.isApproved) {
+            credits[newId].isApproved = true;
+            emit CreditApproved(newId, msg.sender);
+        }
+        isSuperseded[originalId] = true;
+        supersededBy[originalId] = newId;
+        isCorrection[newId] = true;
+        correctionOf[newId] = originalId;
+        emit CreditCorrected(originalId, newId, msg.sender);
+        return newId;
+    }
+
+    // 学分存在且尚未审核、驳回或被更正
+    function _isPending(uint256 creditId) internal view returns (bool) {
+        return credits[creditId].exists && !credits[creditId].isApproved && !isRejected[creditId] && !isSuperseded[creditId];
+    }
+
+    // 查询学生学分（被更正的原记录不返回）
     function getStudentCredits(string calldata studentId) external view returns (Credit[] memory) {
         require(bytes(studentId).length > 0, "CreditContract: studentId empty");
         uint256[] memory ids = studentCreditIds[studentId];
         uint256 validCount = 0;
 
         for (uint256 i = 0; i < ids.length; i++) {
-            if (credits[ids[i]].exists) validCount++;
+            if (credits[ids[i]].exists && !isSuperseded[ids[i]]) validCount++;
         }
 
         Credit[] memory result = new Credit[](validCount);
         uint256 index = 0;
         for (uint256 i = 0; i < ids.length; i++) {
-            if (credits[ids[i]].exists) {
+            if (credits[ids[i]].exists && !isSuperseded[ids[i]]) {
                 result[index] = credits[ids[i]];
                 index++;
             }
@@ -71,4 +71,180 @@ describe("CreditContract", function () {
     expect(credits[0].courseName).to.equal("区块链原理");
     expect(credits[1].courseName).to.equal("Web3开发");
   });
+
+  it("Should allow owner to revoke roles", async function () {
+    await expect(creditContract.revokeRole(teacher.address, "teacher"))
+      .to.emit(creditContract, "RoleRevoked");
+    expect(await creditContract.isTeacher(teacher.address)).to.be.false;
+    expect(await creditContract.getRole(teacher.address)).to.equal("");
+
+    await expect(
+      creditContract.connect(teacher).recordCredit("20230001", "高数", 80)
+    ).to.be.revertedWith("CreditContract: not a teacher");
+
+    await creditContract.revokeRole(admin.address, "admin");
+    expect(await creditContract.isAdmin(admin.address)).to.be.false;
+  });
+
+  it("Should reject invalid revocations", async function () {
+    await expect(
+      creditContract.connect(admin).revokeRole(teacher.address, "teacher")
+    ).to.be.revertedWith("CreditContract: only owner");
+    await expect(
+      creditContract.revokeRole(student.address, "teacher")
+    ).to.be.revertedWith("CreditContract: not a teacher");
+    await expect(
+      creditContract.revokeRole(teacher.address, "student")
+    ).to.be.revertedWith("CreditContract: invalid role");
+  });
+
+  it("Should allow admin to reject credit with reason hash", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 90);
+    const reasonHash = ethers.utils.keccak256(ethers.utils.toUtf8Bytes("成绩单与系统不符"));
+
+    await expect(creditContract.connect(admin).rejectCredit(0, reasonHash))
+      .to.emit(creditContract, "CreditRejected")
+      .withArgs(0, admin.address, reasonHash);
+    expect(await creditContract.isRejected(0)).to.be.true;
+    expect(await creditContract.rejectReasonHash(0)).to.equal(reasonHash);
+
+    await expect(
+      creditContract.connect(admin).approveCredit(0)
+    ).to.be.revertedWith("CreditContract: credit already rejected");
+    await expect(
+      creditContract.connect(admin).rejectCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: credit already rejected");
+  });
+
+  it("Should reject invalid rejections", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 90);
+    const reasonHash = ethers.utils.keccak256(ethers.utils.toUtf8Bytes("重复录入"));
+
+    await expect(
+      creditContract.connect(teacher).rejectCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: not a admin");
+    await expect(
+      creditContract.connect(admin).rejectCredit(0, ethers.constants.HashZero)
+    ).to.be.revertedWith("CreditContract: empty reason");
+
+    await creditContract.connect(admin).approveCredit(0);
+    await expect(
+      creditContract.connect(admin).rejectCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: credit already approved");
+  });
+
+  it("Should batch approve and skip non-pending credits", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 90);
+    await creditContract.connect(teacher).recordCredit("20230001", "Web3开发", 85);
+    await creditContract.connect(teacher).recordCredit("20230002", "高数", 70);
+    await creditContract.connect(admin).approveCredit(1);
+
+    const tx = await creditContract.connect(admin).approveCredits([0, 1, 2, 99]);
+    const receipt = await tx.wait();
+    const approved = receipt.events
+      .filter((e) => e.event === "CreditApproved")
+      .map((e) => e.args.creditId.toNumber());
+    expect(approved).to.deep.equal([0, 2]);
+    expect((await creditContract.getCreditById(2)).isApproved).to.be.true;
+
+    await expect(
+      creditContract.connect(admin).approveCredits([])
+    ).to.be.revertedWith("CreditContract: empty batch");
+    await expect(
+      creditContract.connect(teacher).approveCredits([0])
+    ).to.be.revertedWith("CreditContract: not a admin");
+  });
+
+  it("Should batch reject with a shared reason hash", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 90);
+    await creditContract.connect(teacher).recordCredit("20230001", "Web3开发", 85);
+    await creditContract.connect(admin).approveCredit(0);
+    const reasonHash = ethers.utils.keccak256(ethers.utils.toUtf8Bytes("课程未开设"));
+
+    await expect(creditContract.connect(admin).rejectCredits([0, 1], reasonHash))
+      .to.emit(creditContract, "CreditRejected")
+      .withArgs(1, admin.address, reasonHash);
+    expect(await creditContract.isRejected(0)).to.be.false;
+    expect(await creditContract.isRejected(1)).to.be.true;
+    expect(await creditContract.rejectReasonHash(1)).to.equal(reasonHash);
+
+    await expect(
+      creditContract.connect(admin).rejectCredits([1], ethers.constants.HashZero)
+    ).to.be.revertedWith("CreditContract: empty reason");
+  });
+
+  it("Should allow admin to revoke approved credit with reason hash", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 90);
+    const reasonHash = ethers.utils.keccak256(ethers.utils.toUtf8Bytes("成绩造假"));
+
+    await expect(
+      creditContract.connect(admin).revokeCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: credit not approved");
+
+    await creditContract.connect(admin).approveCredit(0);
+    await expect(
+      creditContract.connect(teacher).revokeCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: not a admin");
+    await expect(
+      creditContract.connect(admin).revokeCredit(0, ethers.constants.HashZero)
+    ).to.be.revertedWith("CreditContract: empty reason");
+
+    await expect(creditContract.connect(admin).revokeCredit(0, reasonHash))
+      .to.emit(creditContract, "CreditRevoked")
+      .withArgs(0, admin.address, reasonHash);
+    expect(await creditContract.isRevoked(0)).to.be.true;
+    expect(await creditContract.revokeReasonHash(0)).to.equal(reasonHash);
+    expect((await creditContract.getCreditById(0)).isApproved).to.be.true;
+
+    await expect(
+      creditContract.connect(admin).revokeCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: credit already revoked");
+  });
+
+  it("Should supersede credit with a linked correction record", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "区块链原理", 59);
+    await creditContract.connect(admin).approveCredit(0);
+
+    await expect(
+      creditContract.connect(teacher).supersedeCredit(0, "区块链原理", 95)
+    ).to.be.revertedWith("CreditContract: not a admin");
+
+    await expect(creditContract.connect(admin).supersedeCredit(0, "区块链原理", 95))
+      .to.emit(creditContract, "CreditCorrected")
+      .withArgs(0, 1, admin.address);
+
+    const corrected = await creditContract.getCreditById(1);
+    expect(corrected.score).to.equal(95);
+    expect(corrected.studentId).to.equal("20230001");
+    expect(corrected.teacherAddress).to.equal(teacher.address);
+    expect(corrected.isApproved).to.be.true;
+    expect(await creditContract.isSuperseded(0)).to.be.true;
+    expect(await creditContract.supersededBy(0)).to.equal(1);
+    expect(await creditContract.isCorrection(1)).to.be.true;
+    expect(await creditContract.correctionOf(1)).to.equal(0);
+
+    const credits = await creditContract.getStudentCredits("20230001");
+    expect(credits.length).to.equal(1);
+    expect(credits[0].creditId).to.equal(1);
+
+    await expect(
+      creditContract.connect(admin).supersedeCredit(0, "区块链原理", 90)
+    ).to.be.revertedWith("CreditContract: credit superseded");
+    const reasonHash = ethers.utils.keccak256(ethers.utils.toUtf8Bytes("成绩造假"));
+    await expect(
+      creditContract.connect(admin).revokeCredit(0, reasonHash)
+    ).to.be.revertedWith("CreditContract: credit superseded");
+  });
+
+  it("Should keep pending state when superseding an unapproved credit", async function () {
+    await creditContract.connect(teacher).recordCredit("20230001", "高数", 80);
+    await creditContract.connect(admin).supersedeCredit(0, "高等数学", 80);
+
+    expect((await creditContract.getCreditById(1)).isApproved).to.be.false;
+    expect((await creditContract.getCreditById(1)).courseName).to.equal("高等数学");
+    await expect(
+      creditContract.connect(admin).approveCredit(0)
+    ).to.be.revertedWith("CreditContract: credit superseded");
+    await creditContract.connect(admin).approveCredit(1);
+  });
 });
@@ -0,0 +1,91 @@
+// cmd/gen-jwt-key 生成 JWT 签名私钥（PKCS#8 PEM），写入 <dir>/<kid>.pem：
+//
+//	go run ./cmd/gen-jwt-key -alg EdDSA -kid 2026-10           # 默认目录 ./config/jwt-keys
+//	go run ./cmd/gen-jwt-key -alg RS256 -kid 2026-10-rsa -bits 3072
+//
+// 生成后把 jwt.active_kid 改为新 kid；旧密钥可用 -retire 导出为 <kid>.pub.pem 并删除私钥，已签发的 Token 仍可验证至过期
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func main() {
+	alg := flag.String("alg", "EdDSA", "签名算法：EdDSA 或 RS256")
+	kid := flag.String("kid", time.Now().Format("2006-01-02"), "密钥ID（文件名）")
+	dir := flag.String("dir", "./config/jwt-keys", "密钥目录")
+	bits := flag.Int("bits", 2048, "RSA 密钥长度")
+	retire := flag.Bool("retire", false, "退役 -kid 对应私钥：导出公钥为 <kid>.pub.pem 并删除私钥")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatalf("创建目录失败: %v", err)
+	}
+	privPath := filepath.Join(*dir, *kid+".pem")
+
+	if *retire {
+		retireKey(privPath, filepath.Join(*dir, *kid+".pub.pem"))
+		return
+	}
+
+	if _, err := os.Stat(privPath); err == nil {
+		log.Fatalf("%s 已存在", privPath)
+	}
+	var key crypto.Signer
+	var err error
+	switch *alg {
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, *bits)
+	default:
+		log.Fatalf("不支持的算法: %s", *alg)
+	}
+	if err != nil {
+		log.Fatalf("生成密钥失败: %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatalf("编码私钥失败: %v", err)
+	}
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		log.Fatalf("写入私钥失败: %v", err)
+	}
+	log.Printf("已生成 %s（%s），请将 jwt.active_kid 设为 %s", privPath, *alg, *kid)
+}
+
+func retireKey(privPath, pubPath string) {
+	data, err := os.ReadFile(privPath)
+	if err != nil {
+		log.Fatalf("读取私钥失败: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		log.Fatalf("%s 不是 PEM 文件", privPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		log.Fatalf("解析私钥失败: %v", err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.(crypto.Signer).Public())
+	if err != nil {
+		log.Fatalf("编码公钥失败: %v", err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o644); err != nil {
+		log.Fatalf("写入公钥失败: %v", err)
+	}
+	if err := os.Remove(privPath); err != nil {
+		log.Fatalf("删除私钥失败: %v", err)
+	}
+	log.Printf("已退役：%s -> %s", privPath, pubPath)
+}
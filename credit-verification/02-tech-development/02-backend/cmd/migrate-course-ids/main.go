@@ -0,0 +1,171 @@
+// cmd/migrate-course-ids 一次性迁移命令：把历史学分的自由填写 course_name 映射到课程目录，写入 credits.course_id
+// 按规范化后的值（去首尾空白、合并连续空白、不区分大小写）与课程代码或课程名称精确比对：
+// 唯一命中为 matched，命中多门为 ambiguous，无命中为 unmatched；后两类须人工确认后写入 -map 文件再次运行。
+// course_name 是链上原值，迁移只补 course_id，不改写。需在 02-backend 目录下运行（读取 ./config/config.yaml）：
+//
+//	go run ./cmd/migrate-course-ids                                   # 仅打印映射结果
+//	go run ./cmd/migrate-course-ids -report review.csv                # 另输出审核报告（course_name,count,result,candidates）
+//	go run ./cmd/migrate-course-ids -map manual.csv -apply            # 人工映射（course_name,course_code）优先，写回数据库
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+)
+
+// 映射结果
+const (
+	resultMatched   = "matched"
+	resultManual    = "manual"
+	resultAmbiguous = "ambiguous"
+	resultUnmatched = "unmatched"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "写回数据库（默认仅打印映射结果）")
+	mapFile := flag.String("map", "", "人工映射 CSV：每行 course_name,course_code")
+	reportFile := flag.String("report", "", "审核报告输出路径（CSV）")
+	flag.Parse()
+
+	utils.InitConfig()
+	utils.InitMySQL()
+
+	courses, err := model.ListCourses("", "")
+	if err != nil {
+		log.Fatalf("查询课程目录失败: %v", err)
+	}
+	if len(courses) == 0 {
+		log.Fatalf("课程目录为空，请先通过 /api/courses 录入课程")
+	}
+	byKey := make(map[string][]model.Course)
+	byCode := make(map[string]model.Course)
+	for _, c := range courses {
+		byCode[c.Code] = c
+		byKey[normalize(c.Code)] = append(byKey[normalize(c.Code)], c)
+		if key := normalize(c.Name); key != normalize(c.Code) {
+			byKey[key] = append(byKey[key], c)
+		}
+	}
+
+	manual := make(map[string]model.Course)
+	if *mapFile != "" {
+		manual, err = loadManualMap(*mapFile, byCode)
+		if err != nil {
+			log.Fatalf("读取人工映射失败: %v", err)
+		}
+	}
+
+	names, err := model.ListUnmappedCourseNames()
+	if err != nil {
+		log.Fatalf("查询学分记录失败: %v", err)
+	}
+
+	var report [][]string
+	mapping := make(map[string]int64)
+	counts := make(map[string]int)
+	for _, n := range names {
+		result, candidates := resultUnmatched, dedupe(byKey[normalize(n.CourseName)])
+		if c, ok := manual[normalize(n.CourseName)]; ok {
+			result, candidates = resultManual, []model.Course{c}
+		} else if len(candidates) == 1 {
+			result = resultMatched
+		} else if len(candidates) > 1 {
+			result = resultAmbiguous
+		}
+		if result == resultMatched || result == resultManual {
+			mapping[n.CourseName] = candidates[0].Id
+		}
+		counts[result]++
+
+		codes := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			codes = append(codes, c.Code)
+		}
+		log.Printf("%-10s %q (%d 条) -> %s", result, n.CourseName, n.Count, strings.Join(codes, " / "))
+		report = append(report, []string{n.CourseName, strconv.FormatInt(n.Count, 10), result, strings.Join(codes, ";")})
+	}
+	log.Printf("共 %d 个课程名：自动匹配 %d，人工映射 %d，多义 %d，未匹配 %d",
+		len(names), counts[resultMatched], counts[resultManual], counts[resultAmbiguous], counts[resultUnmatched])
+
+	if *reportFile != "" {
+		if err := writeReport(*reportFile, report); err != nil {
+			log.Fatalf("写审核报告失败: %v", err)
+		}
+		log.Printf("审核报告已写入 %s", *reportFile)
+	}
+
+	if !*apply || len(mapping) == 0 {
+		return
+	}
+	n, err := model.MapCreditCourses(mapping)
+	if err != nil {
+		log.Fatalf("写回失败: %v", err)
+	}
+	log.Printf("已为 %d 条学分记录写入 course_id", n)
+}
+
+// normalize 去首尾空白、合并连续空白并转小写
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+func dedupe(list []model.Course) []model.Course {
+	seen := make(map[int64]bool)
+	var out []model.Course
+	for _, c := range list {
+		if !seen[c.Id] {
+			seen[c.Id] = true
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// loadManualMap 读取人工映射（course_name,course_code），课程代码须在目录中
+func loadManualMap(path string, byCode map[string]model.Course) (map[string]model.Course, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 2
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	manual := make(map[string]model.Course)
+	for i, rec := range records {
+		code := strings.ToUpper(strings.TrimSpace(rec[1]))
+		c, ok := byCode[code]
+		if !ok {
+			return nil, fmt.Errorf("第 %d 行: 课程代码 %s 不在课程目录中", i+1, code)
+		}
+		manual[normalize(rec[0])] = c
+	}
+	return manual, nil
+}
+
+func writeReport(path string, rows [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	if err := w.Write([]string{"course_name", "count", "result", "candidates"}); err != nil {
+		return err
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,93 @@
+// cmd/repair-credit-ids 一次性修复命令：按 credits.tx_hash 回查交易回执，从 CreditRecorded 事件重新推导 contract_credit_id
+// 用于修正旧版「取学生最大链上ID」逻辑在并发录入时关联错的记录。需在 02-backend 目录下运行（读取 ./config/config.yaml）：
+//
+//	go run ./cmd/repair-credit-ids            # 仅打印差异与冲突
+//	go run ./cmd/repair-credit-ids -apply     # 写回数据库（冲突的记录逐条报告并跳过，其余照常写回）
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"sort"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func main() {
+	apply := flag.Bool("apply", false, "写回数据库（默认仅打印差异）")
+	flag.Parse()
+
+	utils.InitConfig()
+	utils.InitMySQL()
+	utils.InitEthClient()
+
+	rows, err := model.GetCreditsWithTxHash()
+	if err != nil {
+		log.Fatalf("查询学分记录失败: %v", err)
+	}
+
+	links := make(map[int64]int64)
+	var unchanged, failed int
+	for _, row := range rows {
+		receipt, err := utils.EthClient.TransactionReceipt(context.Background(), common.HexToHash(row.TxHash.String))
+		if err != nil {
+			log.Printf("记录 %d: 获取回执失败 (%s): %v", row.Id, row.TxHash.String, err)
+			failed++
+			continue
+		}
+		chainId, err := utils.ParseCreditRecordedId(receipt)
+		if err != nil {
+			log.Printf("记录 %d: %v", row.Id, err)
+			failed++
+			continue
+		}
+		if row.ContractCreditId.Valid && row.ContractCreditId.Int64 == int64(chainId) {
+			unchanged++
+			continue
+		}
+		if row.ContractCreditId.Valid {
+			log.Printf("记录 %d: contract_credit_id %d -> %d", row.Id, row.ContractCreditId.Int64, chainId)
+		} else {
+			log.Printf("记录 %d: contract_credit_id NULL -> %d", row.Id, chainId)
+		}
+		links[row.Id] = int64(chainId)
+	}
+	log.Printf("共 %d 条：需修复 %d，无需修改 %d，失败 %d", len(rows), len(links), unchanged, failed)
+
+	if len(links) == 0 {
+		return
+	}
+	if !*apply {
+		conflicts, err := model.ContractCreditIdConflicts(links)
+		if err != nil {
+			log.Fatalf("检查冲突失败: %v", err)
+		}
+		printConflicts(conflicts)
+		return
+	}
+	conflicts, err := model.RelinkContractCreditIds(links)
+	if err != nil {
+		log.Fatalf("写回失败: %v", err)
+	}
+	printConflicts(conflicts)
+	log.Printf("已写回 %d 条，冲突跳过 %d 条", len(links)-len(conflicts), len(conflicts))
+}
+
+// printConflicts 逐条打印会违反唯一键的记录（写回时跳过，需人工处理占用方后重跑）
+func printConflicts(conflicts map[int64]string) {
+	ids := make([]int64, 0, len(conflicts))
+	for id := range conflicts {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		log.Printf("记录 %d: 冲突，跳过 — %s", id, conflicts[id])
+	}
+	if len(ids) > 0 {
+		log.Printf("冲突 %d 条：处理占用方（或一并纳入修复）后重跑", len(ids))
+	}
+}
@@ -0,0 +1,370 @@
+CREATE DATABASE IF NOT EXISTS credit_dapp DEFAULT CHARSET utf8mb4;
+USE credit_dapp;
+
+-- 学分表（链上数据同步）
+CREATE TABLE IF NOT EXISTS credits (
+    id BIGINT AUTO_INCREMENT PRIMARY KEY,
+    contract_credit_id BIGINT COMMENT '链上学分ID（合约 creditId）',
+    student_address VARCHAR(64) NOT NULL COMMENT '学生地址',
+    teacher_address VARCHAR(64) NOT NULL COMMENT '录入教师地址',
+    course_id BIGINT COMMENT '课程目录主键（courses.id），迁移前的历史记录可能为空',
+    course_name VARCHAR(128) NOT NULL COMMENT '链上 courseName：课程代码（历史记录为自由填写的课程名）',
+    score DECIMAL(5,2) NOT NULL COMMENT '分数',
+    term_id BIGINT COMMENT '所属学期（terms.id），链上直接录入的记录为空',
+    status VARCHAR(32) NOT NULL COMMENT '状态：pending/approved/rejected/revoked/failed',
+    tx_hash VARCHAR(66) COMMENT '链上交易哈希',
+    audit_admin VARCHAR(64) COMMENT '审核管理员地址',
+    audit_time DATETIME COMMENT '审核时间',
+    reject_tx_hash VARCHAR(66) COMMENT '链上 rejectCredit 交易哈希',
+    reject_reason VARCHAR(256) COMMENT '驳回原因/撤销理由原文（链上存其 keccak256）',
+    revoke_tx_hash VARCHAR(66) COMMENT '链上 revokeCredit 交易哈希',
+    revoke_reason VARCHAR(256) COMMENT '撤销理由原文（链上存其 keccak256）',
+    revoked_by VARCHAR(64) COMMENT '撤销管理员地址',
+    revoked_at DATETIME COMMENT '撤销时间',
+    supersedes BIGINT COMMENT '本记录更正自哪条记录（credits.id）',
+    superseded_by BIGINT COMMENT '本记录被哪条更正记录取代（credits.id），非空即为历史版本',
+    locked TINYINT(1) NOT NULL DEFAULT 0 COMMENT '所属学期已关闭，不可再审核/驳回/撤销/更正',
+    chain_block BIGINT UNSIGNED COMMENT '索引器建档或关联链上ID时事件所在区块，分叉回退时据此解除关联',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    UNIQUE KEY uk_contract_credit_id (contract_credit_id),
+    KEY idx_student (student_address),
+    KEY idx_teacher (teacher_address),
+    KEY idx_status (status),
+    KEY idx_course_id (course_id),
+    KEY idx_term_id (term_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- 1. 用户表（存储登录账号、密码、基础信息）
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT COMMENT '用户ID',
+  `username` varchar(50) NOT NULL COMMENT '登录账号（唯一）',
+  `password` varchar(100) NOT NULL COMMENT '加密密码（bcrypt）', -- 关键字段：password
+  `address` varchar(64) DEFAULT NULL COMMENT '以太坊地址（关联合约角色）',
+  `role` varchar(20) NOT NULL DEFAULT 'student' COMMENT '本地角色（teacher/admin/student）',
+  `merged_into` bigint unsigned DEFAULT NULL COMMENT 'wallet_ 用户被合并到的账号ID',
+  `token_version` int unsigned NOT NULL DEFAULT 0 COMMENT 'Token 版本，改角色/改密码/全部登出时递增',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_username` (`username`),
+  UNIQUE KEY `idx_address` (`address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='用户基础信息表';
+
+-- 2. 接口访问日志表（可选，用于调试）
+CREATE TABLE IF NOT EXISTS `access_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned DEFAULT NULL,
+  `path` varchar(100) NOT NULL,
+  `method` varchar(10) NOT NULL,
+  `ip` varchar(32) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='接口访问日志';
+
+-- 3. 链上事件索引游标（索引器断点续传）
+CREATE TABLE IF NOT EXISTS `chain_cursors` (
+  `name` varchar(64) NOT NULL COMMENT '游标名（如 credit_contract）',
+  `block_number` bigint unsigned NOT NULL COMMENT '已处理到的区块号',
+  `block_hash` varchar(66) NOT NULL COMMENT '该区块哈希，用于检测分叉',
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='链上事件索引游标';
+-- 索引器按 contract_credit_id 幂等写入 credits，依赖其唯一键（加唯一键前先清理重复的 contract_credit_id）
+-- 已有库升级：ALTER TABLE credits ADD UNIQUE KEY uk_contract_credit_id (contract_credit_id);
+-- 分叉回退：游标区块哈希不一致时，索引器撤销 credits.chain_block / credit_events.block_number 不早于回退区块的关联与状态流转，再按新链重扫
+-- 已有库升级：ALTER TABLE credits ADD COLUMN chain_block BIGINT UNSIGNED COMMENT '索引器建档或关联链上ID时事件所在区块，分叉回退时据此解除关联' AFTER locked;
+-- 已有库升级：ALTER TABLE credit_events ADD COLUMN block_number BIGINT UNSIGNED DEFAULT NULL COMMENT '索引器确认的链上事件所在区块，分叉回退时撤销该区块及之后的事件' AFTER tx_hash, ADD KEY idx_block_number (block_number);
+
+-- 4. 链上交易台账（后端发出的每笔交易及回执状态）
+CREATE TABLE IF NOT EXISTS `chain_transactions` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `tx_hash` varchar(66) NOT NULL COMMENT '交易哈希',
+  `method` varchar(64) NOT NULL COMMENT '合约方法（assignRole/recordCredit/approveCredit 等）',
+  `args` text COMMENT '调用参数（JSON 数组）',
+  `sender` varchar(64) NOT NULL COMMENT '发送地址',
+  `nonce` bigint unsigned NOT NULL,
+  `gas_limit` bigint unsigned NOT NULL,
+  `gas_price` varchar(32) NOT NULL COMMENT 'gasPrice 或 maxFeePerGas（wei）',
+  `status` varchar(16) NOT NULL DEFAULT 'submitted' COMMENT 'submitted/mined/reverted/replaced/failed',
+  `block_number` bigint unsigned DEFAULT NULL COMMENT '打包区块号',
+  `gas_used` bigint unsigned DEFAULT NULL,
+  `replaced_by` varchar(66) DEFAULT NULL COMMENT '提价重发后的新交易哈希',
+  `error` varchar(256) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_tx_hash` (`tx_hash`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='链上交易台账';
+
+-- 5. 异步录入学分任务（交易提交后立即返回任务ID，打包后关联链上学分ID）
+CREATE TABLE IF NOT EXISTS `credit_jobs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `credit_id` bigint NOT NULL COMMENT 'credits 表主键',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'recordCredit 交易哈希（被提价替换后更新）',
+  `status` varchar(16) NOT NULL DEFAULT 'submitted' COMMENT 'submitted/linked/failed',
+  `contract_credit_id` bigint DEFAULT NULL COMMENT '关联到的链上学分ID',
+  `error` varchar(256) DEFAULT NULL,
+  `created_by` bigint unsigned NOT NULL COMMENT '发起录入的用户ID',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='异步录入学分任务';
+
+-- 6. 钱包登录 nonce（SIWE 一次性挑战，核销后不可重放）
+CREATE TABLE IF NOT EXISTS `auth_nonces` (
+  `nonce` varchar(32) NOT NULL,
+  `address` varchar(64) NOT NULL COMMENT '申请 nonce 的钱包地址（小写）',
+  `expires_at` datetime NOT NULL,
+  `used_at` datetime DEFAULT NULL COMMENT '核销时间，非空即已使用',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`nonce`),
+  KEY `idx_expires_at` (`expires_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='钱包登录 nonce';
+
+-- 7. 钱包地址绑定审计（每次绑定/解绑/合并一条）
+-- 已有库升级：ALTER TABLE users ADD COLUMN merged_into bigint unsigned DEFAULT NULL COMMENT 'wallet_ 用户被合并到的账号ID' AFTER role;
+CREATE TABLE IF NOT EXISTS `address_bind_logs` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL COMMENT '地址所属账号',
+  `address` varchar(64) NOT NULL,
+  `action` varchar(16) NOT NULL COMMENT 'bind/unbind/merge',
+  `related_user_id` bigint unsigned DEFAULT NULL COMMENT 'merge 时被合并的 wallet_ 用户ID',
+  `operator_id` bigint unsigned NOT NULL COMMENT '操作人',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_address` (`address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='钱包地址绑定审计';
+
+-- 8. 刷新 Token（仅存 SHA-256，每次刷新轮换；同一 family 中已轮换的 Token 被重用视为泄露，整族吊销）
+-- 已有库升级：ALTER TABLE users ADD COLUMN token_version int unsigned NOT NULL DEFAULT 0 COMMENT 'Token 版本，改角色/改密码/全部登出时递增' AFTER merged_into;
+CREATE TABLE IF NOT EXISTS `refresh_tokens` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL,
+  `token_hash` char(64) NOT NULL COMMENT '刷新 Token 的 SHA-256',
+  `family_id` varchar(64) NOT NULL COMMENT '同一次登录派生的 Token 共用',
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  `replaced_by` bigint unsigned DEFAULT NULL COMMENT '轮换后的新 Token ID',
+  `user_agent` varchar(255) DEFAULT NULL,
+  `ip` varchar(64) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_token_hash` (`token_hash`),
+  KEY `idx_user_id` (`user_id`),
+  KEY `idx_family_id` (`family_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='刷新 Token';
+
+-- 9. 角色与权限（内置角色 student/teacher/admin/teacher_admin/super_admin/auditor/credit_reviewer 由后端启动时写入）
+-- 已有库升级：链上同时持有教师与管理员权限位的用户此前被同步为 super_admin，应核对后改为 teacher_admin：
+--   UPDATE users SET role = 'teacher_admin', token_version = token_version + 1 WHERE role = 'super_admin' AND id NOT IN (<确认的超级管理员ID>);
+-- 首个 super_admin 只能直接改库设置：UPDATE users SET role = 'super_admin', token_version = token_version + 1 WHERE id = <用户ID>;
+-- 已有库升级：原 dept_admin（院系管理员）并不按院系限定审核范围，已更名为全校范围的 credit_reviewer，启动前执行：
+--   UPDATE roles SET name = 'credit_reviewer', description = '学分审核员（全校，不按院系限定）' WHERE name = 'dept_admin';
+--   UPDATE role_permissions SET role = 'credit_reviewer' WHERE role = 'dept_admin';
+--   UPDATE users SET role = 'credit_reviewer', token_version = token_version + 1 WHERE role = 'dept_admin';
+CREATE TABLE IF NOT EXISTS `roles` (
+  `name` varchar(20) NOT NULL COMMENT '角色名（users.role）',
+  `description` varchar(128) NOT NULL DEFAULT '',
+  `builtin` tinyint(1) NOT NULL DEFAULT 0 COMMENT '内置角色不可删除',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`name`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='角色定义';
+
+CREATE TABLE IF NOT EXISTS `role_permissions` (
+  `role` varchar(20) NOT NULL,
+  `permission` varchar(64) NOT NULL COMMENT '权限点，如 credit:record / credit:approve / role:assign，* 为全部',
+  PRIMARY KEY (`role`, `permission`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='角色权限';
+
+-- 10. 链上角色变更（assignRole / revokeRole 提交后登记，打包成功才在同一事务中写 users.role；撤销记录操作人与原因）
+-- 已有库升级：ALTER TABLE role_changes ADD COLUMN action varchar(16) NOT NULL DEFAULT 'assign' COMMENT 'assign/revoke' AFTER user_address;
+-- 已有库升级：ALTER TABLE role_changes ADD COLUMN reason varchar(256) DEFAULT NULL COMMENT '撤销原因' AFTER db_role;
+CREATE TABLE IF NOT EXISTS `role_changes` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `user_address` varchar(64) NOT NULL COMMENT '被分配/撤销角色的地址',
+  `action` varchar(16) NOT NULL DEFAULT 'assign' COMMENT 'assign/revoke',
+  `chain_role` varchar(20) NOT NULL COMMENT 'assignRole（teacher/admin/student）或 revokeRole（teacher/admin）的角色参数',
+  `db_role` varchar(20) DEFAULT NULL COMMENT '打包后 users.role 的最终取值（分配时已隐含该链上角色的本地角色保持不变，撤销时按剩余权限降级）',
+  `reason` varchar(256) DEFAULT NULL COMMENT '撤销原因',
+  `tx_hash` varchar(66) NOT NULL COMMENT 'assignRole/revokeRole 交易哈希（被提价替换后更新）',
+  `status` varchar(16) NOT NULL DEFAULT 'submitted' COMMENT 'submitted/applied/failed',
+  `operator_id` bigint unsigned NOT NULL COMMENT '操作人',
+  `error` varchar(256) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_tx_hash` (`tx_hash`),
+  KEY `idx_status` (`status`),
+  KEY `idx_user_address` (`user_address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='链上角色变更';
+
+-- 11. 学分链上驳回（合约 rejectCredit 记录原因哈希，原文存 credits.reject_reason）
+-- 已有库升级：ALTER TABLE credits ADD COLUMN reject_tx_hash VARCHAR(66) COMMENT '链上 rejectCredit 交易哈希' AFTER audit_time;
+-- 已有库升级：ALTER TABLE credits ADD COLUMN reject_reason VARCHAR(256) COMMENT '驳回原因原文（链上存其 keccak256）' AFTER reject_tx_hash;
+
+-- 12. 学分状态流转记录（录入/审核/驳回/撤销/更正/录入失败/分叉回退各追加一条，credits 只保存当前状态）
+CREATE TABLE IF NOT EXISTS `credit_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `credit_id` bigint NOT NULL COMMENT 'credits 表主键',
+  `event` varchar(16) NOT NULL COMMENT 'recorded/approved/rejected/revoked/corrected/failed/reorged',
+  `from_status` varchar(20) DEFAULT NULL COMMENT '流转前状态，录入时为空',
+  `to_status` varchar(20) NOT NULL COMMENT '流转后状态',
+  `actor_id` bigint unsigned DEFAULT NULL COMMENT '操作人用户ID，索引器/链上对账补记时为空',
+  `actor_address` varchar(64) DEFAULT NULL COMMENT '操作人钱包地址',
+  `comment` varchar(256) DEFAULT NULL COMMENT '审核意见/驳回原因/失败原因',
+  `tx_hash` varchar(66) DEFAULT NULL COMMENT '对应链上交易哈希',
+  `block_number` bigint unsigned DEFAULT NULL COMMENT '索引器确认的链上事件所在区块，分叉回退时撤销该区块及之后的事件',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_credit_id` (`credit_id`),
+  KEY `idx_block_number` (`block_number`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='学分状态流转记录';
+
+-- 13. 已审核学分撤销（合约 revokeCredit 记录理由哈希，原文存 credits.revoke_reason，状态置为 revoked）
+-- 已有库升级：ALTER TABLE credits ADD COLUMN revoke_tx_hash VARCHAR(66) COMMENT '链上 revokeCredit 交易哈希' AFTER reject_reason;
+-- 已有库升级：ALTER TABLE credits ADD COLUMN revoke_reason VARCHAR(256) COMMENT '撤销理由原文（链上存其 keccak256）' AFTER revoke_tx_hash;
+-- 已有库升级：ALTER TABLE credits ADD COLUMN revoked_by VARCHAR(64) COMMENT '撤销管理员地址' AFTER revoke_reason;
+-- 已有库升级：ALTER TABLE credits ADD COLUMN revoked_at DATETIME COMMENT '撤销时间' AFTER revoked_by;
+-- 已有库升级：INSERT IGNORE INTO role_permissions (role, permission) VALUES ('admin', 'credit:revoke');
+
+-- 14. 学分更正（教师申请、管理员同意后上链 supersedeCredit 生成新记录，credits.supersedes / superseded_by 双向关联，列表只显示当前版本）
+-- 已有库升级：ALTER TABLE credits ADD COLUMN supersedes BIGINT COMMENT '本记录更正自哪条记录（credits.id）' AFTER revoked_at;
+-- 已有库升级：ALTER TABLE credits ADD COLUMN superseded_by BIGINT COMMENT '本记录被哪条更正记录取代（credits.id），非空即为历史版本' AFTER supersedes;
+CREATE TABLE IF NOT EXISTS `credit_corrections` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `credit_id` bigint NOT NULL COMMENT '被更正的 credits.id',
+  `course_name` varchar(128) NOT NULL COMMENT '更正后的课程代码',
+  `score` decimal(5,2) NOT NULL COMMENT '更正后的成绩',
+  `reason` varchar(256) NOT NULL COMMENT '教师填写的更正原因',
+  `status` varchar(16) NOT NULL DEFAULT 'requested' COMMENT 'requested/submitted/applied/rejected/failed',
+  `requested_by` bigint unsigned NOT NULL COMMENT '申请教师',
+  `reviewed_by` bigint unsigned DEFAULT NULL COMMENT '审核管理员',
+  `review_comment` varchar(256) DEFAULT NULL COMMENT '审核意见',
+  `tx_hash` varchar(66) DEFAULT NULL COMMENT 'supersedeCredit 交易哈希（被提价替换后更新）',
+  `new_credit_id` bigint DEFAULT NULL COMMENT '更正后的新 credits.id',
+  `error` varchar(256) DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_credit_id` (`credit_id`),
+  KEY `idx_status` (`status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='学分更正申请';
+
+-- 15. 课程目录（录入学分选择课程，链上 courseName 写课程代码；历史记录用 go run ./cmd/migrate-course-ids 补 course_id）
+-- 已有库升级：ALTER TABLE credits ADD COLUMN course_id BIGINT COMMENT '课程目录主键（courses.id），迁移前的历史记录可能为空' AFTER teacher_address, ADD KEY idx_course_id (course_id);
+-- 已有库升级：INSERT IGNORE INTO role_permissions (role, permission) VALUES ('admin', 'course:manage');
+CREATE TABLE IF NOT EXISTS `courses` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `code` varchar(32) NOT NULL COMMENT '课程代码（上链值，不可修改）',
+  `name` varchar(128) NOT NULL COMMENT '课程名称',
+  `credit_hours` decimal(4,1) NOT NULL COMMENT '学分',
+  `department` varchar(64) NOT NULL COMMENT '开课院系',
+  `semester_offered` varchar(16) NOT NULL COMMENT '开课学期：spring/fall/summer/all',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_code` (`code`),
+  KEY `idx_department` (`department`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='课程目录';
+
+-- 16. 学期与成绩录入窗口（录入学分须选学期，窗口外需管理员开通补录；关闭学期后其学分 locked=1）
+-- 已有库升级：ALTER TABLE credits ADD COLUMN term_id BIGINT COMMENT '所属学期（terms.id），链上直接录入的记录为空' AFTER score, ADD KEY idx_term_id (term_id);
+-- 已有库升级：ALTER TABLE credits ADD COLUMN locked TINYINT(1) NOT NULL DEFAULT 0 COMMENT '所属学期已关闭，不可再审核/驳回/撤销/更正' AFTER superseded_by;
+-- 已有库升级（回填学期）：升级前的记录 term_id 为空，按录入日期落在哪个学期的起止日期内回填，并按学期状态锁定；
+--   学期日期有重叠时该记录可能匹配多个学期，回填前先检查。链上直接录入（不经后端）的记录仍为空，这类记录不参与学期锁定，也不能发起更正
+-- UPDATE credits c JOIN terms t ON DATE(c.created_at) BETWEEN t.start_date AND t.end_date
+--   SET c.term_id = t.id, c.locked = (t.status = 'closed') WHERE c.term_id IS NULL;
+-- 已有库升级：INSERT IGNORE INTO role_permissions (role, permission) VALUES ('admin', 'term:manage');
+CREATE TABLE IF NOT EXISTS `terms` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `code` varchar(32) NOT NULL COMMENT '学期代码，如 2026-FALL',
+  `name` varchar(64) NOT NULL COMMENT '学期名称',
+  `start_date` date NOT NULL COMMENT '学期开始日期',
+  `end_date` date NOT NULL COMMENT '学期结束日期',
+  `grading_start` date NOT NULL COMMENT '成绩录入窗口开始（含）',
+  `grading_end` date NOT NULL COMMENT '成绩录入窗口结束（含）',
+  `status` varchar(16) NOT NULL DEFAULT 'open' COMMENT 'open/closed',
+  `closed_by` bigint unsigned DEFAULT NULL COMMENT '关闭学期的管理员',
+  `closed_at` datetime DEFAULT NULL,
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_code` (`code`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='学期';
+
+CREATE TABLE IF NOT EXISTS `term_overrides` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `term_id` bigint NOT NULL COMMENT '学期',
+  `teacher_id` bigint unsigned NOT NULL COMMENT '获准补录的教师用户ID',
+  `reason` varchar(256) NOT NULL COMMENT '补录原因',
+  `granted_by` bigint unsigned NOT NULL COMMENT '开通的管理员',
+  `expires_at` datetime NOT NULL COMMENT '授权失效时间',
+  `revoked_at` datetime DEFAULT NULL COMMENT '提前收回时间',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_term_teacher` (`term_id`, `teacher_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='成绩录入窗口外补录授权';
+
+-- 17. 开课（学期 + 课程）的选课名单与任课安排：录入学分须教师任教且学生已选该开课（student_address 比较不区分大小写）
+-- 已有库升级：INSERT IGNORE INTO role_permissions (role, permission) VALUES ('admin', 'enrollment:manage');
+CREATE TABLE IF NOT EXISTS `enrollments` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `term_id` bigint NOT NULL COMMENT '学期',
+  `course_id` bigint NOT NULL COMMENT '课程',
+  `student_address` varchar(64) NOT NULL COMMENT '学生地址（与链上 studentId 一致）',
+  `imported_by` bigint unsigned NOT NULL COMMENT '导入的管理员',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_offering_student` (`term_id`, `course_id`, `student_address`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='选课名单';
+
+CREATE TABLE IF NOT EXISTS `teaching_assignments` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `term_id` bigint NOT NULL COMMENT '学期',
+  `course_id` bigint NOT NULL COMMENT '课程',
+  `teacher_id` bigint unsigned NOT NULL COMMENT '任课教师用户ID',
+  `imported_by` bigint unsigned NOT NULL COMMENT '导入的管理员',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uk_offering_teacher` (`term_id`, `course_id`, `teacher_id`),
+  KEY `idx_teacher` (`teacher_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='任课安排';
+
+-- 18. 本地角色设置审计（管理员经 /api/users/:id/role 为其他用户设置 users.role，不能改自己；拥有 * 的角色只能由超级管理员分配）
+CREATE TABLE IF NOT EXISTS `user_role_logs` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `user_id` bigint unsigned NOT NULL COMMENT '被设置的用户',
+  `old_role` varchar(20) NOT NULL COMMENT '原角色',
+  `new_role` varchar(20) NOT NULL COMMENT '新角色',
+  `reason` varchar(256) NOT NULL COMMENT '设置原因',
+  `operator_id` bigint unsigned NOT NULL COMMENT '操作的管理员',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_user_id` (`user_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='本地角色设置记录';
+
+-- 19. 学分审核交易登记（审核等交易广播后记为 submitted，回执成功后才写 credits 状态与学分事件；批量交易每条学分一行）
+CREATE TABLE IF NOT EXISTS `credit_audits` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `credit_id` bigint NOT NULL COMMENT 'credits 表主键',
+  `contract_credit_id` bigint NOT NULL COMMENT '链上学分ID，按回执事件逐条核对',
+  `action` varchar(16) NOT NULL COMMENT '审核动作（与学分事件一致）：approved/rejected/revoked',
+  `tx_hash` varchar(66) NOT NULL COMMENT '交易哈希（被提价替换后改记新哈希）',
+  `reason` varchar(256) DEFAULT NULL COMMENT '驳回原因/撤销理由原文（链上存其 keccak256）',
+  `comment` varchar(256) DEFAULT NULL COMMENT '审核意见',
+  `operator_id` bigint unsigned NOT NULL COMMENT '提交的管理员',
+  `operator_address` varchar(64) DEFAULT NULL COMMENT '管理员钱包地址',
+  `status` varchar(16) NOT NULL DEFAULT 'submitted' COMMENT 'submitted/applied/skipped/failed',
+  `error` varchar(256) DEFAULT NULL COMMENT '跳过或失败原因',
+  `created_at` datetime DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` datetime DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_status` (`status`),
+  KEY `idx_credit_id` (`credit_id`),
+  KEY `idx_tx_hash` (`tx_hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='学分审核交易登记';
@@ -0,0 +1,163 @@
+// controller/course_controller.go 课程目录：登录用户可查询，course:manage 维护
+package controller
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 课程代码：大写字母开头，2-32 位大写字母/数字/中划线/下划线（上链值，创建后不可修改）
+var courseCodePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_-]{1,31}$`)
+
+// CourseReq 新建/更新课程；更新时 code 忽略
+type CourseReq struct {
+	Code            string  `json:"code"`
+	Name            string  `json:"name" binding:"required,max=128"`
+	CreditHours     float64 `json:"credit_hours" binding:"required,gt=0,lte=30"`
+	Department      string  `json:"department" binding:"required,max=64"`
+	SemesterOffered string  `json:"semester_offered" binding:"required,oneof=spring fall summer all"`
+}
+
+// CourseList 课程列表（可按 keyword、department 过滤），供录入学分时选择
+func CourseList(c *gin.Context) {
+	list, err := model.ListCourses(c.Query("keyword"), c.Query("department"))
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// CourseCreate 新建课程
+func CourseCreate(c *gin.Context) {
+	var req CourseReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !courseCodePattern.MatchString(code) {
+		utils.Fail(c, "课程代码须为大写字母开头的 2-32 位字母/数字/中划线/下划线")
+		return
+	}
+	existing, err := model.GetCourseByCode(code)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing != nil {
+		utils.Fail(c, "课程代码已存在: "+code)
+		return
+	}
+	course := courseFromReq(&req)
+	course.Code = code
+	id, err := model.CreateCourse(course)
+	if err != nil {
+		utils.Fail(c, "创建失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"id": id}, "创建成功")
+}
+
+// CourseUpdate 更新课程名称、学分、院系与开课学期；课程代码已写入链上记录，不可修改
+func CourseUpdate(c *gin.Context) {
+	id, ok := courseIdParam(c)
+	if !ok {
+		return
+	}
+	var req CourseReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	existing, err := model.GetCourseById(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing == nil {
+		utils.Fail(c, "课程不存在")
+		return
+	}
+	if code := strings.TrimSpace(req.Code); code != "" && !strings.EqualFold(code, existing.Code) {
+		utils.Fail(c, "课程代码不可修改")
+		return
+	}
+	course := courseFromReq(&req)
+	course.Id = id
+	if _, err := model.UpdateCourse(course); err != nil {
+		utils.Fail(c, "更新失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "更新成功")
+}
+
+// CourseDelete 删除课程（已被学分记录引用的不可删）
+func CourseDelete(c *gin.Context) {
+	id, ok := courseIdParam(c)
+	if !ok {
+		return
+	}
+	existing, err := model.GetCourseById(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing == nil {
+		utils.Fail(c, "课程不存在")
+		return
+	}
+	n, err := model.CountCreditsByCourse(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if n > 0 {
+		utils.Fail(c, "该课程已被 "+strconv.FormatInt(n, 10)+" 条学分记录引用，不可删除")
+		return
+	}
+	if err := model.DeleteCourse(id); err != nil {
+		utils.Fail(c, "删除失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "删除成功")
+}
+
+func courseFromReq(req *CourseReq) *model.Course {
+	return &model.Course{
+		Name:            strings.TrimSpace(req.Name),
+		CreditHours:     req.CreditHours,
+		Department:      strings.TrimSpace(req.Department),
+		SemesterOffered: req.SemesterOffered,
+	}
+}
+
+// courseIdParam 解析路径中的课程ID，失败时已写响应
+func courseIdParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "课程ID无效")
+		return 0, false
+	}
+	return id, true
+}
+
+// catalogCourse 按课程ID取目录条目，不存在时已写响应
+func catalogCourse(c *gin.Context, id int64) (*model.Course, bool) {
+	course, err := model.GetCourseById(id)
+	if err != nil {
+		utils.Fail(c, "查询课程失败: "+err.Error())
+		return nil, false
+	}
+	if course == nil {
+		utils.Fail(c, "课程不在课程目录中")
+		return nil, false
+	}
+	return course, true
+}
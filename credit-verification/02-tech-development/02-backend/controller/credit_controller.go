@@ -3,18 +3,29 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"campus-credit-backend/model"
+	"campus-credit-backend/service"
 	"campus-credit-backend/utils"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/gin-gonic/gin"
 )
 
-// CreditRecordReq 录入学分请求（教师）
+// CreditRecordReq 录入学分请求（教师）：course_id 为课程目录主键，上链写入其课程代码；term_id 为所属学期
 type CreditRecordReq struct {
 	StudentAddress string  `json:"student_address" binding:"required"`
-	CourseName     string  `json:"course_name" binding:"required"`
+	CourseId       int64   `json:"course_id" binding:"required,gt=0"`
+	TermId         int64   `json:"term_id" binding:"required,gt=0"`
 	Score          float64 `json:"score" binding:"required,gte=0,lte=100"`
 }
 
@@ -37,87 +48,294 @@ func CreditRecord(c *gin.Context) {
 		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
+	course, ok := catalogCourse(c, req.CourseId)
+	if !ok {
+		return
+	}
+	if !termOpenForRecord(c, req.TermId) {
+		return
+	}
+	if !checkOfferingForRecord(c, req.TermId, course.Id, req.StudentAddress) {
+		return
+	}
 
-	txHash, err := utils.RecordCredit(req.StudentAddress, req.CourseName, req.Score)
+	// 钱包签名模式：教师在自己的钱包签名，再调用 /credit/record/submit
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "recordCredit", req.StudentAddress, course.Code, uint8(req.Score))
+		return
+	}
+
+	// 只提交交易不等待打包：先落库 pending 记录并建任务，链上学分ID由后台任务从回执事件关联
+	tx, err := utils.RecordCredit(req.StudentAddress, course.Code, req.Score)
 	if err != nil {
 		utils.Fail(c, "上链失败: "+err.Error())
 		return
 	}
+	txHash := tx.Hash().Hex()
 
-	// 等待交易打包后再查链上，否则 getStudentCredits 可能还读不到新数据
-	if err := utils.WaitTxMined(context.Background(), txHash, 15*time.Second); err != nil {
-		utils.Fail(c, "上链成功但等待打包超时，请稍后在「录入列表」查看")
+	creditId, err := model.CreateCredit(req.StudentAddress, teacherAddress, course.Code, req.Score, req.TermId, "pending", txHash, user.Id)
+	if err != nil {
+		utils.Fail(c, "交易已提交但保存记录失败（可稍后由同步/索引补录）: "+err.Error())
 		return
 	}
+	jobId, err := model.CreateCreditJob(creditId, txHash, user.Id)
+	if err != nil {
+		utils.Fail(c, "交易已提交但创建任务失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"job_id": jobId, "credit_id": creditId, "tx_hash": txHash}, "学分已提交上链，正在等待确认")
+}
 
-	credits, err := utils.GetUserCredits(req.StudentAddress)
-	if err != nil || len(credits) == 0 {
-		utils.Fail(c, "上链成功但获取链上学分ID失败，请稍后同步")
+// CreditRecordPrepare 教师自签录入第一步：以教师绑定地址为发送方构造 recordCredit 未签名交易，
+// 合约记录的 teacherAddress 即为教师本人
+func CreditRecordPrepare(c *gin.Context) {
+	var req CreditRecordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
-	var maxId uint64
-	for _, cr := range credits {
-		if id, ok := cr["id"].(uint64); ok && id > maxId {
-			maxId = id
-		}
+	course, ok := catalogCourse(c, req.CourseId)
+	if !ok {
+		return
+	}
+	if !termOpenForRecord(c, req.TermId) {
+		return
+	}
+	if !checkOfferingForRecord(c, req.TermId, course.Id, req.StudentAddress) {
+		return
+	}
+	respondUnsignedTx(c, "recordCredit", req.StudentAddress, course.Code, uint8(req.Score))
+}
+
+// CreditRecordSubmitReq 教师自签录入第二步：已签名原始交易，或已在钱包中直接发送的交易哈希（二选一），以及所属学期
+type CreditRecordSubmitReq struct {
+	RawTx  string `json:"raw_tx"`
+	TxHash string `json:"tx_hash"`
+	TermId int64  `json:"term_id" binding:"required,gt=0"`
+}
+
+// CreditRecordSubmit 校验交易为 recordCredit 且签名地址为教师绑定地址后广播（或登记已发送的交易），再落库并创建关联任务
+func CreditRecordSubmit(c *gin.Context) {
+	var req CreditRecordSubmitReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	if (req.RawTx == "") == (req.TxHash == "") {
+		utils.Fail(c, "raw_tx 与 tx_hash 需且仅需提供一个")
+		return
+	}
+	if !termOpenForRecord(c, req.TermId) {
+		return
+	}
+	teacher, ok := boundAddress(c)
+	if !ok {
+		return
 	}
-	contractCreditId := int64(maxId)
 
-	_, err = model.CreateCredit(req.StudentAddress, teacherAddress, req.CourseName, req.Score, "pending", txHash, contractCreditId)
+	var signed *utils.SignedContractTx
+	var err error
+	if req.RawTx != "" {
+		signed, err = utils.DecodeSignedTx(c.Request.Context(), req.RawTx)
+	} else if isTxHash(req.TxHash) {
+		signed, err = utils.GetContractTxByHash(c.Request.Context(), req.TxHash)
+	} else {
+		utils.Fail(c, "交易哈希格式错误")
+		return
+	}
 	if err != nil {
-		utils.Fail(c, "保存记录失败: "+err.Error())
+		utils.Fail(c, err.Error())
+		return
+	}
+	if signed.Method != "recordCredit" || len(signed.Args) != 3 {
+		utils.Fail(c, "交易不是 recordCredit 调用")
 		return
 	}
-	utils.Success(c, gin.H{"tx_hash": txHash, "contract_credit_id": contractCreditId}, "录入学分成功")
+	if signed.From != teacher {
+		utils.FailWithCode(c, 403, "交易签名地址与当前教师绑定地址不一致")
+		return
+	}
+	studentAddress, _ := signed.Args[0].(string)
+	courseName, _ := signed.Args[1].(string)
+	score, _ := signed.Args[2].(uint8)
+	txHash := signed.Tx.Hash().Hex()
+
+	// courseName 须为课程目录中的课程代码，且教师任教、学生已选该开课
+	course, err := model.GetCourseByCode(courseName)
+	if err != nil {
+		utils.Fail(c, "查询课程失败: "+err.Error())
+		return
+	}
+	if course == nil {
+		utils.Fail(c, "交易中的课程代码不在课程目录中: "+courseName)
+		return
+	}
+	if !checkOfferingForRecord(c, req.TermId, course.Id, studentAddress) {
+		return
+	}
+
+	existing, err := model.GetCreditByTxHash(txHash)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing != nil {
+		utils.Fail(c, fmt.Sprintf("该交易已登记为学分记录 %d", existing.Id))
+		return
+	}
+
+	if req.RawTx != "" {
+		err = utils.SubmitSignedTx(context.Background(), signed)
+	} else {
+		// 已由钱包广播，只登记到交易队列/台账跟踪状态
+		utils.DefaultTxQueue.TrackExternal(signed.Tx, signed.Method, signed.Args, signed.From)
+	}
+	if err != nil {
+		utils.Fail(c, err.Error())
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	creditId, err := model.CreateCredit(studentAddress, teacher.Hex(), courseName, float64(score), req.TermId, "pending", txHash, userId.(uint64))
+	if err != nil {
+		utils.Fail(c, "交易已提交但保存记录失败（可稍后由同步/索引补录）: "+err.Error())
+		return
+	}
+	jobId, err := model.CreateCreditJob(creditId, txHash, userId.(uint64))
+	if err != nil {
+		utils.Fail(c, "交易已提交但创建任务失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"job_id": jobId, "credit_id": creditId, "tx_hash": txHash}, "学分已提交上链，正在等待确认")
 }
 
-// CreditApproveReq 审核请求（管理员）
+// CreditJobStatus 查询异步录入任务进度（任务创建者或管理员可查）
+func CreditJobStatus(c *gin.Context) {
+	job, ok := loadCreditJob(c)
+	if !ok {
+		return
+	}
+	utils.Success(c, job, "查询成功")
+}
+
+// CreditJobStream 以 Server-Sent Events 推送录入任务进度，任务结束（linked/failed）或超时后关闭
+func CreditJobStream(c *gin.Context) {
+	job, ok := loadCreditJob(c)
+	if !ok {
+		return
+	}
+	deadline := time.Now().Add(2 * time.Minute)
+	lastStatus := ""
+	c.Stream(func(w io.Writer) bool {
+		if job.Status != lastStatus {
+			c.SSEvent("progress", job)
+			lastStatus = job.Status
+		}
+		if job.Status != model.CreditJobSubmitted || time.Now().After(deadline) {
+			return false
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-time.After(time.Second):
+		}
+		latest, err := model.GetCreditJob(job.Id)
+		if err != nil || latest == nil {
+			return false
+		}
+		job = latest
+		return true
+	})
+}
+
+// loadCreditJob 解析路径中的任务ID并校验访问权限，失败时已写响应
+func loadCreditJob(c *gin.Context) (*model.CreditJob, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "任务ID无效")
+		return nil, false
+	}
+	job, err := model.GetCreditJob(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return nil, false
+	}
+	if job == nil {
+		utils.Fail(c, "任务不存在")
+		return nil, false
+	}
+	userId, _ := c.Get("userId")
+	role, _ := c.Get("role")
+	if job.CreatedBy != userId.(uint64) && !model.RoleHasPermission(role.(string), model.PermCreditReadAll) {
+		utils.FailWithCode(c, 403, "无权查看该任务")
+		return nil, false
+	}
+	return job, true
+}
+
+// CreditApproveReq 审核请求（管理员），审核意见必填，记入学分时间线
 type CreditApproveReq struct {
-	CreditId int64 `json:"credit_id" binding:"required"` // 数据库主键 id
+	CreditId int64  `json:"credit_id" binding:"required"` // 数据库主键 id
+	Comment  string `json:"comment" binding:"required"`
 }
 
-// CreditApprove 管理员审核学分（调合约 + 更新库）
+// CreditApprove 管理员审核学分：调合约后登记审核交易，打包成功后由审核回执任务更新库
 func CreditApprove(c *gin.Context) {
 	var req CreditApproveReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
+	var ok bool
+	if req.Comment, ok = checkAuditComment(c, req.Comment, true); !ok {
+		return
+	}
 	row, err := model.GetCreditById(req.CreditId)
 	if err != nil || row == nil {
 		utils.Fail(c, "学分记录不存在")
 		return
 	}
+	if row.SupersededBy.Valid {
+		utils.Fail(c, fmt.Sprintf("该记录已被更正为学分 %d，请处理最新版本", row.SupersededBy.Int64))
+		return
+	}
+	if row.Locked {
+		utils.Fail(c, model.ErrCreditLocked.Error())
+		return
+	}
 	if row.Status != "pending" {
 		utils.Fail(c, "该记录已审核")
 		return
 	}
-	contractId := row.ContractCreditId.Int64
-	if contractId == 0 {
+	if !row.ContractCreditId.Valid {
 		utils.Fail(c, "该记录缺少链上学分ID，无法审核")
 		return
 	}
+	if !checkNoOpenAudit(c, row.Id) {
+		return
+	}
+	contractId := row.ContractCreditId.Int64
 
-	txHash, err := utils.AuditCredit(uint64(contractId), true)
-	if err != nil {
-		utils.Fail(c, "链上审核失败: "+err.Error())
+	// 钱包签名模式：管理员在自己的钱包签名，提交 /tx/submit 时需带上 comment
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "approveCredit", big.NewInt(contractId))
 		return
 	}
 
-	userId, _ := c.Get("userId")
-	user, _ := model.GetUserById(userId.(uint64))
-	auditAdmin := ""
-	if user != nil && user.Address.Valid {
-		auditAdmin = user.Address.String
+	txHash, err := utils.AuditCredit(uint64(contractId), true, "")
+	if err != nil {
+		utils.Fail(c, "链上审核失败: "+err.Error())
+		return
 	}
-	if err := model.UpdateCreditStatus(req.CreditId, "approved", auditAdmin); err != nil {
-		utils.Fail(c, "更新状态失败: "+err.Error())
+	auditId, err := model.SubmitCreditAudit(row, model.CreditEventApproved, currentCreditActor(c), txHash, "", req.Comment)
+	if err != nil {
+		utils.FailWithCode(c, 500, "交易已提交（"+txHash+"），登记审核失败: "+err.Error())
 		return
 	}
-	utils.Success(c, gin.H{"tx_hash": txHash}, "审核通过")
+	utils.Success(c, gin.H{"tx_hash": txHash, "audit_id": auditId}, "审核交易已提交，打包后生效")
 }
 
-// CreditList 学分列表（按角色：学生看自己、教师看自己录入、管理员看全部）
+// CreditList 学分列表（按权限：credit:read_all 看全部、credit:record 看自己录入、否则看本人学分）
 func CreditList(c *gin.Context) {
 	userId, _ := c.Get("userId")
 	role, _ := c.Get("role")
@@ -128,21 +346,21 @@ func CreditList(c *gin.Context) {
 	}
 
 	var list []model.CreditRow
-	switch role.(string) {
-	case "student":
+	switch {
+	case model.RoleHasPermission(role.(string), model.PermCreditReadAll):
+		list, err = model.GetAllCredits()
+	case model.RoleHasPermission(role.(string), model.PermCreditRecord):
 		if !user.Address.Valid || user.Address.String == "" {
-			utils.Success(c, []interface{}{}, "暂无学分")
+			utils.Success(c, []interface{}{}, "暂无录入记录")
 			return
 		}
-		list, err = model.GetCreditsByStudentAddress(user.Address.String)
-	case "teacher":
+		list, err = model.GetCreditsByTeacherAddress(user.Address.String)
+	case model.RoleHasPermission(role.(string), model.PermCreditReadOwn):
 		if !user.Address.Valid || user.Address.String == "" {
-			utils.Success(c, []interface{}{}, "暂无录入记录")
+			utils.Success(c, []interface{}{}, "暂无学分")
 			return
 		}
-		list, err = model.GetCreditsByTeacherAddress(user.Address.String)
-	case "admin":
-		list, err = model.GetAllCredits()
+		list, err = model.GetCreditsByStudentAddress(user.Address.String)
 	default:
 		utils.Fail(c, "无权限")
 		return
@@ -154,7 +372,7 @@ func CreditList(c *gin.Context) {
 	utils.Success(c, list, "查询成功")
 }
 
-// CreditPending 管理员：待审核列表
+// CreditPending 待审核列表（审核人/审计员）
 func CreditPending(c *gin.Context) {
 	list, err := model.GetPendingCredits()
 	if err != nil {
@@ -164,49 +382,619 @@ func CreditPending(c *gin.Context) {
 	utils.Success(c, list, "查询成功")
 }
 
-// CreditSync 链上学分同步到本地（可根据 student_address 拉取并更新状态）
+// CreditSyncItem 单条学分对账结果
+type CreditSyncItem struct {
+	Id               int64    `json:"id"`
+	ContractCreditId int64    `json:"contract_credit_id"`
+	Result           string   `json:"result"` // updated / matched / diverged / error
+	LocalStatus      string   `json:"local_status"`
+	ChainApproved    bool     `json:"chain_approved"`
+	ChainRejected    bool     `json:"chain_rejected"`
+	ChainRevoked     bool     `json:"chain_revoked"`
+	Diffs            []string `json:"diffs,omitempty"` // 不一致的字段说明
+	Error            string   `json:"error,omitempty"`
+}
+
+// CreditSync 链上学分同步到本地：逐条按 contract_credit_id 调合约 getCreditById / isRejected / isRevoked 对账
+// 链上已审核或已驳回而本地仍 pending（如管理员直接在 MetaMask 操作）时更新为 approved / rejected，
+// 链上已撤销而本地仍 approved 时更新为 revoked；其余差异只报告不覆盖
 func CreditSync(c *gin.Context) {
-	// 简单实现：把本地 pending 的根据 contract_credit_id 调合约 getCreditById 更新 is_approved 到 status
-	list, err := model.GetPendingCredits()
+	list, err := model.GetCreditsWithContractId()
 	if err != nil {
 		utils.Fail(c, "查询待同步记录失败: "+err.Error())
 		return
 	}
-	// 若没有 GetCreditByIdFromChain，可先只返回成功，后续再实现
-	_ = list
-	utils.Success(c, gin.H{"synced": 0}, "同步完成（当前仅落库记录，链上状态需审核后更新）")
+	// 后端代录时链上 teacherAddress 为后端账户，视为与本地教师一致
+	signerAddr, _ := utils.GetSignerAddress()
+
+	items := make([]CreditSyncItem, 0, len(list))
+	var updated, matched, diverged, failed int
+	for _, row := range list {
+		item := syncCreditRow(row, signerAddr)
+		switch item.Result {
+		case "updated":
+			updated++
+		case "matched":
+			matched++
+		case "diverged":
+			diverged++
+		default:
+			failed++
+		}
+		items = append(items, item)
+	}
+	utils.Success(c, gin.H{
+		"synced":   updated,
+		"matched":  matched,
+		"diverged": diverged,
+		"failed":   failed,
+		"items":    items,
+	}, "同步完成")
+}
+
+// syncCreditRow 对比单条本地记录与链上记录，必要时更新本地状态
+func syncCreditRow(row model.CreditRow, signerAddr common.Address) CreditSyncItem {
+	item := CreditSyncItem{
+		Id:               row.Id,
+		ContractCreditId: row.ContractCreditId.Int64,
+		LocalStatus:      row.Status,
+	}
+	chain, err := utils.GetCreditByIdFromChain(uint64(row.ContractCreditId.Int64))
+	if err != nil {
+		item.Result = "error"
+		item.Error = err.Error()
+		return item
+	}
+	item.ChainApproved = chain.IsApproved
+	rejected, reasonHash, err := utils.GetCreditRejectionFromChain(uint64(row.ContractCreditId.Int64))
+	if err != nil {
+		item.Result = "error"
+		item.Error = err.Error()
+		return item
+	}
+	item.ChainRejected = rejected
+	revoked, revokeHash, err := utils.GetCreditRevocationFromChain(uint64(row.ContractCreditId.Int64))
+	if err != nil {
+		item.Result = "error"
+		item.Error = err.Error()
+		return item
+	}
+	item.ChainRevoked = revoked
+
+	if normalAddress(chain.StudentId) != normalAddress(row.StudentAddress) {
+		item.Diffs = append(item.Diffs, fmt.Sprintf("student: 本地 %s / 链上 %s", row.StudentAddress, chain.StudentId))
+	}
+	if chain.CourseName != row.CourseName {
+		item.Diffs = append(item.Diffs, fmt.Sprintf("course: 本地 %s / 链上 %s", row.CourseName, chain.CourseName))
+	}
+	if uint8(row.Score) != chain.Score {
+		item.Diffs = append(item.Diffs, fmt.Sprintf("score: 本地 %v / 链上 %d", row.Score, chain.Score))
+	}
+	if !strings.EqualFold(chain.TeacherAddress.Hex(), row.TeacherAddress) && chain.TeacherAddress != signerAddr {
+		item.Diffs = append(item.Diffs, fmt.Sprintf("teacher: 本地 %s / 链上 %s", row.TeacherAddress, chain.TeacherAddress.Hex()))
+	}
+	if rejected && row.RejectReason.Valid && utils.RejectReasonHash(row.RejectReason.String) != reasonHash {
+		item.Diffs = append(item.Diffs, "reject_reason: 本地原因与链上哈希不符")
+	}
+	if revoked && row.RevokeReason.Valid && utils.RejectReasonHash(row.RevokeReason.String) != revokeHash {
+		item.Diffs = append(item.Diffs, "revoke_reason: 本地理由与链上哈希不符")
+	}
+	if len(item.Diffs) > 0 {
+		// 内容不一致说明本地关联的链上ID可能有误，不据此改状态
+		item.Result = "diverged"
+		return item
+	}
+
+	switch {
+	case revoked && row.Status == "revoked":
+		item.Result = "matched"
+	case revoked && row.Status == "approved":
+		n, err := model.MarkCreditRevokedFromChain(row.Id)
+		if err != nil {
+			item.Result = "error"
+			item.Error = "更新状态失败: " + err.Error()
+			return item
+		}
+		item.Result = "matched"
+		if n > 0 {
+			item.Result = "updated"
+		}
+	case revoked:
+		item.Diffs = append(item.Diffs, fmt.Sprintf("status: 本地 %s / 链上已撤销", row.Status))
+		item.Result = "diverged"
+	case row.Status == "revoked":
+		item.Diffs = append(item.Diffs, "status: 本地 revoked / 链上未撤销")
+		item.Result = "diverged"
+	case chain.IsApproved && row.Status == "pending":
+		n, err := model.MarkCreditApprovedFromChain(row.Id)
+		if err != nil {
+			item.Result = "error"
+			item.Error = "更新状态失败: " + err.Error()
+			return item
+		}
+		if n > 0 {
+			item.Result = "updated"
+			return item
+		}
+		item.Result = "matched"
+	case chain.IsApproved && row.Status != "approved":
+		item.Diffs = append(item.Diffs, fmt.Sprintf("status: 本地 %s / 链上已审核", row.Status))
+		item.Result = "diverged"
+	case !chain.IsApproved && row.Status == "approved":
+		item.Diffs = append(item.Diffs, "status: 本地 approved / 链上未审核")
+		item.Result = "diverged"
+	case rejected && row.Status == "pending":
+		n, err := model.MarkCreditRejectedFromChain(row.Id)
+		if err != nil {
+			item.Result = "error"
+			item.Error = "更新状态失败: " + err.Error()
+			return item
+		}
+		item.Result = "matched"
+		if n > 0 {
+			item.Result = "updated"
+		}
+	case rejected && row.Status != "rejected":
+		item.Diffs = append(item.Diffs, fmt.Sprintf("status: 本地 %s / 链上已驳回", row.Status))
+		item.Result = "diverged"
+	case !rejected && row.Status == "rejected":
+		item.Diffs = append(item.Diffs, "status: 本地 rejected / 链上未驳回")
+		item.Result = "diverged"
+	default:
+		item.Result = "matched"
+	}
+	return item
 }
 
-// CreditRejectReq 驳回请求（可选）
+// CreditRejectReq 驳回请求：原因原文存库，链上记录其 keccak256；comment 为审核意见，不填则以原因记入时间线
 type CreditRejectReq struct {
-	CreditId int64 `json:"credit_id" binding:"required"`
+	CreditId int64  `json:"credit_id" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+	Comment  string `json:"comment"`
 }
 
+// CreditReject 管理员驳回学分：调合约 rejectCredit 后登记驳回交易，打包成功后由审核回执任务写入 rejected 与原因原文
 func CreditReject(c *gin.Context) {
 	var req CreditRejectReq
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "驳回原因不能为空且不超过256个字符")
+		return
+	}
+	var ok bool
+	if req.Comment, ok = checkAuditComment(c, req.Comment, false); !ok {
+		return
+	}
 	row, err := model.GetCreditById(req.CreditId)
 	if err != nil || row == nil {
 		utils.Fail(c, "学分记录不存在")
 		return
 	}
+	if row.SupersededBy.Valid {
+		utils.Fail(c, fmt.Sprintf("该记录已被更正为学分 %d，请处理最新版本", row.SupersededBy.Int64))
+		return
+	}
+	if row.Locked {
+		utils.Fail(c, model.ErrCreditLocked.Error())
+		return
+	}
 	if row.Status != "pending" {
 		utils.Fail(c, "该记录已处理")
 		return
 	}
+	if !row.ContractCreditId.Valid {
+		utils.Fail(c, "该记录缺少链上学分ID，无法驳回")
+		return
+	}
+	if !checkNoOpenAudit(c, row.Id) {
+		return
+	}
+	contractId := row.ContractCreditId.Int64
+
+	// 钱包签名模式：管理员在自己的钱包签名，提交 /tx/submit 时需带上同一 reason（及可选 comment）
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "rejectCredit", big.NewInt(contractId), utils.RejectReasonHash(req.Reason))
+		return
+	}
+
+	txHash, err := utils.AuditCredit(uint64(contractId), false, req.Reason)
+	if err != nil {
+		utils.Fail(c, "链上驳回失败: "+err.Error())
+		return
+	}
+	auditId, err := model.SubmitCreditAudit(row, model.CreditEventRejected, currentCreditActor(c), txHash, req.Reason, req.Comment)
+	if err != nil {
+		utils.FailWithCode(c, 500, "交易已提交（"+txHash+"），登记驳回失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"tx_hash": txHash, "audit_id": auditId}, "驳回交易已提交，打包后生效")
+}
+
+// CreditRevokeReq 撤销请求：理由必填，原文存库，链上记录其 keccak256；comment 为审核意见，不填则以理由记入时间线
+type CreditRevokeReq struct {
+	CreditId int64  `json:"credit_id" binding:"required"`
+	Reason   string `json:"reason" binding:"required"`
+	Comment  string `json:"comment"`
+}
+
+// CreditRevoke 管理员撤销已审核的学分：调合约 revokeCredit 后登记撤销交易，打包成功后由审核回执任务置为 revoked，学生列表中可见撤销状态与理由
+func CreditRevoke(c *gin.Context) {
+	var req CreditRevokeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "撤销理由不能为空且不超过256个字符")
+		return
+	}
+	var ok bool
+	if req.Comment, ok = checkAuditComment(c, req.Comment, false); !ok {
+		return
+	}
+	row, err := model.GetCreditById(req.CreditId)
+	if err != nil || row == nil {
+		utils.Fail(c, "学分记录不存在")
+		return
+	}
+	if row.SupersededBy.Valid {
+		utils.Fail(c, fmt.Sprintf("该记录已被更正为学分 %d，请处理最新版本", row.SupersededBy.Int64))
+		return
+	}
+	if row.Locked {
+		utils.Fail(c, model.ErrCreditLocked.Error())
+		return
+	}
+	if row.Status != "approved" {
+		utils.Fail(c, "只能撤销已审核通过的学分")
+		return
+	}
+	if !row.ContractCreditId.Valid {
+		utils.Fail(c, "该记录缺少链上学分ID，无法撤销")
+		return
+	}
+	if !checkNoOpenAudit(c, row.Id) {
+		return
+	}
+	contractId := row.ContractCreditId.Int64
+
+	// 钱包签名模式：管理员在自己的钱包签名，提交 /tx/submit 时需带上同一 reason（及可选 comment）
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "revokeCredit", big.NewInt(contractId), utils.RejectReasonHash(req.Reason))
+		return
+	}
+
+	txHash, err := utils.RevokeCredit(uint64(contractId), req.Reason)
+	if err != nil {
+		utils.Fail(c, "链上撤销失败: "+err.Error())
+		return
+	}
+	auditId, err := model.SubmitCreditAudit(row, model.CreditEventRevoked, currentCreditActor(c), txHash, req.Reason, req.Comment)
+	if err != nil {
+		utils.FailWithCode(c, 500, "交易已提交（"+txHash+"），登记撤销失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"tx_hash": txHash, "audit_id": auditId}, "撤销交易已提交，打包后生效")
+}
+
+// checkNoOpenAudit 学分已有等待打包的审核交易时拒绝再次提交，失败时已写响应
+func checkNoOpenAudit(c *gin.Context, creditId int64) bool {
+	open, err := model.HasOpenCreditAudit(creditId)
+	if err != nil {
+		utils.Fail(c, "查询审核交易失败: "+err.Error())
+		return false
+	}
+	if open {
+		utils.Fail(c, fmt.Sprintf("学分记录 %d 已有等待打包的审核交易，请稍后再试", creditId))
+		return false
+	}
+	return true
+}
+
+// currentCreditActor 当前登录用户作为学分事件操作人（地址为其绑定钱包，未绑定为空）
+func currentCreditActor(c *gin.Context) model.CreditActor {
 	userId, _ := c.Get("userId")
-	user, _ := model.GetUserById(userId.(uint64))
-	auditAdmin := ""
+	actor := model.CreditActor{UserId: userId.(uint64)}
+	user, _ := model.GetUserById(actor.UserId)
 	if user != nil && user.Address.Valid {
-		auditAdmin = user.Address.String
+		actor.Address = user.Address.String
+	}
+	return actor
+}
+
+// checkAuditComment 审核意见去空白后校验长度（不超过256字符），required 时不能为空；失败时已写响应
+func checkAuditComment(c *gin.Context, comment string, required bool) (string, bool) {
+	comment = strings.TrimSpace(comment)
+	if required && comment == "" {
+		utils.Fail(c, "审核意见 comment 不能为空")
+		return "", false
+	}
+	if utf8.RuneCountInString(comment) > 256 {
+		utils.Fail(c, "审核意见不能超过256个字符")
+		return "", false
+	}
+	return comment, true
+}
+
+// CreditTimeline 学分时间线：录入、审核、驳回等每次状态流转，以及更正形成的全部版本（credit:read_all，或该记录的录入教师/学生本人）
+func CreditTimeline(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "学分ID无效")
+		return
+	}
+	row, err := model.GetCreditById(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if row == nil {
+		utils.Fail(c, "学分记录不存在")
+		return
+	}
+	role, _ := c.Get("role")
+	if !model.RoleHasPermission(role.(string), model.PermCreditReadAll) {
+		addr := currentCreditActor(c).Address
+		if addr == "" || (!strings.EqualFold(addr, row.TeacherAddress) && !strings.EqualFold(addr, row.StudentAddress)) {
+			utils.FailWithCode(c, 403, "无权查看该学分记录")
+			return
+		}
+	}
+	events, err := model.ListCreditEvents(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	versions, err := model.ListCreditVersions(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"credit": row, "events": events, "versions": versions}, "查询成功")
+}
+
+// maxCreditBatch 单次批量审核/驳回的学分数上限
+const maxCreditBatch = 100
+
+// batchReceiptTimeout 批量交易等待打包的时长，超时后由后台审核回执任务按回执写入
+const batchReceiptTimeout = 60 * time.Second
+
+// CreditApproveBatchReq 批量审核请求：审核意见必填，整批共用
+type CreditApproveBatchReq struct {
+	CreditIds []int64 `json:"credit_ids" binding:"required,min=1"` // 数据库主键 id
+	Comment   string  `json:"comment" binding:"required"`
+}
+
+// CreditRejectBatchReq 批量驳回请求：整批共用驳回原因（链上记录其 keccak256）与可选审核意见
+type CreditRejectBatchReq struct {
+	CreditIds []int64 `json:"credit_ids" binding:"required,min=1"`
+	Reason    string  `json:"reason" binding:"required"`
+	Comment   string  `json:"comment"`
+}
+
+// CreditBatchItem 批量审核/驳回中单条学分的结果
+type CreditBatchItem struct {
+	CreditId         int64  `json:"credit_id"`
+	ContractCreditId int64  `json:"contract_credit_id,omitempty"`
+	Result           string `json:"result"` // submitted（已打包并更新库）/ skipped（合约跳过：链上已非待审核）/ pending（交易未在等待时间内打包或回执待重试）/ invalid（校验未通过，未上链）/ error（交易执行失败或登记、更新库失败）
+	Error            string `json:"error,omitempty"`
+}
+
+// CreditApproveBatch 管理员批量审核：逐条校验后把有效记录合并为一笔 approveCredits 交易，返回逐条结果
+func CreditApproveBatch(c *gin.Context) {
+	var req CreditApproveBatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	var ok bool
+	if req.Comment, ok = checkAuditComment(c, req.Comment, true); !ok {
+		return
+	}
+	auditCreditBatch(c, req.CreditIds, true, "", req.Comment)
+}
+
+// CreditRejectBatch 管理员批量驳回：逐条校验后把有效记录合并为一笔 rejectCredits 交易，返回逐条结果
+func CreditRejectBatch(c *gin.Context) {
+	var req CreditRejectBatchReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "驳回原因不能为空且不超过256个字符")
+		return
+	}
+	var ok bool
+	if req.Comment, ok = checkAuditComment(c, req.Comment, false); !ok {
+		return
+	}
+	auditCreditBatch(c, req.CreditIds, false, req.Reason, req.Comment)
+}
+
+// auditCreditBatch 批量审核/驳回公共流程：校验 → 单笔批量交易（钱包签名模式返回未签名交易）→ 逐条登记审核交易 → 等待打包，按回执事件逐条更新库并记事件
+func auditCreditBatch(c *gin.Context, ids []int64, approved bool, reason, comment string) {
+	if len(ids) > maxCreditBatch {
+		utils.Fail(c, fmt.Sprintf("单次最多处理 %d 条", maxCreditBatch))
+		return
+	}
+	items, rows := validateCreditBatch(ids)
+	if len(rows) == 0 {
+		utils.Success(c, gin.H{"submitted": 0, "items": items}, "没有可处理的记录")
+		return
+	}
+	contractIds := make([]uint64, len(rows))
+	bigIds := make([]*big.Int, len(rows))
+	for i, row := range rows {
+		contractIds[i] = uint64(row.ContractCreditId.Int64)
+		bigIds[i] = big.NewInt(row.ContractCreditId.Int64)
 	}
-	if err := model.UpdateCreditStatus(req.CreditId, "rejected", auditAdmin); err != nil {
-		utils.Fail(c, "更新失败: "+err.Error())
+	method := "approveCredits"
+	args := []interface{}{bigIds}
+	if !approved {
+		method = "rejectCredits"
+		args = append(args, utils.RejectReasonHash(reason))
+	}
+
+	// 钱包签名模式：管理员签名后提交 /tx/submit，需带上同一 comment（驳回另带 reason）
+	if utils.IsWalletSignerMode() {
+		from, ok := boundAddress(c)
+		if !ok {
+			return
+		}
+		unsigned, err := utils.BuildUnsignedTx(c.Request.Context(), from, method, args...)
+		if err != nil {
+			utils.Fail(c, "构造交易失败: "+err.Error())
+			return
+		}
+		utils.Success(c, gin.H{"unsigned_tx": unsigned, "items": items}, "请在钱包中签名后提交到 /api/tx/submit")
+		return
+	}
+
+	txHash, err := utils.AuditCredits(contractIds, approved, reason)
+	if err != nil {
+		utils.Fail(c, "链上批量处理失败: "+err.Error())
 		return
 	}
-	utils.Success(c, nil, "已驳回")
+	action := model.CreditEventApproved
+	if !approved {
+		action = model.CreditEventRejected
+	}
+	submitCreditBatchAudits(items, rows, action, currentCreditActor(c), txHash, reason, comment)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), batchReceiptTimeout)
+	defer cancel()
+	txHash, submitted, msg := waitCreditBatchReceipt(ctx, items, txHash)
+	utils.Success(c, gin.H{"tx_hash": txHash, "submitted": submitted, "items": items}, msg)
+}
+
+// submitCreditBatchAudits 批量交易广播后为通过校验的每条记录登记审核交易，登记失败的标记为 error
+func submitCreditBatchAudits(items []CreditBatchItem, rows []*model.CreditRow, action string, actor model.CreditActor, txHash, reason, comment string) {
+	byId := make(map[int64]*CreditBatchItem, len(items))
+	for i := range items {
+		if items[i].Result == "submitted" {
+			byId[items[i].CreditId] = &items[i]
+		}
+	}
+	for _, row := range rows {
+		if _, err := model.SubmitCreditAudit(row, action, actor, txHash, reason, comment); err != nil {
+			byId[row.Id].Result, byId[row.Id].Error = "error", "交易已提交，登记审核交易失败: "+err.Error()
+		}
+	}
+}
+
+// waitCreditBatchReceipt 等待批量交易打包，按回执处理该交易的审核登记（与后台审核回执任务同一逻辑），再按登记状态回填逐条结果
+// 合约跳过链上已非待审核的学分而不回滚整批，逐条结果以回执中的 CreditApproved / CreditRejected 事件为准；
+// 超时或回执处理失败时登记保持 submitted，由后台审核回执任务继续处理。返回实际打包的交易哈希（可能已被提价替换）、写入条数与提示
+func waitCreditBatchReceipt(ctx context.Context, items []CreditBatchItem, txHash string) (string, int, string) {
+	receipt, err := utils.DefaultTxQueue.WaitMined(ctx, txHash)
+	if err != nil {
+		markCreditBatchPending(items, "交易尚未打包，结果将由后台按回执写入")
+		return txHash, 0, "批量交易已提交，等待打包"
+	}
+	if hash := receipt.TxHash.Hex(); hash != txHash {
+		if err := model.UpdateCreditAuditTx(txHash, hash); err != nil {
+			markCreditBatchPending(items, "交易已打包，登记新交易哈希失败，结果将由后台按回执写入: "+err.Error())
+			return hash, 0, "批量交易已打包，结果待同步"
+		}
+		txHash = hash
+	}
+	if err := service.ApplyCreditAuditReceipt(receipt); err != nil {
+		log.Printf("[CreditBatch] 处理回执 %s 失败: %v", txHash, err)
+	}
+	audits, err := model.GetCreditAuditsByTx(txHash)
+	if err != nil {
+		markCreditBatchPending(items, "查询审核登记失败，结果将由后台按回执写入: "+err.Error())
+		return txHash, 0, "批量交易已打包，结果待同步"
+	}
+	byCredit := make(map[int64]*model.CreditAudit, len(audits))
+	for i := range audits {
+		byCredit[audits[i].CreditId] = &audits[i]
+	}
+	submitted, pending := 0, 0
+	for i := range items {
+		a := byCredit[items[i].CreditId]
+		if items[i].Result != "submitted" || a == nil {
+			continue
+		}
+		switch a.Status {
+		case model.CreditAuditApplied:
+			submitted++
+		case model.CreditAuditSkipped:
+			items[i].Result, items[i].Error = "skipped", a.Error.String
+		case model.CreditAuditFailed:
+			items[i].Result, items[i].Error = "error", a.Error.String
+		default:
+			items[i].Result, items[i].Error = "pending", "回执处理失败，结果将由后台按回执重试或由事件索引器同步"
+			pending++
+		}
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return txHash, 0, "链上批量交易执行失败"
+	}
+	if pending > 0 {
+		return txHash, submitted, "批量交易已打包，部分结果待同步"
+	}
+	return txHash, submitted, "批量处理完成"
+}
+
+// markCreditBatchPending 把仍为 submitted 的条目改为 pending
+func markCreditBatchPending(items []CreditBatchItem, msg string) {
+	for i := range items {
+		if items[i].Result == "submitted" {
+			items[i].Result, items[i].Error = "pending", msg
+		}
+	}
 }
 
+// validateCreditBatch 按 model.GetCreditById 逐条校验：须存在、未锁定、待审核、已关联链上ID、无等待打包的审核交易且不重复
+// 返回逐条结果（通过的预置为 submitted）与通过校验的记录
+func validateCreditBatch(ids []int64) ([]CreditBatchItem, []*model.CreditRow) {
+	items := make([]CreditBatchItem, 0, len(ids))
+	rows := make([]*model.CreditRow, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		item := CreditBatchItem{CreditId: id, Result: "invalid"}
+		if seen[id] {
+			item.Error = "重复的学分ID"
+			items = append(items, item)
+			continue
+		}
+		seen[id] = true
+		row, err := model.GetCreditById(id)
+		switch {
+		case err != nil:
+			item.Error = "查询失败: " + err.Error()
+		case row == nil:
+			item.Error = "学分记录不存在"
+		case row.SupersededBy.Valid:
+			item.Error = fmt.Sprintf("已被更正为学分 %d", row.SupersededBy.Int64)
+		case row.Locked:
+			item.Error = model.ErrCreditLocked.Error()
+		case row.Status != "pending":
+			item.Error = "该记录已处理"
+		case !row.ContractCreditId.Valid:
+			item.Error = "该记录缺少链上学分ID"
+		default:
+			open, err := model.HasOpenCreditAudit(row.Id)
+			if err != nil {
+				item.Error = "查询审核交易失败: " + err.Error()
+				break
+			}
+			if open {
+				item.Error = "该记录已有等待打包的审核交易"
+				break
+			}
+			item.ContractCreditId = row.ContractCreditId.Int64
+			item.Result = "submitted"
+			rows = append(rows, row)
+		}
+		items = append(items, item)
+	}
+	return items, rows
+}
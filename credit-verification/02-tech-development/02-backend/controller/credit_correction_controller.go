@@ -0,0 +1,249 @@
+// controller/credit_correction_controller.go 学分更正申请：教师提出、管理员审核后上链 supersedeCredit
+package controller
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreditCorrectionReq 教师提出更正：course_id（课程目录主键）/ score 至少改一项，未填的沿用原值；reason 必填
+type CreditCorrectionReq struct {
+	CreditId int64    `json:"credit_id" binding:"required"`
+	CourseId int64    `json:"course_id" binding:"omitempty,gt=0"`
+	Score    *float64 `json:"score" binding:"omitempty,gte=0,lte=100"`
+	Reason   string   `json:"reason" binding:"required"`
+}
+
+// CreditCorrectionCreate 教师对本人录入的学分提出更正（仅待审核或已审核、未被更正且无进行中申请的记录）
+func CreditCorrectionCreate(c *gin.Context) {
+	var req CreditCorrectionReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "更正原因不能为空且不超过256个字符")
+		return
+	}
+	teacher, ok := boundAddress(c)
+	if !ok {
+		return
+	}
+	row, err := model.GetCreditById(req.CreditId)
+	if err != nil || row == nil {
+		utils.Fail(c, "学分记录不存在")
+		return
+	}
+	if !strings.EqualFold(row.TeacherAddress, teacher.Hex()) {
+		utils.FailWithCode(c, 403, "只能更正本人录入的学分")
+		return
+	}
+	if row.SupersededBy.Valid {
+		utils.Fail(c, "该记录已被更正，请对最新版本提出")
+		return
+	}
+	if row.Locked {
+		utils.Fail(c, model.ErrCreditLocked.Error())
+		return
+	}
+	if row.Status != "pending" && row.Status != "approved" {
+		utils.Fail(c, "只能更正待审核或已审核通过的学分")
+		return
+	}
+	if !row.ContractCreditId.Valid {
+		utils.Fail(c, "该记录缺少链上学分ID，无法更正")
+		return
+	}
+	if !row.TermId.Valid {
+		utils.Fail(c, "该记录未关联学期（链上直接录入），无法更正")
+		return
+	}
+
+	courseName := row.CourseName
+	if req.CourseId != 0 {
+		course, ok := catalogCourse(c, req.CourseId)
+		if !ok {
+			return
+		}
+		// 改课程时与录入一致：本人须任教、学生须已选原学期的新课程
+		if course.Code != row.CourseName && !checkOfferingForRecord(c, row.TermId.Int64, course.Id, row.StudentAddress) {
+			return
+		}
+		courseName = course.Code
+	}
+	score := row.Score
+	if req.Score != nil {
+		score = float64(uint8(*req.Score))
+	}
+	if courseName == row.CourseName && uint8(score) == uint8(row.Score) {
+		utils.Fail(c, "课程与成绩均未变化")
+		return
+	}
+	open, err := model.HasOpenCreditCorrection(row.Id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if open {
+		utils.Fail(c, "该学分已有进行中的更正申请")
+		return
+	}
+
+	userId, _ := c.Get("userId")
+	id, err := model.CreateCreditCorrection(row.Id, courseName, score, req.Reason, userId.(uint64))
+	if err != nil {
+		utils.Fail(c, "保存失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"correction_id": id}, "更正申请已提交，等待管理员审核")
+}
+
+// CreditCorrectionList 更正申请列表（可按 status 过滤）：有 credit:approve 或 credit:read_all 看全部，否则看本人提出的
+func CreditCorrectionList(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	role, _ := c.Get("role")
+	var requestedBy uint64
+	if !model.RoleHasPermission(role.(string), model.PermCreditApprove) && !model.RoleHasPermission(role.(string), model.PermCreditReadAll) {
+		requestedBy = userId.(uint64)
+	}
+	list, err := model.ListCreditCorrections(c.Query("status"), requestedBy)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// CreditCorrectionReviewReq 管理员审核更正申请，审核意见必填
+type CreditCorrectionReviewReq struct {
+	Comment string `json:"comment" binding:"required"`
+}
+
+// CreditCorrectionApprove 管理员同意更正：调合约 supersedeCredit 生成新记录，打包后由后台任务关联新旧记录
+func CreditCorrectionApprove(c *gin.Context) {
+	corr, row, comment, ok := loadCorrectionForReview(c)
+	if !ok || !checkCorrectionOffering(c, corr, row) {
+		return
+	}
+	contractId := row.ContractCreditId.Int64
+
+	// 钱包签名模式：管理员签名后提交 /tx/submit，需带上 correction_id 与 comment
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "supersedeCredit", big.NewInt(contractId), corr.CourseName, uint8(corr.Score))
+		return
+	}
+
+	txHash, err := utils.SupersedeCredit(uint64(contractId), corr.CourseName, corr.Score)
+	if err != nil {
+		utils.Fail(c, "链上更正失败: "+err.Error())
+		return
+	}
+	userId, _ := c.Get("userId")
+	if _, err := model.SubmitCreditCorrection(corr.Id, userId.(uint64), comment, txHash); err != nil {
+		utils.FailWithCode(c, 500, "交易已提交，记录更正状态失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"correction_id": corr.Id, "tx_hash": txHash}, "更正已提交上链，正在等待确认")
+}
+
+// CreditCorrectionReject 管理员拒绝更正申请
+func CreditCorrectionReject(c *gin.Context) {
+	corr, _, comment, ok := loadCorrectionForReview(c)
+	if !ok {
+		return
+	}
+	userId, _ := c.Get("userId")
+	n, err := model.RejectCreditCorrection(corr.Id, userId.(uint64), comment)
+	if err != nil {
+		utils.Fail(c, "更新失败: "+err.Error())
+		return
+	}
+	if n == 0 {
+		utils.Fail(c, "该申请已处理")
+		return
+	}
+	utils.Success(c, nil, "已拒绝更正申请")
+}
+
+// checkCorrectionOffering 同意更正前复核：原记录须关联学期；改课程时申请人须任教、学生须已选同一学期的新课程（选课或任课可能在申请后被删除），失败时已写响应
+func checkCorrectionOffering(c *gin.Context, corr *model.CreditCorrection, row *model.CreditRow) bool {
+	if !row.TermId.Valid {
+		utils.Fail(c, "原学分记录未关联学期，无法更正")
+		return false
+	}
+	if corr.CourseName == row.CourseName {
+		return true
+	}
+	course, err := model.GetCourseByCode(corr.CourseName)
+	if err != nil {
+		utils.Fail(c, "查询课程失败: "+err.Error())
+		return false
+	}
+	if course == nil {
+		utils.Fail(c, "课程不在课程目录中: "+corr.CourseName)
+		return false
+	}
+	return checkOffering(c, row.TermId.Int64, course.Id, corr.RequestedBy, row.StudentAddress)
+}
+
+// loadCorrectionForReview 解析路径中的申请ID与审核意见，校验申请待审核且原记录仍可更正，失败时已写响应
+func loadCorrectionForReview(c *gin.Context) (*model.CreditCorrection, *model.CreditRow, string, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "申请ID无效")
+		return nil, nil, "", false
+	}
+	var req CreditCorrectionReviewReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return nil, nil, "", false
+	}
+	comment, ok := checkAuditComment(c, req.Comment, true)
+	if !ok {
+		return nil, nil, "", false
+	}
+	corr, row, ok := correctionForReview(c, id)
+	if !ok {
+		return nil, nil, "", false
+	}
+	return corr, row, comment, true
+}
+
+// correctionForReview 申请须为 requested，原记录须未锁定、未被更正、仍为待审核或已审核且已关联链上ID，失败时已写响应
+func correctionForReview(c *gin.Context, id int64) (*model.CreditCorrection, *model.CreditRow, bool) {
+	corr, err := model.GetCreditCorrection(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return nil, nil, false
+	}
+	if corr == nil {
+		utils.Fail(c, "更正申请不存在")
+		return nil, nil, false
+	}
+	if corr.Status != model.CorrectionRequested {
+		utils.Fail(c, "该申请已处理")
+		return nil, nil, false
+	}
+	row, err := model.GetCreditById(corr.CreditId)
+	if err != nil || row == nil {
+		utils.Fail(c, "学分记录不存在")
+		return nil, nil, false
+	}
+	if row.Locked {
+		utils.Fail(c, model.ErrCreditLocked.Error())
+		return nil, nil, false
+	}
+	if row.SupersededBy.Valid || (row.Status != "pending" && row.Status != "approved") || !row.ContractCreditId.Valid {
+		utils.Fail(c, "原学分记录当前状态不可更正")
+		return nil, nil, false
+	}
+	return corr, row, true
+}
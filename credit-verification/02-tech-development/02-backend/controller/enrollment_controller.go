@@ -0,0 +1,284 @@
+// controller/enrollment_controller.go 选课名单与任课安排：enrollment:manage 按学期代码 + 课程代码批量导入，录入学分时校验
+package controller
+
+import (
+	"strconv"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportRows 单次导入行数上限
+const maxImportRows = 1000
+
+// EnrollmentImportRow 一条选课：学期代码、课程代码、学生地址（与录入学分时的 student_address 一致）
+type EnrollmentImportRow struct {
+	TermCode       string `json:"term_code"`
+	CourseCode     string `json:"course_code"`
+	StudentAddress string `json:"student_address"`
+}
+
+// EnrollmentImportReq 批量导入选课
+type EnrollmentImportReq struct {
+	Rows []EnrollmentImportRow `json:"rows" binding:"required"`
+}
+
+// TeachingImportRow 一条任课安排：学期代码、课程代码、教师用户名
+type TeachingImportRow struct {
+	TermCode        string `json:"term_code"`
+	CourseCode      string `json:"course_code"`
+	TeacherUsername string `json:"teacher_username"`
+}
+
+// TeachingImportReq 批量导入任课安排
+type TeachingImportReq struct {
+	Rows []TeachingImportRow `json:"rows" binding:"required"`
+}
+
+// ImportRowResult 导入的逐行结果：imported 已导入 / duplicate 已存在 / invalid 校验未通过 / error 写库失败
+type ImportRowResult struct {
+	Row    int    `json:"row"` // 从 1 开始
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// EnrollmentImport 批量导入选课，逐行返回结果（单行失败不影响其他行）
+func EnrollmentImport(c *gin.Context) {
+	var req EnrollmentImportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	if !checkImportSize(c, len(req.Rows)) {
+		return
+	}
+	userId, _ := c.Get("userId")
+	resolver := newOfferingResolver()
+	results := make([]ImportRowResult, 0, len(req.Rows))
+	for i, row := range req.Rows {
+		res := ImportRowResult{Row: i + 1, Result: "invalid"}
+		student := strings.TrimSpace(row.StudentAddress)
+		termId, courseId, msg := resolver.resolve(row.TermCode, row.CourseCode)
+		switch {
+		case msg != "":
+			res.Error = msg
+		case student == "" || len(student) > 64:
+			res.Error = "学生地址不能为空且不超过64个字符"
+		default:
+			added, err := model.AddEnrollment(termId, courseId, student, userId.(uint64))
+			res.Result, res.Error = importResult(added, err)
+		}
+		results = append(results, res)
+	}
+	respondImport(c, results)
+}
+
+// TeachingImport 批量导入任课安排，逐行返回结果（单行失败不影响其他行）
+func TeachingImport(c *gin.Context) {
+	var req TeachingImportReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	if !checkImportSize(c, len(req.Rows)) {
+		return
+	}
+	userId, _ := c.Get("userId")
+	resolver := newOfferingResolver()
+	teachers := make(map[string]*model.User)
+	results := make([]ImportRowResult, 0, len(req.Rows))
+	for i, row := range req.Rows {
+		res := ImportRowResult{Row: i + 1, Result: "invalid"}
+		termId, courseId, msg := resolver.resolve(row.TermCode, row.CourseCode)
+		if msg != "" {
+			res.Error = msg
+			results = append(results, res)
+			continue
+		}
+		username := strings.TrimSpace(row.TeacherUsername)
+		teacher, ok := teachers[username]
+		if !ok {
+			var err error
+			if teacher, err = model.GetUserByUsername(username); err != nil {
+				res.Error = "查询教师失败: " + err.Error()
+				results = append(results, res)
+				continue
+			}
+			teachers[username] = teacher
+		}
+		switch {
+		case teacher == nil:
+			res.Error = "教师不存在: " + username
+		case !model.RoleHasPermission(teacher.Role, model.PermCreditRecord):
+			res.Error = "该用户没有录入学分权限: " + username
+		default:
+			added, err := model.AddTeachingAssignment(termId, courseId, teacher.Id, userId.(uint64))
+			res.Result, res.Error = importResult(added, err)
+		}
+		results = append(results, res)
+	}
+	respondImport(c, results)
+}
+
+// EnrollmentList 选课名单（可按 term_id、course_id 过滤）
+func EnrollmentList(c *gin.Context) {
+	termId, courseId := offeringQuery(c)
+	list, err := model.ListEnrollments(termId, courseId)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// TeachingList 任课安排（可按 term_id、course_id 过滤）
+func TeachingList(c *gin.Context) {
+	termId, courseId := offeringQuery(c)
+	list, err := model.ListTeachingAssignments(termId, courseId)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// EnrollmentDelete 删除一条选课（已录入的学分不受影响）
+func EnrollmentDelete(c *gin.Context) {
+	deleteById(c, model.DeleteEnrollment)
+}
+
+// TeachingDelete 删除一条任课安排（已录入的学分不受影响）
+func TeachingDelete(c *gin.Context) {
+	deleteById(c, model.DeleteTeachingAssignment)
+}
+
+// checkOfferingForRecord 录入学分前校验：当前教师任教该开课，且学生已选该开课，失败时已写响应
+func checkOfferingForRecord(c *gin.Context, termId, courseId int64, studentAddress string) bool {
+	userId, _ := c.Get("userId")
+	return checkOffering(c, termId, courseId, userId.(uint64), studentAddress)
+}
+
+// checkOffering 校验 teacherId 任教该开课，且学生已选该开课，失败时已写响应
+func checkOffering(c *gin.Context, termId, courseId int64, teacherId uint64, studentAddress string) bool {
+	teaching, err := model.IsTeaching(termId, courseId, teacherId)
+	if err != nil {
+		utils.Fail(c, "查询任课安排失败: "+err.Error())
+		return false
+	}
+	if !teaching {
+		utils.FailWithCode(c, 403, "录入教师不是该学期此课程的任课教师")
+		return false
+	}
+	enrolled, err := model.IsEnrolled(termId, courseId, strings.TrimSpace(studentAddress))
+	if err != nil {
+		utils.Fail(c, "查询选课名单失败: "+err.Error())
+		return false
+	}
+	if !enrolled {
+		utils.FailWithCode(c, 403, "该学生未选修该学期的此课程")
+		return false
+	}
+	return true
+}
+
+// offeringResolver 按学期代码、课程代码解析开课，导入时缓存查询结果
+type offeringResolver struct {
+	terms   map[string]*model.Term
+	courses map[string]*model.Course
+}
+
+func newOfferingResolver() *offeringResolver {
+	return &offeringResolver{terms: make(map[string]*model.Term), courses: make(map[string]*model.Course)}
+}
+
+// resolve 返回学期与课程主键；学期不存在或已关闭、课程不存在时返回错误说明
+func (r *offeringResolver) resolve(termCode, courseCode string) (int64, int64, string) {
+	termCode = strings.ToUpper(strings.TrimSpace(termCode))
+	courseCode = strings.ToUpper(strings.TrimSpace(courseCode))
+	term, ok := r.terms[termCode]
+	if !ok {
+		var err error
+		if term, err = model.GetTermByCode(termCode); err != nil {
+			return 0, 0, "查询学期失败: " + err.Error()
+		}
+		r.terms[termCode] = term
+	}
+	if term == nil {
+		return 0, 0, "学期不存在: " + termCode
+	}
+	if term.Status == model.TermClosed {
+		return 0, 0, "学期已关闭: " + termCode
+	}
+	course, ok := r.courses[courseCode]
+	if !ok {
+		var err error
+		if course, err = model.GetCourseByCode(courseCode); err != nil {
+			return 0, 0, "查询课程失败: " + err.Error()
+		}
+		r.courses[courseCode] = course
+	}
+	if course == nil {
+		return 0, 0, "课程不在课程目录中: " + courseCode
+	}
+	return term.Id, course.Id, ""
+}
+
+func importResult(added bool, err error) (string, string) {
+	if err != nil {
+		return "error", "写入失败: " + err.Error()
+	}
+	if !added {
+		return "duplicate", ""
+	}
+	return "imported", ""
+}
+
+// checkImportSize 行数须在 1 到 maxImportRows 之间，失败时已写响应
+func checkImportSize(c *gin.Context, n int) bool {
+	if n == 0 || n > maxImportRows {
+		utils.Fail(c, "导入行数须在 1-"+strconv.Itoa(maxImportRows)+" 之间")
+		return false
+	}
+	return true
+}
+
+func respondImport(c *gin.Context, results []ImportRowResult) {
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Result]++
+	}
+	utils.Success(c, gin.H{
+		"imported":  counts["imported"],
+		"duplicate": counts["duplicate"],
+		"failed":    counts["invalid"] + counts["error"],
+		"results":   results,
+	}, "导入完成")
+}
+
+func offeringQuery(c *gin.Context) (int64, int64) {
+	termId, _ := strconv.ParseInt(c.Query("term_id"), 10, 64)
+	courseId, _ := strconv.ParseInt(c.Query("course_id"), 10, 64)
+	return termId, courseId
+}
+
+// deleteById 解析路径中的ID并删除，记录不存在时提示
+func deleteById(c *gin.Context, del func(int64) (int64, error)) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "ID无效")
+		return
+	}
+	n, err := del(id)
+	if err != nil {
+		utils.Fail(c, "删除失败: "+err.Error())
+		return
+	}
+	if n == 0 {
+		utils.Fail(c, "记录不存在")
+		return
+	}
+	utils.Success(c, nil, "删除成功")
+}
@@ -0,0 +1,20 @@
+// controller/jwks_controller.go JWKS 公钥发布
+package controller
+
+import (
+	"net/http"
+
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS 返回当前全部验证公钥（RFC 7517 格式，非统一响应结构，便于标准 JWT 库直接使用）
+// @Summary JWKS
+// @Tags 用户管理
+// @Produce json
+// @Router /.well-known/jwks.json [get]
+func JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": utils.JWKS()})
+}
@@ -2,12 +2,19 @@
 package controller
 
 import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/service"
 	"campus-credit-backend/utils"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
 
-// AssignRole 分配合约角色
+// AssignRole 分配合约角色（交易打包后由角色服务同步 users.role 与角色缓存）
 func AssignRole(c *gin.Context) {
 	var req struct {
 		UserAddress string `json:"user_address" binding:"required"`
@@ -18,14 +25,202 @@ func AssignRole(c *gin.Context) {
 		return
 	}
 
-	// 调用合约AssignRole方法
-	txHash, err := utils.AssignRole(req.UserAddress, req.Role)
+	if !common.IsHexAddress(req.UserAddress) {
+		utils.Fail(c, "无效的以太坊地址: "+req.UserAddress)
+		return
+	}
+	if !service.IsChainRole(req.Role) {
+		utils.Fail(c, "合约不支持的角色: "+req.Role+"（teacher/admin/student）")
+		return
+	}
+	if isSelfAddress(c, req.UserAddress) {
+		utils.FailWithCode(c, 403, "不能修改自己的角色")
+		return
+	}
+
+	// 钱包签名模式：由管理员在自己的钱包签名，经 /tx/submit 提交后登记角色变更
+	if utils.IsWalletSignerMode() {
+		respondUnsignedTx(c, "assignRole", common.HexToAddress(req.UserAddress), req.Role)
+		return
+	}
+
+	operatorId, _ := c.Get("userId")
+	changeId, txHash, err := service.AssignRole(req.UserAddress, req.Role, operatorId.(uint64))
+	if errors.Is(err, service.ErrRoleHeld) {
+		utils.Fail(c, err.Error())
+		return
+	}
 	if err != nil {
 		// 服务器内部错误用500码
 		utils.FailWithCode(c, 500, "分配角色失败: "+err.Error())
 		return
 	}
-	utils.Success(c, gin.H{"tx_hash": txHash}, "角色分配成功")
+	utils.Success(c, gin.H{"change_id": changeId, "tx_hash": txHash}, "交易已提交，打包后生效")
+}
+
+// RoleRevokeReq 撤销链上角色（原因必填，随变更记录保存）
+type RoleRevokeReq struct {
+	UserAddress string `json:"user_address" binding:"required"`
+	Role        string `json:"role" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+}
+
+// RevokeRole 撤销合约角色：缓存立即清除，打包后降级 users.role 并使该用户所有 Token 失效
+func RevokeRole(c *gin.Context) {
+	var req RoleRevokeReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if isSelfAddress(c, req.UserAddress) {
+		utils.FailWithCode(c, 403, "不能修改自己的角色")
+		return
+	}
+
+	// 钱包签名模式：校验后返回未签名交易，提交 /tx/submit 时需带上同一 reason
+	if utils.IsWalletSignerMode() {
+		if err := service.CheckRevoke(req.UserAddress, req.Role, req.Reason); err != nil {
+			utils.Fail(c, err.Error())
+			return
+		}
+		respondUnsignedTx(c, "revokeRole", common.HexToAddress(req.UserAddress), req.Role)
+		return
+	}
+
+	operatorId, _ := c.Get("userId")
+	changeId, txHash, err := service.RevokeRole(req.UserAddress, req.Role, req.Reason, operatorId.(uint64))
+	if err != nil {
+		utils.Fail(c, "撤销角色失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"change_id": changeId, "tx_hash": txHash}, "交易已提交，打包后生效")
+}
+
+// RoleChangeList 角色分配/撤销记录（可按 user_address 过滤，含操作人与撤销原因）
+func RoleChangeList(c *gin.Context) {
+	address := c.Query("user_address")
+	if address != "" && !common.IsHexAddress(address) {
+		utils.Fail(c, "无效的以太坊地址: "+address)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	list, err := model.ListRoleChanges(address, limit)
+	if err != nil {
+		utils.FailWithCode(c, 500, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// RoleChangeStatus 查询角色变更进度（status: submitted / applied / failed）
+func RoleChangeStatus(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "变更ID无效")
+		return
+	}
+	rc, err := model.GetRoleChange(id)
+	if err != nil {
+		utils.FailWithCode(c, 500, "查询失败: "+err.Error())
+		return
+	}
+	if rc == nil {
+		utils.FailWithCode(c, 404, "角色变更不存在")
+		return
+	}
+	utils.Success(c, rc, "查询成功")
+}
+
+// RoleDrift 角色漂移报告：已绑定地址的用户中，本地角色与合约 isTeacher/isAdmin 不一致的
+func RoleDrift(c *gin.Context) {
+	list, err := service.RoleDriftReport()
+	if err != nil {
+		utils.FailWithCode(c, 500, "生成漂移报告失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"list": list, "total": len(list)}, "查询成功")
+}
+
+// RoleRepairReq 修复方向：chain 以链上为准改数据库，db 以数据库为准补发 assignRole
+type RoleRepairReq struct {
+	Source string `json:"source" binding:"required"`
+}
+
+// RoleRepair 修复单个用户的角色漂移
+func RoleRepair(c *gin.Context) {
+	userId, err := strconv.ParseUint(c.Param("userId"), 10, 64)
+	if err != nil || userId == 0 {
+		utils.Fail(c, "用户ID无效")
+		return
+	}
+	var req RoleRepairReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	user, err := model.GetUserById(userId)
+	if err != nil {
+		utils.FailWithCode(c, 500, "查询用户失败: "+err.Error())
+		return
+	}
+	if user == nil {
+		utils.FailWithCode(c, 404, "用户不存在")
+		return
+	}
+	if !user.Address.Valid || !common.IsHexAddress(user.Address.String) {
+		utils.Fail(c, "该用户未绑定钱包地址")
+		return
+	}
+
+	// 钱包签名模式：补发链上角色需管理员逐笔签名
+	if req.Source == service.RepairFromDB && utils.IsWalletSignerMode() {
+		respondRepairUnsignedTxs(c, user)
+		return
+	}
+
+	operatorId, _ := c.Get("userId")
+	res, err := service.RepairRoleDrift(user, req.Source, operatorId.(uint64))
+	if err != nil {
+		utils.Fail(c, "修复失败: "+err.Error())
+		return
+	}
+	utils.Success(c, res, "修复已提交")
+}
+
+// respondRepairUnsignedTxs 钱包签名模式：缺少的链上权限位各构造一笔 assignRole，多出的各构造一笔 revokeRole（提交时带上返回的 reason）
+func respondRepairUnsignedTxs(c *gin.Context, user *model.User) {
+	d := service.CheckRoleDrift(user)
+	if d.Error != "" {
+		utils.FailWithCode(c, 500, "读取链上角色失败: "+d.Error)
+		return
+	}
+	from, ok := boundAddress(c)
+	if !ok {
+		return
+	}
+	txs := make([]gin.H, 0, len(d.Missing)+len(d.Extra))
+	for _, item := range []struct {
+		method string
+		roles  []string
+	}{{"assignRole", d.Missing}, {"revokeRole", d.Extra}} {
+		for _, role := range item.roles {
+			unsigned, err := utils.BuildUnsignedTx(c.Request.Context(), from, item.method, common.HexToAddress(d.Address), role)
+			if err != nil {
+				utils.Fail(c, "构造交易失败: "+err.Error())
+				return
+			}
+			tx := gin.H{"method": item.method, "role": role, "unsigned_tx": unsigned}
+			if item.method == "revokeRole" {
+				tx["reason"] = service.RepairRevokeReason
+			}
+			txs = append(txs, tx)
+		}
+	}
+	utils.Success(c, gin.H{"drift": d, "unsigned_txs": txs}, "请在钱包中逐笔签名后提交到 /api/tx/submit")
 }
 
 // GetRole 查询合约角色
@@ -43,3 +238,8 @@ func GetRole(c *gin.Context) {
 	}
 	utils.Success(c, gin.H{"role": role}, "查询成功")
 }
+
+// RoleCacheStats 链上角色缓存统计（条目数、命中/未命中、淘汰与失效次数）
+func RoleCacheStats(c *gin.Context) {
+	utils.Success(c, utils.DefaultRoleCache.Stats(), "查询成功")
+}
@@ -0,0 +1,175 @@
+// controller/role_def_controller.go 角色定义与权限管理（需 role:manage）
+package controller
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,19}$`)
+
+// RoleDefReq 新建/更新角色请求
+type RoleDefReq struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// PermissionList 可分配的权限点
+func PermissionList(c *gin.Context) {
+	list := make([]gin.H, 0, len(model.AllPermissions))
+	for p, desc := range model.AllPermissions {
+		list = append(list, gin.H{"permission": p, "description": desc})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i]["permission"].(string) < list[j]["permission"].(string) })
+	utils.Success(c, list, "查询成功")
+}
+
+// RoleDefList 全部角色及权限
+func RoleDefList(c *gin.Context) {
+	list, err := model.ListRoles()
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// RoleDefCreate 新建角色
+func RoleDefCreate(c *gin.Context) {
+	var req RoleDefReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !roleNamePattern.MatchString(name) {
+		utils.Fail(c, "角色名须为小写字母开头的 2-20 位字母/数字/下划线")
+		return
+	}
+	if !checkPermissions(c, req.Permissions) {
+		return
+	}
+	existing, err := model.GetRoleDef(name)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing != nil {
+		utils.Fail(c, "角色已存在: "+name)
+		return
+	}
+	if err := model.CreateRoleDef(name, req.Description, req.Permissions); err != nil {
+		utils.Fail(c, "创建失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "创建成功")
+}
+
+// RoleDefUpdate 更新角色说明与权限（整体替换），该角色用户的 Token 随即失效
+func RoleDefUpdate(c *gin.Context) {
+	var req RoleDefReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	name := c.Param("name")
+	if !checkPermissions(c, req.Permissions) {
+		return
+	}
+	existing, err := model.GetRoleDef(name)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing == nil {
+		utils.Fail(c, "角色不存在: "+name)
+		return
+	}
+	if !checkAllRoleAccess(c, name) {
+		return
+	}
+	// 防止把自己的角色改成无法再管理角色
+	role, _ := c.Get("role")
+	if strings.EqualFold(role.(string), name) && !containsPermission(req.Permissions, model.PermRoleManage) {
+		utils.Fail(c, "不能移除当前登录角色的 role:manage 权限")
+		return
+	}
+	if err := model.UpdateRoleDef(name, req.Description, req.Permissions); err != nil {
+		utils.Fail(c, "更新失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "更新成功")
+}
+
+// RoleDefDelete 删除角色（内置角色与仍有用户的角色不可删）
+func RoleDefDelete(c *gin.Context) {
+	name := c.Param("name")
+	existing, err := model.GetRoleDef(name)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing == nil {
+		utils.Fail(c, "角色不存在: "+name)
+		return
+	}
+	if existing.Builtin {
+		utils.Fail(c, "内置角色不可删除")
+		return
+	}
+	if !checkAllRoleAccess(c, name) {
+		return
+	}
+	if err := model.DeleteRoleDef(name); err != nil {
+		utils.Fail(c, "删除失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "删除成功")
+}
+
+// checkPermissions 校验权限点均已定义，且只有持有 * 者可授予 *，失败时已写响应
+func checkPermissions(c *gin.Context, perms []string) bool {
+	for _, p := range perms {
+		if !model.ValidPermission(p) {
+			utils.Fail(c, "未知的权限: "+p)
+			return false
+		}
+		if p == model.PermAll && !callerHasAll(c) {
+			utils.FailWithCode(c, 403, "只有超级管理员可以授予全部权限（*）")
+			return false
+		}
+	}
+	return true
+}
+
+// checkAllRoleAccess 持有 * 的角色（如 super_admin）只能由持有 * 者修改、删除或分配，失败时已写响应
+func checkAllRoleAccess(c *gin.Context, role string) bool {
+	if model.RoleHasPermission(role, model.PermAll) && !callerHasAll(c) {
+		utils.FailWithCode(c, 403, "只有超级管理员可以操作拥有全部权限的角色: "+role)
+		return false
+	}
+	return true
+}
+
+// callerHasAll 当前登录角色是否持有 *
+func callerHasAll(c *gin.Context) bool {
+	role, _ := c.Get("role")
+	r, _ := role.(string)
+	return model.RoleHasPermission(r, model.PermAll)
+}
+
+func containsPermission(perms []string, perm string) bool {
+	for _, p := range perms {
+		if p == perm || p == model.PermAll {
+			return true
+		}
+	}
+	return false
+}
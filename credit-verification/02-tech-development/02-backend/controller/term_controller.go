@@ -0,0 +1,304 @@
+// controller/term_controller.go 学期与成绩录入窗口：登录用户可查询，term:manage 维护、开通补录与关闭学期
+package controller
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 学期代码：如 2026-FALL，2-32 位大写字母/数字/中划线/下划线
+var termCodePattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9_-]{1,31}$`)
+
+const dateLayout = "2006-01-02"
+
+// TermReq 新建/更新学期，日期格式 YYYY-MM-DD（含首尾两天）；更新时 code 忽略
+type TermReq struct {
+	Code         string `json:"code"`
+	Name         string `json:"name" binding:"required,max=64"`
+	StartDate    string `json:"start_date" binding:"required"`
+	EndDate      string `json:"end_date" binding:"required"`
+	GradingStart string `json:"grading_start" binding:"required"`
+	GradingEnd   string `json:"grading_end" binding:"required"`
+}
+
+// TermOverrideReq 为教师开通窗口外补录，hours 小时后失效
+type TermOverrideReq struct {
+	TeacherId uint64 `json:"teacher_id" binding:"required"`
+	Reason    string `json:"reason" binding:"required"`
+	Hours     int    `json:"hours" binding:"required,gt=0,lte=720"`
+}
+
+// TermList 学期列表（可按 status 过滤），供录入学分时选择
+func TermList(c *gin.Context) {
+	list, err := model.ListTerms(c.Query("status"))
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// TermCreate 新建学期
+func TermCreate(c *gin.Context) {
+	var req TermReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	if !termCodePattern.MatchString(code) {
+		utils.Fail(c, "学期代码须为 2-32 位大写字母/数字/中划线/下划线，如 2026-FALL")
+		return
+	}
+	term, ok := termFromReq(c, &req)
+	if !ok {
+		return
+	}
+	existing, err := model.GetTermByCode(code)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if existing != nil {
+		utils.Fail(c, "学期代码已存在: "+code)
+		return
+	}
+	term.Code = code
+	id, err := model.CreateTerm(term)
+	if err != nil {
+		utils.Fail(c, "创建失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"id": id}, "创建成功")
+}
+
+// TermUpdate 更新学期名称与日期（已关闭的学期不可修改）
+func TermUpdate(c *gin.Context) {
+	id, ok := termIdParam(c)
+	if !ok {
+		return
+	}
+	var req TermReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	existing, ok := openTerm(c, id)
+	if !ok {
+		return
+	}
+	if code := strings.TrimSpace(req.Code); code != "" && !strings.EqualFold(code, existing.Code) {
+		utils.Fail(c, "学期代码不可修改")
+		return
+	}
+	term, ok := termFromReq(c, &req)
+	if !ok {
+		return
+	}
+	term.Id = id
+	n, err := model.UpdateTerm(term)
+	if err != nil {
+		utils.Fail(c, "更新失败: "+err.Error())
+		return
+	}
+	if n == 0 {
+		utils.Fail(c, "学期已关闭，不可修改")
+		return
+	}
+	utils.Success(c, nil, "更新成功")
+}
+
+// TermClose 关闭学期：不可再录入，该学期全部学分锁定，不能再审核、驳回、撤销或更正（不可重新开启）
+func TermClose(c *gin.Context) {
+	id, ok := termIdParam(c)
+	if !ok {
+		return
+	}
+	if _, ok := openTerm(c, id); !ok {
+		return
+	}
+	userId, _ := c.Get("userId")
+	n, err := model.CloseTerm(id, userId.(uint64))
+	if err != nil {
+		utils.Fail(c, "关闭失败: "+err.Error())
+		return
+	}
+	if n < 0 {
+		utils.Fail(c, "学期已关闭")
+		return
+	}
+	utils.Success(c, gin.H{"locked_credits": n}, "学期已关闭，学分已锁定")
+}
+
+// TermOverrideList 学期的补录授权列表
+func TermOverrideList(c *gin.Context) {
+	id, ok := termIdParam(c)
+	if !ok {
+		return
+	}
+	list, err := model.ListTermOverrides(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// TermOverrideCreate 为教师开通窗口外补录（学期须未关闭，教师须有 credit:record 权限）
+func TermOverrideCreate(c *gin.Context) {
+	id, ok := termIdParam(c)
+	if !ok {
+		return
+	}
+	var req TermOverrideReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "补录原因不能为空且不超过256个字符")
+		return
+	}
+	if _, ok := openTerm(c, id); !ok {
+		return
+	}
+	teacher, err := model.GetUserById(req.TeacherId)
+	if err != nil || teacher == nil {
+		utils.Fail(c, "教师不存在")
+		return
+	}
+	if !model.RoleHasPermission(teacher.Role, model.PermCreditRecord) {
+		utils.Fail(c, "该用户没有录入学分权限")
+		return
+	}
+	userId, _ := c.Get("userId")
+	expiresAt := time.Now().Add(time.Duration(req.Hours) * time.Hour)
+	overrideId, err := model.CreateTermOverride(id, req.TeacherId, req.Reason, userId.(uint64), expiresAt)
+	if err != nil {
+		utils.Fail(c, "开通失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"id": overrideId, "expires_at": expiresAt}, "已开通补录")
+}
+
+// TermOverrideRevoke 提前收回补录授权
+func TermOverrideRevoke(c *gin.Context) {
+	id, ok := termIdParam(c)
+	if !ok {
+		return
+	}
+	overrideId, err := strconv.ParseInt(c.Param("overrideId"), 10, 64)
+	if err != nil || overrideId <= 0 {
+		utils.Fail(c, "授权ID无效")
+		return
+	}
+	n, err := model.RevokeTermOverride(id, overrideId)
+	if err != nil {
+		utils.Fail(c, "收回失败: "+err.Error())
+		return
+	}
+	if n == 0 {
+		utils.Fail(c, "授权不存在或已收回")
+		return
+	}
+	utils.Success(c, nil, "已收回补录授权")
+}
+
+// termOpenForRecord 录入学分前校验学期：须存在、未关闭，且当前在成绩录入窗口内或教师有有效的补录授权，失败时已写响应
+func termOpenForRecord(c *gin.Context, id int64) bool {
+	term, err := model.GetTermById(id)
+	if err != nil {
+		utils.Fail(c, "查询学期失败: "+err.Error())
+		return false
+	}
+	if term == nil {
+		utils.Fail(c, "学期不存在")
+		return false
+	}
+	if term.Status == model.TermClosed {
+		utils.Fail(c, "学期 "+term.Code+" 已关闭，不能再录入学分")
+		return false
+	}
+	if term.GradingOpen(time.Now()) {
+		return true
+	}
+	userId, _ := c.Get("userId")
+	granted, err := model.HasActiveTermOverride(id, userId.(uint64))
+	if err != nil {
+		utils.Fail(c, "查询补录授权失败: "+err.Error())
+		return false
+	}
+	if !granted {
+		utils.FailWithCode(c, 403, fmt.Sprintf("当前不在学期 %s 的成绩录入窗口（%s 至 %s）内，补录请联系管理员开通",
+			term.Code, term.GradingStart.Format(dateLayout), term.GradingEnd.Format(dateLayout)))
+		return false
+	}
+	return true
+}
+
+// termFromReq 解析并校验日期：开始不晚于结束，录入窗口不早于学期开始、且开始不晚于结束，失败时已写响应
+func termFromReq(c *gin.Context, req *TermReq) (*model.Term, bool) {
+	var dates [4]time.Time
+	for i, s := range []string{req.StartDate, req.EndDate, req.GradingStart, req.GradingEnd} {
+		d, err := time.ParseInLocation(dateLayout, strings.TrimSpace(s), time.Local)
+		if err != nil {
+			utils.Fail(c, "日期格式须为 YYYY-MM-DD: "+s)
+			return nil, false
+		}
+		dates[i] = d
+	}
+	term := &model.Term{
+		Name:         strings.TrimSpace(req.Name),
+		StartDate:    dates[0],
+		EndDate:      dates[1],
+		GradingStart: dates[2],
+		GradingEnd:   dates[3],
+	}
+	if term.EndDate.Before(term.StartDate) {
+		utils.Fail(c, "学期结束日期不能早于开始日期")
+		return nil, false
+	}
+	if term.GradingEnd.Before(term.GradingStart) || term.GradingStart.Before(term.StartDate) {
+		utils.Fail(c, "成绩录入窗口须在学期开始之后，且开始不晚于结束")
+		return nil, false
+	}
+	return term, true
+}
+
+// termIdParam 解析路径中的学期ID，失败时已写响应
+func termIdParam(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		utils.Fail(c, "学期ID无效")
+		return 0, false
+	}
+	return id, true
+}
+
+// openTerm 取未关闭的学期，不存在或已关闭时已写响应
+func openTerm(c *gin.Context, id int64) (*model.Term, bool) {
+	term, err := model.GetTermById(id)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return nil, false
+	}
+	if term == nil {
+		utils.Fail(c, "学期不存在")
+		return nil, false
+	}
+	if term.Status == model.TermClosed {
+		utils.Fail(c, "学期已关闭")
+		return nil, false
+	}
+	return term, true
+}
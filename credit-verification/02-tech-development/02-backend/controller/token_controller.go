@@ -0,0 +1,208 @@
+// controller/token_controller.go 登录会话：签发访问/刷新 Token、刷新轮换、登出、修改密码
+package controller
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// issueLoginTokens 登录成功：签发访问 Token 与新一族刷新 Token
+func issueLoginTokens(c *gin.Context, user *model.User) {
+	familyId, err := newFamilyId()
+	if err != nil {
+		utils.Fail(c, "生成Token失败: "+err.Error())
+		return
+	}
+	tokens, err := issueTokens(c, user, familyId)
+	if err != nil {
+		utils.Fail(c, "生成Token失败: "+err.Error())
+		return
+	}
+	tokens["user"] = user
+	tokens["permissions"] = model.RolePermissions(user.Role)
+	utils.Success(c, tokens, "登录成功")
+}
+
+// issueTokens 签发访问 Token，并在 familyId 下保存一枚新刷新 Token
+func issueTokens(c *gin.Context, user *model.User, familyId string) (gin.H, error) {
+	token, err := utils.GenerateToken(user.Id, user.Username, user.Role, user.TokenVersion)
+	if err != nil {
+		return nil, err
+	}
+	refresh, refreshHash, err := utils.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	id, err := model.CreateRefreshToken(user.Id, refreshHash, familyId, time.Now().Add(utils.RefreshTokenTTL()), c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return nil, err
+	}
+	return gin.H{
+		"token":            token,
+		"expires_in":       int(utils.AccessTokenTTL().Seconds()),
+		"refresh_token":    refresh,
+		"refresh_token_id": id,
+	}, nil
+}
+
+func newFamilyId() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// RefreshTokenReq 刷新 / 登出请求
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// UserRefresh 用刷新 Token 换取新的访问 Token 与刷新 Token（旧刷新 Token 随即失效）
+// 已轮换过的刷新 Token 再次出现视为泄露，吊销整族
+// @Summary 刷新Token
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param data body RefreshTokenReq true "刷新Token"
+// @Success 200 {object} utils.Response
+// @Router /api/user/refresh [post]
+func UserRefresh(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	old, err := model.GetRefreshTokenByHash(utils.HashRefreshToken(req.RefreshToken))
+	if err != nil {
+		utils.Fail(c, "查询Token失败: "+err.Error())
+		return
+	}
+	if old == nil {
+		utils.FailWithCode(c, 401, "刷新Token无效")
+		return
+	}
+	if old.RevokedAt.Valid {
+		_ = model.RevokeRefreshFamily(old.FamilyId)
+		utils.FailWithCode(c, 401, "刷新Token已失效，请重新登录")
+		return
+	}
+	if time.Now().After(old.ExpiresAt) {
+		utils.FailWithCode(c, 401, "刷新Token已过期，请重新登录")
+		return
+	}
+	user, err := model.GetUserById(old.UserId)
+	if err != nil || user == nil {
+		utils.FailWithCode(c, 401, "用户不存在")
+		return
+	}
+
+	tokens, err := issueTokens(c, user, old.FamilyId)
+	if err != nil {
+		utils.Fail(c, "生成Token失败: "+err.Error())
+		return
+	}
+	ok, err := model.RotateRefreshToken(old.Id, tokens["refresh_token_id"].(int64))
+	if err != nil {
+		utils.Fail(c, "轮换Token失败: "+err.Error())
+		return
+	}
+	if !ok {
+		// 并发刷新：另一请求已轮换该 Token，本次新签发的也一并作废
+		_ = model.RevokeRefreshFamily(old.FamilyId)
+		utils.FailWithCode(c, 401, "刷新Token已失效，请重新登录")
+		return
+	}
+	utils.Success(c, tokens, "刷新成功")
+}
+
+// UserLogout 登出当前会话：吊销传入的刷新 Token（访问 Token 到期自然失效）
+// @Summary 登出
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Param data body RefreshTokenReq true "刷新Token"
+// @Success 200 {object} utils.Response
+// @Router /api/user/logout [post]
+func UserLogout(c *gin.Context) {
+	var req RefreshTokenReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	userId, _ := c.Get("userId")
+	if err := model.RevokeRefreshToken(userId.(uint64), utils.HashRefreshToken(req.RefreshToken)); err != nil {
+		utils.Fail(c, "登出失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "已登出")
+}
+
+// UserLogoutAll 登出所有会话：吊销全部刷新 Token 并递增 token_version，已签发的访问 Token 立即失效
+// @Summary 登出所有会话
+// @Tags 用户管理
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Success 200 {object} utils.Response
+// @Router /api/user/logout-all [post]
+func UserLogoutAll(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	if err := model.RevokeUserRefreshTokens(userId.(uint64)); err != nil {
+		utils.Fail(c, "登出失败: "+err.Error())
+		return
+	}
+	if err := model.BumpTokenVersion(userId.(uint64)); err != nil {
+		utils.Fail(c, "登出失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "已登出所有会话")
+}
+
+// ChangePasswordReq 修改密码请求
+type ChangePasswordReq struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+// UserChangePassword 修改密码：校验旧密码，成功后所有会话失效，需重新登录
+// @Summary 修改密码
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Param data body ChangePasswordReq true "新旧密码"
+// @Success 200 {object} utils.Response
+// @Router /api/user/password [post]
+func UserChangePassword(c *gin.Context) {
+	var req ChangePasswordReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	username, _ := c.Get("username")
+	user, err := model.GetUserByUsername(username.(string))
+	if err != nil || user == nil {
+		utils.Fail(c, "用户不存在")
+		return
+	}
+	if !utils.CheckPassword(req.OldPassword, user.Password) {
+		utils.Fail(c, "原密码错误")
+		return
+	}
+	if err := user.UpdatePassword(req.NewPassword); err != nil {
+		utils.Fail(c, "修改失败: "+err.Error())
+		return
+	}
+	if err := model.RevokeUserRefreshTokens(user.Id); err != nil {
+		utils.Fail(c, "密码已修改，但吊销会话失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "密码已修改，请重新登录")
+}
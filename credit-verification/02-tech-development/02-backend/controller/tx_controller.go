@@ -0,0 +1,350 @@
+// controller/tx_controller.go 后端链上交易队列与交易台账查询
+package controller
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/service"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+// TxQueueList 查看后端交易队列（pending / mined / replaced / failed）
+func TxQueueList(c *gin.Context) {
+	list := utils.DefaultTxQueue.Snapshot()
+	stats := map[string]int{
+		utils.TxStatusPending:  0,
+		utils.TxStatusMined:    0,
+		utils.TxStatusReplaced: 0,
+		utils.TxStatusFailed:   0,
+	}
+	for _, tx := range list {
+		stats[tx.Status]++
+	}
+	utils.Success(c, gin.H{"stats": stats, "list": list}, "查询成功")
+}
+
+// TxDetail 按哈希查询后端发出的链上交易状态（submitted / mined / reverted / replaced / failed），需 tx:read
+func TxDetail(c *gin.Context) {
+	hash := c.Param("hash")
+	if !isTxHash(hash) {
+		utils.Fail(c, "交易哈希格式错误")
+		return
+	}
+	tx, err := model.GetChainTxByHash(common.HexToHash(hash).Hex())
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	if tx == nil {
+		utils.Fail(c, "交易不存在")
+		return
+	}
+	utils.Success(c, tx, "查询成功")
+}
+
+// TxList 链上交易台账分页列表（可按 status 过滤）
+func TxList(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 20
+	}
+	list, total, err := model.ListChainTxs(c.Query("status"), page, size)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"list": list, "total": total, "page": page, "size": size}, "查询成功")
+}
+
+// walletTxPerms 钱包签名模式下允许经 /tx/submit 提交的合约方法及所需权限
+// recordCredit 需落库学分记录，走 /credit/record/submit
+var walletTxPerms = map[string]string{
+	"assignRole":      model.PermRoleAssign,
+	"revokeRole":      model.PermRoleAssign,
+	"approveCredit":   model.PermCreditApprove,
+	"rejectCredit":    model.PermCreditReject,
+	"approveCredits":  model.PermCreditApprove,
+	"rejectCredits":   model.PermCreditReject,
+	"revokeCredit":    model.PermCreditRevoke,
+	"supersedeCredit": model.PermCreditApprove,
+}
+
+// creditAuditMethods 学分审核类合约方法及对应的学分事件
+var creditAuditMethods = map[string]string{
+	"approveCredit":  model.CreditEventApproved,
+	"approveCredits": model.CreditEventApproved,
+	"rejectCredit":   model.CreditEventRejected,
+	"rejectCredits":  model.CreditEventRejected,
+	"revokeCredit":   model.CreditEventRevoked,
+}
+
+// roleTxActions 需登记角色变更的合约方法
+var roleTxActions = map[string]string{
+	"assignRole": model.RoleActionAssign,
+	"revokeRole": model.RoleActionRevoke,
+}
+
+// TxSubmitReq 提交钱包签名后的原始交易（revokeRole 须附撤销原因，rejectCredit(s) / revokeCredit 须附原因原文，
+// approveCredit(s) 须附审核意见，supersedeCredit 须附更正申请ID与审核意见）
+type TxSubmitReq struct {
+	RawTx        string `json:"raw_tx" binding:"required"`
+	Reason       string `json:"reason"`
+	Comment      string `json:"comment"`
+	CorrectionId int64  `json:"correction_id"`
+}
+
+// TxSubmit 钱包签名模式：校验签名地址为当前用户绑定地址、方法与角色匹配后广播；
+// assignRole / revokeRole 登记角色变更，打包后由角色服务写 users.role；approveCredit(s) / rejectCredit(s) / revokeCredit 广播后登记审核交易，
+// 打包成功后由审核回执任务记录审核结果与审核意见，approveCredits / rejectCredits 另等待打包并返回逐条结果
+// （驳回、撤销另校验原因哈希）；supersedeCredit 校验与更正申请一致后登记为已提交，打包后由后台任务关联新旧记录；其余落库由事件索引器或 /credit/sync 按链上结果完成
+func TxSubmit(c *gin.Context) {
+	var req TxSubmitReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	from, ok := boundAddress(c)
+	if !ok {
+		return
+	}
+	signed, err := utils.DecodeSignedTx(c.Request.Context(), req.RawTx)
+	if err != nil {
+		utils.Fail(c, err.Error())
+		return
+	}
+	if signed.From != from {
+		utils.FailWithCode(c, 403, "交易签名地址与当前账号绑定地址不一致")
+		return
+	}
+	needPerm, allowed := walletTxPerms[signed.Method]
+	role, _ := c.Get("role")
+	if !allowed || !model.RoleHasPermission(role.(string), needPerm) {
+		utils.FailWithCode(c, 403, "无权提交该合约方法: "+signed.Method)
+		return
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if signed.Method == "revokeRole" && req.Reason == "" {
+		utils.Fail(c, "撤销角色须填写原因 reason")
+		return
+	}
+	if _, ok := roleTxActions[signed.Method]; ok && len(signed.Args) == 2 {
+		if target, _ := signed.Args[0].(common.Address); target == from {
+			utils.FailWithCode(c, 403, "不能修改自己的角色")
+			return
+		}
+	}
+	var auditRows []*model.CreditRow
+	auditEvent, isAudit := creditAuditMethods[signed.Method]
+	if isAudit {
+		if req.Comment, ok = checkAuditComment(c, req.Comment, auditEvent == model.CreditEventApproved); !ok {
+			return
+		}
+		if auditRows, ok = checkSignedAudit(c, signed, auditEvent, req.Reason); !ok {
+			return
+		}
+	}
+	var correction *model.CreditCorrection
+	if signed.Method == "supersedeCredit" {
+		if req.Comment, ok = checkAuditComment(c, req.Comment, true); !ok {
+			return
+		}
+		if correction, ok = checkSignedSupersede(c, signed, req.CorrectionId); !ok {
+			return
+		}
+	}
+	if err := utils.SubmitSignedTx(context.Background(), signed); err != nil {
+		utils.Fail(c, err.Error())
+		return
+	}
+	txHash := signed.Tx.Hash().Hex()
+	data := gin.H{"tx_hash": txHash, "method": signed.Method}
+	if action, ok := roleTxActions[signed.Method]; ok && len(signed.Args) == 2 {
+		user, _ := signed.Args[0].(common.Address)
+		chainRole, _ := signed.Args[1].(string)
+		operatorId, _ := c.Get("userId")
+		changeId, err := service.TrackRoleChange(user, action, chainRole, req.Reason, txHash, operatorId.(uint64))
+		if err != nil {
+			utils.FailWithCode(c, 500, "交易已提交，登记角色变更失败: "+err.Error())
+			return
+		}
+		data["change_id"] = changeId
+	}
+	if isAudit {
+		userId, _ := c.Get("userId")
+		actor := model.CreditActor{UserId: userId.(uint64), Address: from.Hex()}
+		reason := req.Reason
+		if auditEvent == model.CreditEventApproved {
+			reason = ""
+		}
+		if signed.Method == "approveCredits" || signed.Method == "rejectCredits" {
+			// 批量交易与后端签名的批量审核同一流程：逐条登记后等待打包，按回执事件返回逐条结果
+			items := make([]CreditBatchItem, len(auditRows))
+			for i, row := range auditRows {
+				items[i] = CreditBatchItem{CreditId: row.Id, ContractCreditId: row.ContractCreditId.Int64, Result: "submitted"}
+			}
+			submitCreditBatchAudits(items, auditRows, auditEvent, actor, txHash, reason, req.Comment)
+			ctx, cancel := context.WithTimeout(c.Request.Context(), batchReceiptTimeout)
+			defer cancel()
+			hash, submitted, msg := waitCreditBatchReceipt(ctx, items, txHash)
+			data["tx_hash"], data["submitted"], data["items"] = hash, submitted, items
+			utils.Success(c, data, msg)
+			return
+		}
+		for _, row := range auditRows {
+			if _, err := model.SubmitCreditAudit(row, auditEvent, actor, txHash, reason, req.Comment); err != nil {
+				utils.FailWithCode(c, 500, fmt.Sprintf("交易已提交，登记学分 %d 审核交易失败: %v", row.Id, err))
+				return
+			}
+		}
+	}
+	if correction != nil {
+		userId, _ := c.Get("userId")
+		if _, err := model.SubmitCreditCorrection(correction.Id, userId.(uint64), req.Comment, txHash); err != nil {
+			utils.FailWithCode(c, 500, "交易已提交，记录更正状态失败: "+err.Error())
+			return
+		}
+		data["correction_id"] = correction.Id
+	}
+	utils.Success(c, data, "交易已提交")
+}
+
+// checkSignedSupersede 钱包签名的 supersedeCredit：参数须与待审核的更正申请（原记录链上ID、新课程名、新成绩）一致
+func checkSignedSupersede(c *gin.Context, signed *utils.SignedContractTx, correctionId int64) (*model.CreditCorrection, bool) {
+	if correctionId <= 0 {
+		utils.Fail(c, "更正学分须附 correction_id")
+		return nil, false
+	}
+	corr, row, ok := correctionForReview(c, correctionId)
+	if !ok || !checkCorrectionOffering(c, corr, row) {
+		return nil, false
+	}
+	originalId, _ := signed.Args[0].(*big.Int)
+	courseName, _ := signed.Args[1].(string)
+	score, _ := signed.Args[2].(uint8)
+	if originalId == nil || !originalId.IsInt64() || originalId.Int64() != row.ContractCreditId.Int64 ||
+		courseName != corr.CourseName || score != uint8(corr.Score) {
+		utils.Fail(c, "交易参数与更正申请不一致")
+		return nil, false
+	}
+	return corr, true
+}
+
+// checkSignedAudit 钱包签名的审核/驳回（单条或批量）/撤销：审核与驳回的学分须为本地待审核记录，撤销的须为已审核记录；
+// 驳回、撤销时 reason 的 keccak256 须与交易参数一致
+func checkSignedAudit(c *gin.Context, signed *utils.SignedContractTx, event, reason string) ([]*model.CreditRow, bool) {
+	if event != model.CreditEventApproved {
+		if reason == "" {
+			utils.Fail(c, "驳回或撤销学分须填写原因 reason")
+			return nil, false
+		}
+		reasonHash, _ := signed.Args[1].([32]byte)
+		if utils.RejectReasonHash(reason) != reasonHash {
+			utils.Fail(c, "reason 与交易中的原因哈希不一致")
+			return nil, false
+		}
+	}
+	var ids []*big.Int
+	switch arg := signed.Args[0].(type) {
+	case *big.Int:
+		ids = []*big.Int{arg}
+	case []*big.Int:
+		ids = arg
+	}
+	if len(ids) == 0 {
+		utils.Fail(c, signed.Method+" 参数异常")
+		return nil, false
+	}
+	fromStatus := "pending"
+	if event == model.CreditEventRevoked {
+		fromStatus = "approved"
+	}
+	rows := make([]*model.CreditRow, 0, len(ids))
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if id == nil || !id.IsInt64() {
+			utils.Fail(c, signed.Method+" 参数异常")
+			return nil, false
+		}
+		if seen[id.Int64()] {
+			utils.Fail(c, fmt.Sprintf("链上学分ID %d 重复", id.Int64()))
+			return nil, false
+		}
+		seen[id.Int64()] = true
+		row, ok := creditByContractId(c, id.Int64(), fromStatus)
+		if !ok || !checkNoOpenAudit(c, row.Id) {
+			return nil, false
+		}
+		rows = append(rows, row)
+	}
+	return rows, true
+}
+
+// creditByContractId 按链上学分ID取本地记录并校验当前状态，失败时已写响应
+func creditByContractId(c *gin.Context, contractCreditId int64, status string) (*model.CreditRow, bool) {
+	row, err := model.GetCreditByContractId(contractCreditId)
+	if err != nil || row == nil {
+		utils.Fail(c, fmt.Sprintf("链上学分ID %d 对应的学分记录不存在", contractCreditId))
+		return nil, false
+	}
+	if row.Status != status {
+		utils.Fail(c, fmt.Sprintf("学分记录 %d 当前状态为 %s，无法处理", row.Id, row.Status))
+		return nil, false
+	}
+	if row.Locked {
+		utils.Fail(c, fmt.Sprintf("学分记录 %d 所属学期已关闭，记录已锁定", row.Id))
+		return nil, false
+	}
+	return row, true
+}
+
+// boundAddress 当前登录用户绑定的钱包地址，未绑定时已写响应
+func boundAddress(c *gin.Context) (common.Address, bool) {
+	userId, _ := c.Get("userId")
+	user, err := model.GetUserById(userId.(uint64))
+	if err != nil || user == nil {
+		utils.Fail(c, "用户不存在")
+		return common.Address{}, false
+	}
+	if !user.Address.Valid || !common.IsHexAddress(user.Address.String) {
+		utils.Fail(c, "请先绑定钱包地址")
+		return common.Address{}, false
+	}
+	return common.HexToAddress(user.Address.String), true
+}
+
+// respondUnsignedTx 钱包签名模式：以当前用户绑定地址为发送方构造未签名交易返回前端
+func respondUnsignedTx(c *gin.Context, method string, args ...interface{}) {
+	from, ok := boundAddress(c)
+	if !ok {
+		return
+	}
+	unsigned, err := utils.BuildUnsignedTx(c.Request.Context(), from, method, args...)
+	if err != nil {
+		utils.Fail(c, "构造交易失败: "+err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"unsigned_tx": unsigned}, "请在钱包中签名后提交到 /api/tx/submit")
+}
+
+// isTxHash 校验 0x 前缀的 32 字节十六进制哈希
+func isTxHash(s string) bool {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -4,21 +4,23 @@ package controller
 import (
 	"campus-credit-backend/model"
 	"campus-credit-backend/utils"
-	"database/sql"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gin-gonic/gin"
 )
 
-// UserLogin 用户登录接口（支持用户名密码 + 钱包地址两种方式）
+// UserLogin 用户登录接口（支持用户名密码 + 钱包签名两种方式）
 // @Summary 用户登录
-// @Description 传 address 为钱包登录（从链上取角色）；传 username+password 为账号密码登录
+// @Description 传 message+signature 为钱包登录（SIWE，消息由 /api/user/nonce 获取）；传 username+password 为账号密码登录
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param data body struct{Address string `json:"address"`;Username string `json:"username"`;Password string `json:"password"`} true "登录信息"
+// @Param data body struct{Message string `json:"message"`;Signature string `json:"signature"`;Username string `json:"username"`;Password string `json:"password"`} true "登录信息"
 // @Success 200 {object} utils.Response{data=struct{Token string;User model.User}}
 // @Failure 400 {object} utils.Response
 // @Router /api/user/login [post]
@@ -31,24 +33,30 @@ func UserLogin(c *gin.Context) {
 		}
 	}()
 	var req struct {
-		Address  string `json:"address"`
-		Username string `json:"username"`
-		Password string `json:"password"`
+		Message   string `json:"message"`
+		Signature string `json:"signature"`
+		Address   string `json:"address"`
+		Username  string `json:"username"`
+		Password  string `json:"password"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
 
-	// 钱包登录：仅传 address
+	// 钱包登录：SIWE 消息 + 签名
+	if req.Message != "" || req.Signature != "" {
+		userLoginBySiwe(c, req.Message, req.Signature)
+		return
+	}
 	if req.Address != "" {
-		userLoginByAddress(c, req.Address)
+		utils.Fail(c, "钱包登录需签名：请先调用 /api/user/nonce 获取消息并在钱包中签名")
 		return
 	}
 
 	// 账号密码登录
 	if req.Username == "" || req.Password == "" {
-		utils.Fail(c, "请提供 username 与 password，或提供 address 进行钱包登录")
+		utils.Fail(c, "请提供 username 与 password，或提供 message 与 signature 进行钱包登录")
 		return
 	}
 	user, err := model.GetUserByUsername(req.Username)
@@ -60,15 +68,137 @@ func UserLogin(c *gin.Context) {
 		utils.Fail(c, "用户名或密码错误")
 		return
 	}
-	token, err := utils.GenerateToken(user.Id, user.Username, user.Role)
+	issueLoginTokens(c, user)
+}
+
+// UserNonce 钱包登录第一步：为地址签发一次性 nonce，并返回待签名的 SIWE 消息
+// @Summary 获取钱包登录挑战
+// @Tags 用户管理
+// @Produce json
+// @Param address query string true "钱包地址"
+// @Success 200 {object} utils.Response{data=struct{Nonce string;Message string}}
+// @Router /api/user/nonce [get]
+func UserNonce(c *gin.Context) {
+	issueSiweChallenge(c, c.Query("address"), utils.GlobalConfig.Siwe.Statement, "")
+}
+
+// issueSiweChallenge 为地址签发一次性 nonce 并返回 SIWE 消息；requestId 区分用途（登录为空，绑定为 bind:<用户ID>）
+func issueSiweChallenge(c *gin.Context, address, statement, requestId string) {
+	address = strings.TrimSpace(address)
+	if !common.IsHexAddress(address) {
+		utils.Fail(c, "钱包地址无效")
+		return
+	}
+	chainID, err := utils.EthClient.ChainID(c.Request.Context())
+	if err != nil {
+		utils.Fail(c, "获取链ID失败: "+err.Error())
+		return
+	}
+	nonce, err := utils.NewSiweNonce()
 	if err != nil {
-		utils.Fail(c, "生成Token失败: "+err.Error())
+		utils.Fail(c, "生成nonce失败: "+err.Error())
 		return
 	}
-	utils.Success(c, gin.H{
-		"token": token,
-		"user":  user,
-	}, "登录成功")
+	ttl := utils.GlobalConfig.Siwe.NonceTtlSeconds
+	if ttl <= 0 {
+		ttl = 300
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(ttl) * time.Second)
+	addr := common.HexToAddress(address)
+	if err := model.CreateAuthNonce(nonce, normalAddress(addr.Hex()), expiresAt); err != nil {
+		utils.Fail(c, "保存nonce失败: "+err.Error())
+		return
+	}
+	_ = model.PurgeExpiredAuthNonces()
+
+	domain, uri, ok := siweDomain(c)
+	if !ok {
+		return
+	}
+	msg := &utils.SiweMessage{
+		Domain:         domain,
+		Address:        addr,
+		Statement:      statement,
+		Uri:            uri,
+		Version:        "1",
+		ChainId:        chainID.Int64(),
+		Nonce:          nonce,
+		IssuedAt:       now,
+		ExpirationTime: &expiresAt,
+		RequestId:      requestId,
+	}
+	utils.Success(c, gin.H{"nonce": nonce, "message": msg.String(), "expires_at": expiresAt}, "请在钱包中签名该消息")
+}
+
+// siweDomain 期望的 SIWE domain/URI，只取配置：请求头（Origin/Host）可被钓鱼站点控制，不能作为依据；
+// 未配置 siwe.domain / siwe.uri 时拒绝钱包签名，失败时已写响应
+func siweDomain(c *gin.Context) (string, string, bool) {
+	domain, uri := utils.GlobalConfig.Siwe.Domain, utils.GlobalConfig.Siwe.Uri
+	if domain == "" || uri == "" {
+		utils.FailWithCode(c, 500, "服务端未配置 siwe.domain / siwe.uri，钱包签名不可用")
+		return "", "", false
+	}
+	return domain, uri, true
+}
+
+// userLoginBySiwe 钱包签名登录：校验 SIWE 消息与签名、核销 nonce 后，按地址查库，无则从链上取角色自动建用户
+func userLoginBySiwe(c *gin.Context, message, signature string) {
+	signer, ok := verifySiwe(c, message, signature, "")
+	if !ok {
+		return
+	}
+	userLoginByAddress(c, signer.Hex())
+}
+
+// verifySiwe 校验 SIWE 消息（domain/URI/链ID/有效期/用途）与签名并核销 nonce，返回签名地址；失败时已写响应
+func verifySiwe(c *gin.Context, message, signature, requestId string) (common.Address, bool) {
+	if message == "" || signature == "" {
+		utils.Fail(c, "请同时提供 message 与 signature")
+		return common.Address{}, false
+	}
+	msg, err := utils.ParseSiweMessage(message)
+	if err != nil {
+		utils.Fail(c, err.Error())
+		return common.Address{}, false
+	}
+	if msg.RequestId != requestId {
+		utils.FailWithCode(c, 401, "签名消息用途不匹配")
+		return common.Address{}, false
+	}
+	chainID, err := utils.EthClient.ChainID(c.Request.Context())
+	if err != nil {
+		utils.Fail(c, "获取链ID失败: "+err.Error())
+		return common.Address{}, false
+	}
+	domain, uri, ok := siweDomain(c)
+	if !ok {
+		return common.Address{}, false
+	}
+	if err := msg.Validate(domain, uri, chainID.Int64(), time.Now()); err != nil {
+		utils.FailWithCode(c, 401, err.Error())
+		return common.Address{}, false
+	}
+	signer, err := utils.RecoverPersonalSign(message, signature)
+	if err != nil {
+		utils.FailWithCode(c, 401, err.Error())
+		return common.Address{}, false
+	}
+	if signer != msg.Address {
+		utils.FailWithCode(c, 401, "签名地址与消息中的地址不一致")
+		return common.Address{}, false
+	}
+	// 签名校验通过后再核销，防止无效签名耗尽他人 nonce
+	ok, err = model.ConsumeAuthNonce(msg.Nonce, normalAddress(signer.Hex()))
+	if err != nil {
+		utils.Fail(c, "核销nonce失败: "+err.Error())
+		return common.Address{}, false
+	}
+	if !ok {
+		utils.FailWithCode(c, 401, "nonce 无效、已使用或已过期，请重新获取")
+		return common.Address{}, false
+	}
+	return signer, true
 }
 
 func panicToStr(r interface{}) string {
@@ -87,7 +217,7 @@ func normalAddress(s string) string {
 	return strings.ToLower(strings.TrimSpace(s))
 }
 
-// userLoginByAddress 钱包地址登录：从链上取角色，无则自动建用户
+// userLoginByAddress 已验证钱包所有权后登录：从链上取角色，无则自动建用户（仅由 userLoginBySiwe 调用）
 func userLoginByAddress(c *gin.Context, address string) {
 	defer func() {
 		if r := recover(); r != nil && !c.Writer.Written() {
@@ -107,12 +237,7 @@ func userLoginByAddress(c *gin.Context, address string) {
 	}
 	if user != nil {
 		// 数据库已有该地址：直接使用 DB 角色，不按链上覆盖（避免 Postman 注册的 admin 被链上未分配而变成 student）
-		token, err := utils.GenerateToken(user.Id, user.Username, user.Role)
-		if err != nil {
-			utils.Fail(c, "生成Token失败: "+err.Error())
-			return
-		}
-		utils.Success(c, gin.H{"token": token, "user": user}, "登录成功")
+		issueLoginTokens(c, user)
 		return
 	}
 	// 新钱包用户：从链上取角色再建用户，链上无则默认 student
@@ -130,15 +255,7 @@ func userLoginByAddress(c *gin.Context, address string) {
 		utils.Fail(c, "创建用户失败，请重试")
 		return
 	}
-	token, err := utils.GenerateToken(user.Id, user.Username, user.Role)
-	if err != nil {
-		utils.Fail(c, "生成Token失败: "+err.Error())
-		return
-	}
-	utils.Success(c, gin.H{
-		"token": token,
-		"user":  user,
-	}, "登录成功")
+	issueLoginTokens(c, user)
 }
 
 // UserInfo 获取当前用户信息
@@ -168,7 +285,7 @@ func UserInfo(c *gin.Context) {
 
 // UserUpdate 更新用户信息
 // @Summary 更新用户信息
-// @Description 需登录，更新地址/角色（仅管理员可改角色）
+// @Description 需登录；不能修改自己的角色（管理员经 /api/users/:id/role 为其他用户设置），地址只能经签名绑定接口修改
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -195,14 +312,15 @@ func UserUpdate(c *gin.Context) {
 		return
 	}
 
-	// 更新地址（规范化后入库，便于钱包登录时匹配）
-	if req.Address != "" {
-		user.Address = sql.NullString{String: normalAddress(req.Address), Valid: true}
+	// 地址须经签名证明所有权，只能走绑定接口
+	if req.Address != "" && !(user.Address.Valid && normalAddress(user.Address.String) == normalAddress(req.Address)) {
+		utils.Fail(c, "修改钱包地址请使用 /api/user/bind-address（需钱包签名）")
+		return
 	}
-	// 仅管理员可更新角色
-	currentRole, _ := c.Get("role")
-	if r, ok := currentRole.(string); ok && strings.ToLower(r) == "admin" && req.Role != "" {
-		user.Role = strings.ToLower(strings.TrimSpace(req.Role))
+	// 不能修改自己的角色
+	if req.Role != "" && !strings.EqualFold(strings.TrimSpace(req.Role), user.Role) {
+		utils.FailWithCode(c, 403, "不能修改自己的角色，请由管理员经 /api/users/:id/role 设置")
+		return
 	}
 
 	// 执行更新
@@ -213,27 +331,42 @@ func UserUpdate(c *gin.Context) {
 	utils.Success(c, nil, "更新成功")
 }
 
-// BindAddress 绑定钱包地址到当前账号（先释放被 wallet_ 占用的同地址，再绑定）
+// BindNonce 绑定钱包第一步：为目标地址签发绑定用途的 SIWE 消息（Request ID 为 bind:<当前用户ID>）
+// @Summary 获取绑定钱包挑战
+// @Tags 用户管理
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Param address query string true "要绑定的钱包地址"
+// @Success 200 {object} utils.Response{data=struct{Nonce string;Message string}}
+// @Router /api/user/bind-nonce [get]
+func BindNonce(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	username, _ := c.Get("username")
+	statement := fmt.Sprintf("将该钱包地址绑定到账号 %v", username)
+	issueSiweChallenge(c, c.Query("address"), statement, bindRequestId(userId.(uint64)))
+}
+
+func bindRequestId(userId uint64) string {
+	return fmt.Sprintf("bind:%d", userId)
+}
+
+// BindAddress 绑定钱包地址到当前账号（需目标地址签名）；若该地址属于自动创建的 wallet_ 用户，则将其历史合并到当前账号
 // @Summary 绑定钱包地址
-// @Description 登录后调用，将钱包地址绑到当前账号；若该地址已被自动创建的 wallet_ 占用则先删除再绑定
+// @Description 登录后先调 /api/user/bind-nonce 获取消息，用目标钱包签名后提交；被其他正式账号占用的地址不可绑定
 // @Tags 用户管理
 // @Accept json
 // @Produce json
 // @Header 200 {string} Authorization "Bearer Token"
-// @Param data body struct{Address string `json:"address" binding:"required"`} true "钱包地址"
+// @Param data body struct{Message string `json:"message" binding:"required"`;Signature string `json:"signature" binding:"required"`} true "绑定签名"
 // @Success 200 {object} utils.Response
 // @Router /api/user/bind-address [post]
 func BindAddress(c *gin.Context) {
 	var req struct {
-		Address string `json:"address" binding:"required"`
+		Message   string `json:"message" binding:"required"`
+		Signature string `json:"signature" binding:"required"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.Fail(c, "请提供 address")
-		return
-	}
-	addr := normalAddress(req.Address)
-	if addr == "" {
-		utils.Fail(c, "钱包地址无效")
+		utils.Fail(c, "请提供 message 与 signature（先调用 /api/user/bind-nonce 获取并在钱包中签名）")
 		return
 	}
 
@@ -243,6 +376,11 @@ func BindAddress(c *gin.Context) {
 		utils.Fail(c, "用户不存在")
 		return
 	}
+	signer, ok := verifySiwe(c, req.Message, req.Signature, bindRequestId(user.Id))
+	if !ok {
+		return
+	}
+	addr := normalAddress(signer.Hex())
 
 	// 已绑定该地址
 	if user.Address.Valid && normalAddress(user.Address.String) == addr {
@@ -250,31 +388,69 @@ func BindAddress(c *gin.Context) {
 		return
 	}
 
-	// 若该地址被 wallet_ 用户占用，先删除以便当前用户可绑定（表有 UNIQUE(address)）
-	_, _ = model.FreeAddressFromWalletUser(addr)
-
-	// 检查是否被其他非 wallet_ 账号占用
-	other, _ := model.GetUserByAddress(addr)
+	other, err := model.GetUserByAddress(addr)
+	if err != nil {
+		utils.Fail(c, "查询用户失败: "+err.Error())
+		return
+	}
 	if other != nil && other.Id != user.Id {
-		utils.Fail(c, "该地址已被其他账号绑定")
+		if !model.IsWalletUser(other) {
+			utils.Fail(c, "该地址已被其他账号绑定")
+			return
+		}
+		// 自动创建的 wallet_ 用户：合并到当前账号（转移其任务记录、保留更高角色），原用户保留并标记 merged_into
+		if err := model.MergeWalletUser(other, user, addr, user.Id); err != nil {
+			utils.Fail(c, "合并钱包用户失败: "+err.Error())
+			return
+		}
+		utils.Success(c, gin.H{"merged_user_id": other.Id}, "绑定成功，已合并该钱包的历史记录")
 		return
 	}
 
-	user.Address = sql.NullString{String: addr, Valid: true}
-	if err := user.UpdateAddress(addr); err != nil {
+	if err := model.BindUserAddress(user, addr, user.Id); err != nil {
 		utils.Fail(c, "绑定失败: "+err.Error())
 		return
 	}
 	utils.Success(c, nil, "绑定成功，可使用该钱包登录并保持当前角色")
 }
 
+// UnbindAddress 解绑当前账号的钱包地址（自动创建的 wallet_ 用户无密码，不允许解绑）
+// @Summary 解绑钱包地址
+// @Tags 用户管理
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Success 200 {object} utils.Response
+// @Router /api/user/unbind-address [post]
+func UnbindAddress(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	user, err := model.GetUserById(userId.(uint64))
+	if err != nil || user == nil {
+		utils.Fail(c, "用户不存在")
+		return
+	}
+	if !user.Address.Valid || user.Address.String == "" {
+		utils.Fail(c, "当前账号未绑定钱包地址")
+		return
+	}
+	if model.IsWalletUser(user) {
+		utils.Fail(c, "钱包登录创建的账号不能解绑地址")
+		return
+	}
+	if err := model.UnbindUserAddress(user, user.Id); err != nil {
+		utils.Fail(c, "解绑失败: "+err.Error())
+		return
+	}
+	utils.Success(c, nil, "解绑成功")
+}
+
 // UserRegister 用户注册（测试用）
+// 注册只建 student 账号：钱包地址只能经签名绑定流程写入，角色由管理员经链上分配提升
 // @Summary 用户注册
-// @Description 新增用户（测试环境用）
+// @Description 新增 student 用户（测试环境用）
 // @Tags 用户管理
 // @Accept json
 // @Produce json
-// @Param data body struct{Username string `json:"username" binding:"required"`;Password string `json:"password" binding:"required"`;Address string `json:"address"`;Role string `json:"role"`} true "注册信息"
+// @Param data body struct{Username string `json:"username" binding:"required"`;Password string `json:"password" binding:"required"`} true "注册信息"
 // @Success 200 {object} utils.Response
 // @Failure 400 {object} utils.Response
 // @Router /api/user/register [post]
@@ -282,35 +458,16 @@ func UserRegister(c *gin.Context) {
 	var req struct {
 		Username string `json:"username" binding:"required"`
 		Password string `json:"password" binding:"required"`
-		Address  string `json:"address"`
-		Role     string `json:"role" default:"student"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		utils.Fail(c, "参数错误: "+err.Error())
 		return
 	}
 
-	// 角色转小写并验证
-	roleLower := strings.ToLower(strings.TrimSpace(req.Role))
-	if roleLower == "" {
-		roleLower = "student"
-	}
-	allowedRoles := map[string]bool{"student": true, "teacher": true, "admin": true}
-	if !allowedRoles[roleLower] {
-		utils.Fail(c, "角色只能是 student/teacher/admin")
-		return
-	}
-
-	// 创建用户（角色、地址统一规范化入库）
-	addrVal := ""
-	if req.Address != "" {
-		addrVal = normalAddress(req.Address)
-	}
 	user := &model.User{
 		Username: req.Username,
 		Password: req.Password,
-		Address:  sql.NullString{String: addrVal, Valid: addrVal != ""},
-		Role:     roleLower,
+		Role:     "student",
 	}
 	if err := user.CreateUser(); err != nil {
 		utils.Fail(c, "注册失败: "+err.Error())
@@ -318,3 +475,26 @@ func UserRegister(c *gin.Context) {
 	}
 	utils.Success(c, nil, "注册成功")
 }
+
+// BindLogs 钱包地址绑定审计记录：普通用户查自己，有 user:read_all 权限者可按 user_id 查询或查全部
+// @Summary 地址绑定审计
+// @Tags 用户管理
+// @Produce json
+// @Header 200 {string} Authorization "Bearer Token"
+// @Param user_id query int false "用户ID（仅管理员）"
+// @Success 200 {object} utils.Response{data=[]model.AddressBindLog}
+// @Router /api/user/bind-logs [get]
+func BindLogs(c *gin.Context) {
+	userId, _ := c.Get("userId")
+	role, _ := c.Get("role")
+	target := userId.(uint64)
+	if model.RoleHasPermission(role.(string), model.PermUserReadAll) {
+		target, _ = strconv.ParseUint(c.Query("user_id"), 10, 64)
+	}
+	list, err := model.ListAddressBindLogs(target, 200)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
@@ -0,0 +1,119 @@
+// controller/user_role_controller.go 管理员为其他用户设置本地角色（不经链上），每次设置记审计
+package controller
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UserRoleSetReq 设置用户角色（原因必填，随审计记录保存）
+type UserRoleSetReq struct {
+	Role   string `json:"role" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// UserRoleSet 设置其他用户的本地角色
+// @Summary 设置用户角色
+// @Description 需 role:manage；不能修改自己的角色，拥有全部权限（*）的角色只能由超级管理员分配或收回；
+// @Description 新角色需要的链上权限位（录入/审核）须当前角色已具备，否则请经 /api/role/assign 上链分配
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param data body UserRoleSetReq true "角色与原因"
+// @Success 200 {object} utils.Response
+// @Router /api/users/{id}/role [post]
+func UserRoleSet(c *gin.Context) {
+	targetId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, "无效的用户ID")
+		return
+	}
+	var req UserRoleSetReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.Fail(c, "参数错误: "+err.Error())
+		return
+	}
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || utf8.RuneCountInString(req.Reason) > 256 {
+		utils.Fail(c, "原因不能为空且不能超过256个字符")
+		return
+	}
+
+	operatorId, _ := c.Get("userId")
+	if targetId == operatorId.(uint64) {
+		utils.FailWithCode(c, 403, "不能修改自己的角色")
+		return
+	}
+	target, err := model.GetUserById(targetId)
+	if err != nil {
+		utils.Fail(c, "查询用户失败: "+err.Error())
+		return
+	}
+	if target == nil {
+		utils.Fail(c, "用户不存在")
+		return
+	}
+	def, err := model.GetRoleDef(role)
+	if err != nil || def == nil {
+		utils.Fail(c, "角色不存在: "+role)
+		return
+	}
+	if !checkAllRoleAccess(c, role) || !checkAllRoleAccess(c, target.Role) {
+		return
+	}
+	needTeacher, needAdmin := model.ChainFlagsRequired(role)
+	hasTeacher, hasAdmin := model.ChainFlagsRequired(target.Role)
+	if (needTeacher && !hasTeacher) || (needAdmin && !hasAdmin) {
+		utils.Fail(c, "角色 "+role+" 需要链上权限位，请经 /api/role/assign 上链分配")
+		return
+	}
+
+	oldRole, err := model.AssignUserRole(targetId, role, req.Reason, operatorId.(uint64))
+	if err != nil {
+		utils.FailWithCode(c, 500, "设置角色失败: "+err.Error())
+		return
+	}
+	if target.Address.Valid && target.Address.String != "" {
+		utils.DefaultRoleCache.Invalidate(target.Address.String)
+	}
+	utils.Success(c, gin.H{"user_id": targetId, "old_role": oldRole, "new_role": role}, "角色已设置，该用户需重新登录")
+}
+
+// UserRoleLogs 用户的本地角色设置记录
+// @Summary 角色设置记录
+// @Tags 用户管理
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {object} utils.Response
+// @Router /api/users/{id}/role-logs [get]
+func UserRoleLogs(c *gin.Context) {
+	targetId, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		utils.Fail(c, "无效的用户ID")
+		return
+	}
+	list, err := model.ListUserRoleLogs(targetId, 200)
+	if err != nil {
+		utils.Fail(c, "查询失败: "+err.Error())
+		return
+	}
+	utils.Success(c, list, "查询成功")
+}
+
+// isSelfAddress 目标地址是否为当前登录用户绑定的地址
+func isSelfAddress(c *gin.Context, address string) bool {
+	userId, _ := c.Get("userId")
+	user, err := model.GetUserById(userId.(uint64))
+	if err != nil || user == nil || !user.Address.Valid || user.Address.String == "" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(user.Address.String), strings.TrimSpace(address))
+}
@@ -2,9 +2,12 @@
 package main
 
 import (
+	"context"
 	"log" // 补充导入log包（原代码中用到log.Printf）
 
+	"campus-credit-backend/model"
 	"campus-credit-backend/router"
+	"campus-credit-backend/service"
 	"campus-credit-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,6 +18,20 @@ func main() {
 	utils.InitConfig()
 	utils.InitMySQL()
 	utils.InitEthClient() // 你的原有以太坊客户端初始化
+	utils.InitRoleCache()
+	utils.InitJWTKeys()
+	if err := model.InitPermissions(); err != nil {
+		log.Fatalf("初始化角色权限失败: %v", err)
+	}
+
+	// 启动后台任务（交易台账 + 交易队列巡检 + 异步录入任务 + 审核回执 + 角色变更同步；合约事件索引器按配置开关）
+	service.StartTxLedger(context.Background())
+	go utils.RunTxQueue(context.Background())
+	service.StartCreditJobs(context.Background())
+	service.StartCreditCorrections(context.Background())
+	service.StartCreditAudits(context.Background())
+	service.StartRoleChanges(context.Background())
+	service.StartIndexer(context.Background())
 
 	// 2. 设置Gin运行模式（核心修复：改为包级别的gin.SetMode）
 	gin.SetMode(utils.GlobalConfig.Server.Mode) // 关键修正！
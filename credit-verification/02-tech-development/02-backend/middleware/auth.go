@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strings"
 
+	"campus-credit-backend/model"
 	"campus-credit-backend/utils"
 
 	"github.com/gin-gonic/gin"
@@ -49,16 +50,37 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 将用户信息存入上下文
+		// 校验 token_version：角色变更、改密码、登出所有会话后旧 Token 立即失效
+		role, version, exists, err := model.GetUserAuthState(claims.UserId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, utils.Response{
+				Code: 500,
+				Msg:  "鉴权失败: " + err.Error(),
+				Data: nil,
+			})
+			c.Abort()
+			return
+		}
+		if !exists || version != claims.Version {
+			c.JSON(http.StatusUnauthorized, utils.Response{
+				Code: 401,
+				Msg:  "Token已失效，请重新登录",
+				Data: nil,
+			})
+			c.Abort()
+			return
+		}
+
+		// 将用户信息存入上下文（角色以数据库为准）
 		c.Set("userId", claims.UserId)
 		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		c.Set("role", role)
 		c.Next()
 	}
 }
 
-// RoleMiddleware 角色权限校验中间件
-func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
+// RequirePermission 权限校验中间件：当前角色拥有任一所列权限即可访问（角色→权限见 roles/role_permissions 表）
+func RequirePermission(perms ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
@@ -71,24 +93,17 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 			return
 		}
 
-		// 校验角色是否在允许列表（不区分大小写，避免 Postman 等传 Admin 导致 403）
-		roleStr := strings.ToLower(role.(string))
-		allow := false
-		for _, r := range allowedRoles {
-			if roleStr == strings.ToLower(r) {
-				allow = true
-				break
+		for _, p := range perms {
+			if model.RoleHasPermission(role.(string), p) {
+				c.Next()
+				return
 			}
 		}
-		if !allow {
-			c.JSON(http.StatusForbidden, utils.Response{
-				Code: 403,
-				Msg:  "仅" + strings.Join(allowedRoles, "/") + "可访问",
-				Data: nil,
-			})
-			c.Abort()
-			return
-		}
-		c.Next()
+		c.JSON(http.StatusForbidden, utils.Response{
+			Code: 403,
+			Msg:  "缺少权限: " + strings.Join(perms, " 或 "),
+			Data: nil,
+		})
+		c.Abort()
 	}
 }
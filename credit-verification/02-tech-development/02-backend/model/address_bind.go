@@ -0,0 +1,147 @@
+// model/address_bind.go 钱包地址绑定/解绑/合并，及绑定审计记录
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 绑定审计动作
+const (
+	BindActionBind   = "bind"
+	BindActionUnbind = "unbind"
+	BindActionMerge  = "merge" // wallet_ 用户合并到正式账号
+)
+
+// AddressBindLog 地址绑定审计记录
+type AddressBindLog struct {
+	Id            int64         `json:"id"`
+	UserId        uint64        `json:"user_id"`
+	Address       string        `json:"address"`
+	Action        string        `json:"action"`
+	RelatedUserId sql.NullInt64 `json:"related_user_id"` // merge 时为被合并的 wallet_ 用户
+	OperatorId    uint64        `json:"operator_id"`
+	CreatedAt     time.Time     `json:"created_at"`
+}
+
+// IsWalletUser 是否为钱包首次登录自动创建的用户
+func IsWalletUser(u *User) bool {
+	return strings.HasPrefix(u.Username, "wallet_")
+}
+
+// BindUserAddress 为用户绑定新地址（原有地址记为解绑）
+func BindUserAddress(u *User, address string, operatorId uint64) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if u.Address.Valid && u.Address.String != "" {
+		if err := insertBindLog(tx, u.Id, u.Address.String, BindActionUnbind, nil, operatorId); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE users SET address = ?, updated_at = NOW() WHERE id = ?`, address, u.Id); err != nil {
+		return err
+	}
+	if err := insertBindLog(tx, u.Id, address, BindActionBind, nil, operatorId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnbindUserAddress 解绑用户当前地址
+func UnbindUserAddress(u *User, operatorId uint64) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE users SET address = NULL, updated_at = NOW() WHERE id = ?`, u.Id); err != nil {
+		return err
+	}
+	if err := insertBindLog(tx, u.Id, u.Address.String, BindActionUnbind, nil, operatorId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MergeWalletUser 将 wallet_ 用户合并到正式账号：转移其录入任务，地址改绑到目标账号，
+// 目标账号叠加 wallet_ 用户的链上权限位（不降级）；wallet_ 用户保留（address 置空并记录 merged_into）以便追溯
+func MergeWalletUser(from, to *User, address string, operatorId uint64) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE credit_jobs SET created_by = ? WHERE created_by = ?`, to.Id, from.Id); err != nil {
+		return err
+	}
+	// 先释放 wallet_ 用户的地址（address 唯一）
+	if _, err := tx.Exec(`UPDATE users SET address = NULL, merged_into = ?, updated_at = NOW() WHERE id = ?`, to.Id, from.Id); err != nil {
+		return err
+	}
+	// wallet_ 用户的角色来自链上分配，把它具备的权限位按分配规则叠加到目标账号
+	role := to.Role
+	if teacher, admin := ChainFlagsRequired(from.Role); teacher || admin {
+		if teacher {
+			role = dbRoleAfterAssign(role, "teacher")
+		}
+		if admin {
+			role = dbRoleAfterAssign(role, "admin")
+		}
+	}
+	if to.Address.Valid && to.Address.String != "" {
+		if err := insertBindLog(tx, to.Id, to.Address.String, BindActionUnbind, nil, operatorId); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE users SET token_version = token_version + (role <> ?), address = ?, role = ?, updated_at = NOW() WHERE id = ?`, role, address, role, to.Id); err != nil {
+		return err
+	}
+	if err := insertBindLog(tx, to.Id, address, BindActionMerge, &from.Id, operatorId); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertBindLog(tx *sql.Tx, userId uint64, address, action string, relatedUserId *uint64, operatorId uint64) error {
+	_, err := tx.Exec(
+		`INSERT INTO address_bind_logs (user_id, address, action, related_user_id, operator_id) VALUES (?, ?, ?, ?, ?)`,
+		userId, address, action, relatedUserId, operatorId,
+	)
+	return err
+}
+
+// ListAddressBindLogs 查询绑定审计记录（userId 为 0 时查全部），最新在前
+func ListAddressBindLogs(userId uint64, limit int) ([]AddressBindLog, error) {
+	query := `SELECT id, user_id, address, action, related_user_id, operator_id, created_at FROM address_bind_logs`
+	args := []interface{}{}
+	if userId > 0 {
+		query += ` WHERE user_id = ? OR related_user_id = ?`
+		args = append(args, userId, userId)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := utils.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []AddressBindLog
+	for rows.Next() {
+		var l AddressBindLog
+		if err := rows.Scan(&l.Id, &l.UserId, &l.Address, &l.Action, &l.RelatedUserId, &l.OperatorId, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
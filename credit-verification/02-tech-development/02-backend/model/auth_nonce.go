@@ -0,0 +1,36 @@
+// model/auth_nonce.go 钱包登录（SIWE）一次性 nonce：签发、核销（防重放）
+package model
+
+import (
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// CreateAuthNonce 为地址签发登录 nonce
+func CreateAuthNonce(nonce, address string, expiresAt time.Time) error {
+	_, err := utils.DB.Exec(
+		`INSERT INTO auth_nonces (nonce, address, expires_at) VALUES (?, ?, ?)`,
+		nonce, address, expiresAt,
+	)
+	return err
+}
+
+// ConsumeAuthNonce 核销 nonce：须存在、属于该地址、未使用且未过期；成功返回 true（同一 nonce 只能成功一次）
+func ConsumeAuthNonce(nonce, address string) (bool, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE auth_nonces SET used_at = NOW() WHERE nonce = ? AND address = ? AND used_at IS NULL AND expires_at > NOW()`,
+		nonce, address,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// PurgeExpiredAuthNonces 清理过期一天以上的 nonce
+func PurgeExpiredAuthNonces() error {
+	_, err := utils.DB.Exec(`DELETE FROM auth_nonces WHERE expires_at < NOW() - INTERVAL 1 DAY`)
+	return err
+}
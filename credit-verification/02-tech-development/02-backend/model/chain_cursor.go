@@ -0,0 +1,41 @@
+// model/chain_cursor.go 链上事件索引进度（区块游标），保证索引器重启后从断点继续
+package model
+
+import (
+	"database/sql"
+
+	"campus-credit-backend/utils"
+)
+
+// ChainCursor 索引游标：已处理到的区块号及其哈希（哈希用于重启/轮询时检测分叉）
+type ChainCursor struct {
+	Name        string `json:"name"`
+	BlockNumber uint64 `json:"block_number"`
+	BlockHash   string `json:"block_hash"`
+}
+
+// GetChainCursor 读取游标，不存在返回 nil
+func GetChainCursor(name string) (*ChainCursor, error) {
+	var cur ChainCursor
+	err := utils.DB.QueryRow(
+		`SELECT name, block_number, block_hash FROM chain_cursors WHERE name = ?`,
+		name,
+	).Scan(&cur.Name, &cur.BlockNumber, &cur.BlockHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cur, nil
+}
+
+// SaveChainCursor 写入/覆盖游标
+func SaveChainCursor(name string, blockNumber uint64, blockHash string) error {
+	_, err := utils.DB.Exec(
+		`INSERT INTO chain_cursors (name, block_number, block_hash) VALUES (?, ?, ?)
+		 ON DUPLICATE KEY UPDATE block_number = VALUES(block_number), block_hash = VALUES(block_hash)`,
+		name, blockNumber, blockHash,
+	)
+	return err
+}
@@ -0,0 +1,162 @@
+// model/chain_tx.go 后端发出的链上交易台账（chain_transactions），由交易队列回调与回执巡检更新
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 台账中的交易状态（前端展示：已提交 / 已打包 / 执行失败）
+const (
+	ChainTxSubmitted = "submitted" // 已广播，等待打包
+	ChainTxMined     = "mined"     // 已打包且执行成功
+	ChainTxReverted  = "reverted"  // 已打包但执行失败
+	ChainTxReplaced  = "replaced"  // 卡住后被同 nonce 的提价交易替换
+	ChainTxFailed    = "failed"    // 未打包即失效（如同 nonce 的另一笔交易先被打包）
+)
+
+// ChainTx 链上交易台账行
+type ChainTx struct {
+	Id          int64          `json:"id"`
+	TxHash      string         `json:"tx_hash"`
+	Method      string         `json:"method"`
+	Args        string         `json:"args"` // JSON 数组
+	Sender      string         `json:"sender"`
+	Nonce       uint64         `json:"nonce"`
+	GasLimit    uint64         `json:"gas_limit"`
+	GasPrice    string         `json:"gas_price"`
+	Status      string         `json:"status"`
+	BlockNumber sql.NullInt64  `json:"block_number"`
+	GasUsed     sql.NullInt64  `json:"gas_used"`
+	ReplacedBy  sql.NullString `json:"replaced_by"`
+	Error       sql.NullString `json:"error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+const chainTxColumns = `id, tx_hash, method, args, sender, nonce, gas_limit, gas_price, status, block_number, gas_used, replaced_by, error, created_at, updated_at`
+
+// chainTxStatus 交易队列状态 -> 台账状态（队列的 failed 有区块号说明是 revert）
+func chainTxStatus(q utils.QueuedTx) string {
+	switch q.Status {
+	case utils.TxStatusMined:
+		return ChainTxMined
+	case utils.TxStatusReplaced:
+		return ChainTxReplaced
+	case utils.TxStatusFailed:
+		if q.BlockNumber > 0 {
+			return ChainTxReverted
+		}
+		return ChainTxFailed
+	}
+	return ChainTxSubmitted
+}
+
+// SaveQueuedTx 按交易哈希写入/更新台账（交易队列状态变更回调）
+func SaveQueuedTx(q utils.QueuedTx) error {
+	args, err := json.Marshal(q.Args)
+	if err != nil {
+		return err
+	}
+	var blockNumber, gasUsed sql.NullInt64
+	if q.BlockNumber > 0 {
+		blockNumber = sql.NullInt64{Int64: int64(q.BlockNumber), Valid: true}
+		gasUsed = sql.NullInt64{Int64: int64(q.GasUsed), Valid: true}
+	}
+	_, err = utils.DB.Exec(
+		`INSERT INTO chain_transactions (tx_hash, method, args, sender, nonce, gas_limit, gas_price, status, block_number, gas_used, replaced_by, error)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE status = VALUES(status), block_number = VALUES(block_number), gas_used = VALUES(gas_used),
+		 replaced_by = VALUES(replaced_by), error = VALUES(error)`,
+		q.Hash, q.Method, string(args), q.From, q.Nonce, q.GasLimit, q.GasPrice, chainTxStatus(q), blockNumber, gasUsed,
+		sql.NullString{String: q.ReplacedBy, Valid: q.ReplacedBy != ""}, sql.NullString{String: q.Error, Valid: q.Error != ""},
+	)
+	return err
+}
+
+// UpdateChainTxReceipt 回执巡检：写入打包结果（仅更新仍为 submitted 的行）
+func UpdateChainTxReceipt(txHash, status string, blockNumber, gasUsed uint64) error {
+	_, err := utils.DB.Exec(
+		`UPDATE chain_transactions SET status = ?, block_number = ?, gas_used = ? WHERE tx_hash = ? AND status = 'submitted'`,
+		status, blockNumber, gasUsed, txHash,
+	)
+	return err
+}
+
+// GetChainTxByHash 按交易哈希查询，不存在返回 nil
+func GetChainTxByHash(txHash string) (*ChainTx, error) {
+	row := utils.DB.QueryRow(`SELECT `+chainTxColumns+` FROM chain_transactions WHERE tx_hash = ?`, txHash)
+	tx, err := scanChainTx(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// ListChainTxs 分页查询台账（status 为空则不过滤），返回列表与总数
+func ListChainTxs(status string, page, size int) ([]ChainTx, int64, error) {
+	where := ""
+	args := []interface{}{}
+	if status != "" {
+		where = " WHERE status = ?"
+		args = append(args, status)
+	}
+	var total int64
+	if err := utils.DB.QueryRow(`SELECT COUNT(1) FROM chain_transactions`+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := utils.DB.Query(
+		`SELECT `+chainTxColumns+` FROM chain_transactions`+where+` ORDER BY id DESC LIMIT ? OFFSET ?`,
+		append(args, size, (page-1)*size)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	list, err := scanChainTxRows(rows)
+	return list, total, err
+}
+
+// GetSubmittedChainTxs 仍在等待打包的交易（回执巡检用，含重启前发出的交易）
+func GetSubmittedChainTxs() ([]ChainTx, error) {
+	rows, err := utils.DB.Query(`SELECT ` + chainTxColumns + ` FROM chain_transactions WHERE status = 'submitted' ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanChainTxRows(rows)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanChainTx(r rowScanner) (*ChainTx, error) {
+	var tx ChainTx
+	err := r.Scan(
+		&tx.Id, &tx.TxHash, &tx.Method, &tx.Args, &tx.Sender, &tx.Nonce, &tx.GasLimit, &tx.GasPrice, &tx.Status,
+		&tx.BlockNumber, &tx.GasUsed, &tx.ReplacedBy, &tx.Error, &tx.CreatedAt, &tx.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+func scanChainTxRows(rows *sql.Rows) ([]ChainTx, error) {
+	var list []ChainTx
+	for rows.Next() {
+		tx, err := scanChainTx(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *tx)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,169 @@
+// model/course.go 课程目录：学分录入以课程代码上链，credits.course_id 关联目录条目
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 开课学期
+const (
+	SemesterSpring = "spring"
+	SemesterFall   = "fall"
+	SemesterSummer = "summer"
+	SemesterAll    = "all" // 每学期均开设
+)
+
+// Course 课程目录条目，code 上链后不可修改
+type Course struct {
+	Id              int64     `json:"id"`
+	Code            string    `json:"code"`
+	Name            string    `json:"name"`
+	CreditHours     float64   `json:"credit_hours"`
+	Department      string    `json:"department"`
+	SemesterOffered string    `json:"semester_offered"` // spring / fall / summer / all
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+const courseColumns = `id, code, name, credit_hours, department, semester_offered, created_at, updated_at`
+
+func scanCourse(row interface{ Scan(...interface{}) error }) (*Course, error) {
+	var c Course
+	err := row.Scan(&c.Id, &c.Code, &c.Name, &c.CreditHours, &c.Department, &c.SemesterOffered, &c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// CreateCourse 新建课程，返回主键
+func CreateCourse(c *Course) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO courses (code, name, credit_hours, department, semester_offered) VALUES (?, ?, ?, ?, ?)`,
+		c.Code, c.Name, c.CreditHours, c.Department, c.SemesterOffered,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateCourse 更新课程名称、学分、院系与开课学期（代码不可改），返回受影响行数
+func UpdateCourse(c *Course) (int64, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE courses SET name = ?, credit_hours = ?, department = ?, semester_offered = ? WHERE id = ?`,
+		c.Name, c.CreditHours, c.Department, c.SemesterOffered, c.Id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteCourse 删除课程（调用方须先确认未被学分引用）
+func DeleteCourse(id int64) error {
+	_, err := utils.DB.Exec(`DELETE FROM courses WHERE id = ?`, id)
+	return err
+}
+
+// GetCourseById 按主键查询，不存在返回 nil
+func GetCourseById(id int64) (*Course, error) {
+	c, err := scanCourse(utils.DB.QueryRow(`SELECT `+courseColumns+` FROM courses WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// GetCourseByCode 按课程代码查询，不存在返回 nil
+func GetCourseByCode(code string) (*Course, error) {
+	c, err := scanCourse(utils.DB.QueryRow(`SELECT `+courseColumns+` FROM courses WHERE code = ?`, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// ListCourses 课程列表：keyword 模糊匹配代码或名称，department 精确过滤，均可为空
+func ListCourses(keyword, department string) ([]Course, error) {
+	query := `SELECT ` + courseColumns + ` FROM courses WHERE 1 = 1`
+	var args []interface{}
+	if keyword = strings.TrimSpace(keyword); keyword != "" {
+		query += ` AND (code LIKE ? OR name LIKE ?)`
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+	}
+	if department != "" {
+		query += ` AND department = ?`
+		args = append(args, department)
+	}
+	rows, err := utils.DB.Query(query+` ORDER BY code ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Course{}
+	for rows.Next() {
+		c, err := scanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+// CountCreditsByCourse 引用该课程的学分记录数
+func CountCreditsByCourse(id int64) (int64, error) {
+	var n int64
+	err := utils.DB.QueryRow(`SELECT COUNT(1) FROM credits WHERE course_id = ?`, id).Scan(&n)
+	return n, err
+}
+
+// CourseNameCount 尚未关联课程目录的 course_name 及其记录数（迁移用）
+type CourseNameCount struct {
+	CourseName string
+	Count      int64
+}
+
+// ListUnmappedCourseNames 按 course_name 汇总 course_id 为空的学分记录
+func ListUnmappedCourseNames() ([]CourseNameCount, error) {
+	rows, err := utils.DB.Query(
+		`SELECT course_name, COUNT(1) FROM credits WHERE course_id IS NULL GROUP BY course_name ORDER BY course_name ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []CourseNameCount
+	for rows.Next() {
+		var n CourseNameCount
+		if err := rows.Scan(&n.CourseName, &n.Count); err != nil {
+			return nil, err
+		}
+		list = append(list, n)
+	}
+	return list, rows.Err()
+}
+
+// MapCreditCourses 把 course_name 对应的未关联记录批量指向课程（course_name 为链上原值，保持不变），在同一事务内执行
+func MapCreditCourses(mapping map[string]int64) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var total int64
+	for name, courseId := range mapping {
+		res, err := tx.Exec(`UPDATE credits SET course_id = ? WHERE course_name = ? AND course_id IS NULL`, courseId, name)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, tx.Commit()
+}
@@ -0,0 +1,167 @@
+// model/credit_audit.go 学分审核交易登记：广播后记为 submitted，回执成功后才写入 credits 状态与学分事件
+// 批量交易中每条学分一行，同一交易共用 tx_hash
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 审核交易登记状态
+const (
+	CreditAuditSubmitted = "submitted" // 交易已广播，等待打包
+	CreditAuditApplied   = "applied"   // 已打包成功并写入学分状态
+	CreditAuditSkipped   = "skipped"   // 已打包但合约跳过该学分（链上状态已变化）
+	CreditAuditFailed    = "failed"    // 交易失效或执行失败，学分状态未变
+)
+
+// CreditAudit 一条学分的审核交易登记；Action 与学分事件类型一致（approved / rejected / revoked）
+type CreditAudit struct {
+	Id               int64          `json:"id"`
+	CreditId         int64          `json:"credit_id"`
+	ContractCreditId int64          `json:"contract_credit_id"`
+	Action           string         `json:"action"`
+	TxHash           string         `json:"tx_hash"`
+	Reason           sql.NullString `json:"reason"`
+	Comment          sql.NullString `json:"comment"`
+	OperatorId       uint64         `json:"operator_id"`
+	OperatorAddress  sql.NullString `json:"operator_address"`
+	Status           string         `json:"status"`
+	Error            sql.NullString `json:"error"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+const creditAuditColumns = `id, credit_id, contract_credit_id, action, tx_hash, reason, comment, operator_id, operator_address, status, error, created_at, updated_at`
+
+func scanCreditAuditRows(rows *sql.Rows) ([]CreditAudit, error) {
+	list := []CreditAudit{}
+	for rows.Next() {
+		var a CreditAudit
+		if err := rows.Scan(
+			&a.Id, &a.CreditId, &a.ContractCreditId, &a.Action, &a.TxHash, &a.Reason, &a.Comment, &a.OperatorId,
+			&a.OperatorAddress, &a.Status, &a.Error, &a.CreatedAt, &a.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// SubmitCreditAudit 登记已广播的审核交易（学分状态不变，由回执任务在打包成功后写入）
+func SubmitCreditAudit(row *CreditRow, action string, actor CreditActor, txHash, reason, comment string) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO credit_audits (credit_id, contract_credit_id, action, tx_hash, reason, comment, operator_id, operator_address, status)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'submitted')`,
+		row.Id, row.ContractCreditId.Int64, action, txHash,
+		sql.NullString{String: reason, Valid: reason != ""},
+		sql.NullString{String: comment, Valid: comment != ""},
+		actor.UserId, sql.NullString{String: actor.Address, Valid: actor.Address != ""},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// HasOpenCreditAudit 学分是否有等待打包的审核交易（防止重复提交）
+func HasOpenCreditAudit(creditId int64) (bool, error) {
+	var n int
+	err := utils.DB.QueryRow(
+		`SELECT COUNT(1) FROM credit_audits WHERE credit_id = ? AND status = 'submitted'`, creditId,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// GetSubmittedCreditAudits 所有等待打包的审核交易登记（回执任务用）
+func GetSubmittedCreditAudits() ([]CreditAudit, error) {
+	rows, err := utils.DB.Query(`SELECT ` + creditAuditColumns + ` FROM credit_audits WHERE status = 'submitted' ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditAuditRows(rows)
+}
+
+// GetCreditAuditsByTx 同一交易的全部审核登记
+func GetCreditAuditsByTx(txHash string) ([]CreditAudit, error) {
+	rows, err := utils.DB.Query(`SELECT `+creditAuditColumns+` FROM credit_audits WHERE tx_hash = ? ORDER BY id ASC`, txHash)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditAuditRows(rows)
+}
+
+// UpdateCreditAuditTx 交易被提价替换后改记新哈希
+func UpdateCreditAuditTx(oldHash, newHash string) error {
+	_, err := utils.DB.Exec(`UPDATE credit_audits SET tx_hash = ? WHERE tx_hash = ? AND status = 'submitted'`, newHash, oldHash)
+	return err
+}
+
+// FinishCreditAudit 结束一条登记（skipped / failed），学分状态不变
+func FinishCreditAudit(id int64, status, reason string) error {
+	_, err := utils.DB.Exec(
+		`UPDATE credit_audits SET status = ?, error = ? WHERE id = ? AND status = 'submitted'`, status, reason, id,
+	)
+	return err
+}
+
+// ApplyCreditAudit 交易已打包成功：按登记写入学分状态与事件（审核意见、原因、操作人取自登记），再把登记置为 applied
+// 提交后学期已关闭的也照链上结果同步；索引器先一步同步了状态时只补记原因原文
+func ApplyCreditAudit(a *CreditAudit, txHash string) error {
+	actor := CreditActor{UserId: a.OperatorId, Address: a.OperatorAddress.String}
+	n, err := applyCreditAudit(a, actor, txHash)
+	if errors.Is(err, ErrCreditLocked) {
+		n, err = applyCreditAudit(a, CreditActor{Address: actor.Address}, txHash)
+	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		row, err := GetCreditById(a.CreditId)
+		if err != nil {
+			return err
+		}
+		if row == nil || row.Status != a.Action {
+			return FinishCreditAudit(a.Id, CreditAuditFailed, "链上已执行，但本地记录状态已变化，未更新")
+		}
+		if err := fillCreditAuditReason(a); err != nil {
+			return err
+		}
+	}
+	_, err = utils.DB.Exec(`UPDATE credit_audits SET status = 'applied', tx_hash = ? WHERE id = ?`, txHash, a.Id)
+	return err
+}
+
+func applyCreditAudit(a *CreditAudit, actor CreditActor, txHash string) (int64, error) {
+	switch a.Action {
+	case CreditEventApproved:
+		return ApproveCredit(a.CreditId, actor, a.Comment.String, txHash)
+	case CreditEventRejected:
+		return RejectCredit(a.CreditId, actor, txHash, a.Reason.String, a.Comment.String)
+	case CreditEventRevoked:
+		return RevokeCredit(a.CreditId, actor, txHash, a.Reason.String, a.Comment.String)
+	}
+	return 0, errors.New("不支持的审核动作: " + a.Action)
+}
+
+// fillCreditAuditReason 索引器已按链上事件同步状态（链上只有原因哈希）时，补记登记中的原因原文
+func fillCreditAuditReason(a *CreditAudit) error {
+	if !a.Reason.Valid {
+		return nil
+	}
+	switch a.Action {
+	case CreditEventRejected:
+		_, err := utils.DB.Exec(`UPDATE credits SET reject_reason = ? WHERE id = ? AND reject_reason IS NULL`, a.Reason.String, a.CreditId)
+		return err
+	case CreditEventRevoked:
+		_, err := utils.DB.Exec(`UPDATE credits SET revoke_reason = ? WHERE id = ? AND revoke_reason IS NULL`, a.Reason.String, a.CreditId)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+// model/credit_correction.go 学分更正申请：教师提出新的课程名/成绩，管理员审核后上链 supersedeCredit，
+// 打包后新记录与原记录互相关联（credits.supersedes / superseded_by），列表只显示当前版本
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 更正申请状态
+const (
+	CorrectionRequested = "requested" // 教师已提出，待管理员审核
+	CorrectionSubmitted = "submitted" // 管理员已同意，supersedeCredit 交易等待打包
+	CorrectionApplied   = "applied"   // 已上链并关联新记录
+	CorrectionRejected  = "rejected"  // 管理员拒绝
+	CorrectionFailed    = "failed"    // 交易失效或执行失败
+)
+
+// CreditCorrection 更正申请
+type CreditCorrection struct {
+	Id            int64          `json:"id"`
+	CreditId      int64          `json:"credit_id"` // 被更正的 credits 主键
+	CourseName    string         `json:"course_name"`
+	Score         float64        `json:"score"`
+	Reason        string         `json:"reason"`
+	Status        string         `json:"status"`
+	RequestedBy   uint64         `json:"requested_by"`
+	ReviewedBy    sql.NullInt64  `json:"reviewed_by"`
+	ReviewComment sql.NullString `json:"review_comment"`
+	TxHash        sql.NullString `json:"tx_hash"`
+	NewCreditId   sql.NullInt64  `json:"new_credit_id"` // 更正后的新 credits 主键
+	Error         sql.NullString `json:"error"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+const creditCorrectionColumns = `id, credit_id, course_name, score, reason, status, requested_by, reviewed_by, review_comment, tx_hash, new_credit_id, error, created_at, updated_at`
+
+func scanCreditCorrection(row interface{ Scan(...interface{}) error }) (*CreditCorrection, error) {
+	var r CreditCorrection
+	err := row.Scan(
+		&r.Id, &r.CreditId, &r.CourseName, &r.Score, &r.Reason, &r.Status, &r.RequestedBy, &r.ReviewedBy,
+		&r.ReviewComment, &r.TxHash, &r.NewCreditId, &r.Error, &r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateCreditCorrection 新建更正申请
+func CreateCreditCorrection(creditId int64, courseName string, score float64, reason string, requestedBy uint64) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO credit_corrections (credit_id, course_name, score, reason, status, requested_by) VALUES (?, ?, ?, ?, 'requested', ?)`,
+		creditId, courseName, score, reason, requestedBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetCreditCorrection 按ID查询，不存在返回 nil
+func GetCreditCorrection(id int64) (*CreditCorrection, error) {
+	r, err := scanCreditCorrection(utils.DB.QueryRow(`SELECT `+creditCorrectionColumns+` FROM credit_corrections WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// HasOpenCreditCorrection 该学分是否已有待审核或等待打包的更正申请
+func HasOpenCreditCorrection(creditId int64) (bool, error) {
+	var n int
+	err := utils.DB.QueryRow(
+		`SELECT COUNT(1) FROM credit_corrections WHERE credit_id = ? AND status IN ('requested', 'submitted')`, creditId,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// ListCreditCorrections 更正申请列表，status 为空不过滤，requestedBy 为 0 不过滤
+func ListCreditCorrections(status string, requestedBy uint64) ([]CreditCorrection, error) {
+	query := `SELECT ` + creditCorrectionColumns + ` FROM credit_corrections WHERE 1 = 1`
+	var args []interface{}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, status)
+	}
+	if requestedBy != 0 {
+		query += ` AND requested_by = ?`
+		args = append(args, requestedBy)
+	}
+	rows, err := utils.DB.Query(query+` ORDER BY id DESC LIMIT 200`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditCorrectionRows(rows)
+}
+
+// GetSubmittedCreditCorrections 等待打包的更正（后台任务轮询用）
+func GetSubmittedCreditCorrections() ([]CreditCorrection, error) {
+	rows, err := utils.DB.Query(`SELECT ` + creditCorrectionColumns + ` FROM credit_corrections WHERE status = 'submitted' ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditCorrectionRows(rows)
+}
+
+func scanCreditCorrectionRows(rows *sql.Rows) ([]CreditCorrection, error) {
+	list := []CreditCorrection{}
+	for rows.Next() {
+		r, err := scanCreditCorrection(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *r)
+	}
+	return list, rows.Err()
+}
+
+// SubmitCreditCorrection 管理员同意并已提交 supersedeCredit 交易（仅 requested 状态），返回受影响行数
+func SubmitCreditCorrection(id int64, reviewerId uint64, comment, txHash string) (int64, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE credit_corrections SET status = 'submitted', reviewed_by = ?, review_comment = ?, tx_hash = ? WHERE id = ? AND status = 'requested'`,
+		reviewerId, comment, txHash, id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RejectCreditCorrection 管理员拒绝更正申请（仅 requested 状态），返回受影响行数
+func RejectCreditCorrection(id int64, reviewerId uint64, comment string) (int64, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE credit_corrections SET status = 'rejected', reviewed_by = ?, review_comment = ? WHERE id = ? AND status = 'requested'`,
+		reviewerId, comment, id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// UpdateCreditCorrectionTx 交易被提价替换后同步新的交易哈希
+func UpdateCreditCorrectionTx(id int64, txHash string) error {
+	_, err := utils.DB.Exec(`UPDATE credit_corrections SET tx_hash = ? WHERE id = ?`, txHash, id)
+	return err
+}
+
+// FailCreditCorrection 交易失效或执行失败
+func FailCreditCorrection(id int64, reason string) error {
+	_, err := utils.DB.Exec(`UPDATE credit_corrections SET status = 'failed', error = ? WHERE id = ?`, reason, id)
+	return err
+}
+
+// ApplyCreditCorrection supersedeCredit 打包后在同一事务中：建立（或沿用索引器已建的）新记录、写入双向关联、
+// 在原记录上记 corrected 事件并把申请标记为 applied，返回新记录主键
+func ApplyCreditCorrection(corr *CreditCorrection, newContractId int64, txHash string) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	original, err := scanCredit(tx.QueryRow(`SELECT `+creditColumns+` FROM credits WHERE id = ? FOR UPDATE`, corr.CreditId))
+	if err != nil {
+		return 0, fmt.Errorf("读取原记录失败: %v", err)
+	}
+	var reviewer CreditActor
+	if corr.ReviewedBy.Valid {
+		reviewer.UserId = uint64(corr.ReviewedBy.Int64)
+		var addr sql.NullString
+		if err := tx.QueryRow(`SELECT address FROM users WHERE id = ?`, reviewer.UserId).Scan(&addr); err != nil && err != sql.ErrNoRows {
+			return 0, err
+		}
+		reviewer.Address = addr.String
+	}
+
+	var newId int64
+	err = tx.QueryRow(`SELECT id FROM credits WHERE contract_credit_id = ? FOR UPDATE`, newContractId).Scan(&newId)
+	switch {
+	case err == sql.ErrNoRows:
+		// 原记录已审核时链上新记录同样已审核
+		status := "pending"
+		if original.Status == "approved" {
+			status = "approved"
+		}
+		res, err := tx.Exec(
+			`INSERT INTO credits (contract_credit_id, student_address, teacher_address, course_id, course_name, score, term_id, status, tx_hash, audit_admin, audit_time, supersedes, locked)
+			 VALUES (?, ?, ?, (SELECT id FROM courses WHERE code = ?), ?, ?, ?, ?, ?, ?, IF(? = 'approved', NOW(), NULL), ?, ?)`,
+			newContractId, original.StudentAddress, original.TeacherAddress, corr.CourseName, corr.CourseName, corr.Score, original.TermId, status, txHash,
+			sql.NullString{String: reviewer.Address, Valid: status == "approved" && reviewer.Address != ""}, status, original.Id, original.Locked,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if newId, err = res.LastInsertId(); err != nil {
+			return 0, err
+		}
+		comment := fmt.Sprintf("更正自学分 #%d", original.Id)
+		if err := insertCreditEvent(tx, newId, CreditEventRecorded, "", status, reviewer, comment, txHash); err != nil {
+			return 0, err
+		}
+	case err != nil:
+		return 0, err
+	default:
+		// 索引器已按 CreditRecorded 建档，补上更正关系与原记录的学期
+		if _, err := tx.Exec(
+			`UPDATE credits SET supersedes = ?, term_id = ?, locked = ? WHERE id = ?`, original.Id, original.TermId, original.Locked, newId,
+		); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE credits SET superseded_by = ? WHERE id = ?`, newId, original.Id); err != nil {
+		return 0, err
+	}
+	comment := fmt.Sprintf("更正为学分 #%d", newId)
+	if corr.ReviewComment.Valid && corr.ReviewComment.String != "" {
+		comment += "：" + corr.ReviewComment.String
+	}
+	if err := insertCreditEvent(tx, original.Id, CreditEventCorrected, original.Status, original.Status, reviewer, comment, txHash); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(
+		`UPDATE credit_corrections SET status = 'applied', new_credit_id = ?, tx_hash = ?, error = NULL WHERE id = ?`,
+		newId, txHash, corr.Id,
+	); err != nil {
+		return 0, err
+	}
+	return newId, tx.Commit()
+}
+
+// LinkCreditCorrectionFromChain 索引器：按链上 CreditCorrected 事件关联新旧记录并在原记录上记 corrected 事件（幂等）
+// 后端发起、仍在等待打包的更正由后台任务处理（带审核意见），此处跳过
+func LinkCreditCorrectionFromChain(originalContractId, newContractId int64, admin, txHash string, blockNumber uint64) error {
+	if err := linkCreditCorrectionFromChain(originalContractId, newContractId, admin, txHash); err != nil {
+		return err
+	}
+	return stampCreditEventBlock(originalContractId, CreditEventCorrected, txHash, blockNumber)
+}
+
+func linkCreditCorrectionFromChain(originalContractId, newContractId int64, admin, txHash string) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var originalId int64
+	var status string
+	var supersededBy sql.NullInt64
+	err = tx.QueryRow(
+		`SELECT id, status, superseded_by FROM credits WHERE contract_credit_id = ? FOR UPDATE`, originalContractId,
+	).Scan(&originalId, &status, &supersededBy)
+	if err == sql.ErrNoRows || (err == nil && supersededBy.Valid) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var pending int
+	if err := tx.QueryRow(
+		`SELECT COUNT(1) FROM credit_corrections WHERE credit_id = ? AND status = 'submitted'`, originalId,
+	).Scan(&pending); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+	var newId int64
+	err = tx.QueryRow(`SELECT id FROM credits WHERE contract_credit_id = ? FOR UPDATE`, newContractId).Scan(&newId)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 新记录沿用原记录的学期
+	if _, err := tx.Exec(
+		`UPDATE credits dst JOIN credits src ON src.id = ? SET dst.supersedes = src.id, dst.term_id = src.term_id, dst.locked = src.locked WHERE dst.id = ?`,
+		originalId, newId,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE credits SET superseded_by = ? WHERE id = ?`, newId, originalId); err != nil {
+		return err
+	}
+	comment := fmt.Sprintf("更正为学分 #%d", newId)
+	if err := insertCreditEvent(tx, originalId, CreditEventCorrected, status, status, CreditActor{Address: admin}, comment, txHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ListCreditVersions 学分的全部版本（沿 supersedes 回溯到最初录入，再沿 superseded_by 到当前版本），按从旧到新排列
+func ListCreditVersions(id int64) ([]CreditRow, error) {
+	first := id
+	for i := 0; i < 32; i++ {
+		var prev sql.NullInt64
+		err := utils.DB.QueryRow(`SELECT supersedes FROM credits WHERE id = ?`, first).Scan(&prev)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if !prev.Valid {
+			break
+		}
+		first = prev.Int64
+	}
+	var list []CreditRow
+	for next, i := first, 0; next != 0 && i < 32; i++ {
+		row, err := GetCreditById(next)
+		if err != nil {
+			return nil, err
+		}
+		if row == nil {
+			break
+		}
+		list = append(list, *row)
+		next = row.SupersededBy.Int64
+	}
+	return list, nil
+}
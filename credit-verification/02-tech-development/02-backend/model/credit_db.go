@@ -3,6 +3,8 @@ package model
 
 import (
 	"database/sql"
+	"fmt"
+	"sort"
 	"time"
 
 	"campus-credit-backend/utils"
@@ -14,33 +16,130 @@ type CreditRow struct {
 	ContractCreditId sql.NullInt64  `json:"contract_credit_id"`
 	StudentAddress   string         `json:"student_address"`
 	TeacherAddress   string         `json:"teacher_address"`
-	CourseName       string         `json:"course_name"`
+	CourseId         sql.NullInt64  `json:"course_id"`   // 课程目录主键，迁移前的历史记录可能为空
+	CourseName       string         `json:"course_name"` // 链上 courseName：课程代码（历史记录为自由填写的课程名）
 	Score            float64        `json:"score"`
-	Status           string         `json:"status"` // pending / approved / rejected
+	TermId           sql.NullInt64  `json:"term_id"` // 所属学期，链上直接录入（索引器补录）的记录为空
+	Status           string         `json:"status"`  // pending / approved / rejected / revoked（审核后撤销）/ failed（上链交易执行失败）
 	TxHash           sql.NullString `json:"tx_hash"`
 	AuditAdmin       sql.NullString `json:"audit_admin"`
 	AuditTime        sql.NullTime   `json:"audit_time"`
+	RejectTxHash     sql.NullString `json:"reject_tx_hash"` // 链上 rejectCredit 交易哈希
+	RejectReason     sql.NullString `json:"reject_reason"`  // 驳回原因原文，链上只存其 keccak256
+	RevokeTxHash     sql.NullString `json:"revoke_tx_hash"` // 链上 revokeCredit 交易哈希
+	RevokeReason     sql.NullString `json:"revoke_reason"`  // 撤销理由原文，链上只存其 keccak256
+	RevokedBy        sql.NullString `json:"revoked_by"`     // 撤销管理员地址
+	RevokedAt        sql.NullTime   `json:"revoked_at"`
+	Supersedes       sql.NullInt64  `json:"supersedes"`    // 本记录是对哪条记录的更正（credits 主键）
+	SupersededBy     sql.NullInt64  `json:"superseded_by"` // 本记录被哪条更正记录取代，非空即为历史版本
+	Locked           bool           `json:"locked"`        // 所属学期已关闭，不可再审核、驳回、撤销或更正
 	CreatedAt        time.Time      `json:"created_at"`
 	UpdatedAt        time.Time      `json:"updated_at"`
 }
 
-// CreateCredit 插入一条学分记录（录入学分后调用）
-func CreateCredit(studentAddress, teacherAddress, courseName string, score float64, status, txHash string, contractCreditId int64) (int64, error) {
-	res, err := utils.DB.Exec(
-		`INSERT INTO credits (contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash) VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		contractCreditId, studentAddress, teacherAddress, courseName, score, status, txHash,
+// creditColumns 查询列，顺序与 scanCredit 一致
+const creditColumns = `id, contract_credit_id, student_address, teacher_address, course_id, course_name, score, term_id, status, tx_hash, audit_admin, audit_time, reject_tx_hash, reject_reason, revoke_tx_hash, revoke_reason, revoked_by, revoked_at, supersedes, superseded_by, locked, created_at, updated_at`
+
+func scanCredit(row interface{ Scan(...interface{}) error }) (*CreditRow, error) {
+	var r CreditRow
+	err := row.Scan(
+		&r.Id, &r.ContractCreditId, &r.StudentAddress, &r.TeacherAddress, &r.CourseId, &r.CourseName, &r.Score, &r.TermId,
+		&r.Status, &r.TxHash, &r.AuditAdmin, &r.AuditTime, &r.RejectTxHash, &r.RejectReason,
+		&r.RevokeTxHash, &r.RevokeReason, &r.RevokedBy, &r.RevokedAt,
+		&r.Supersedes, &r.SupersededBy, &r.Locked, &r.CreatedAt, &r.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateCredit 插入一条学分记录并记 recorded 事件（交易提交后立即调用；contract_credit_id 待打包后由后台任务关联）
+// courseName 为上链的课程代码，course_id 按代码从课程目录关联；
+// 先对学期行加共享锁再按其状态写 locked：与 CloseTerm 并发时，要么本行看到 closed 直接锁定，要么 CloseTerm 等本事务提交后一并锁定
+func CreateCredit(studentAddress, teacherAddress, courseName string, score float64, termId int64, status, txHash string, createdBy uint64) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	var locked bool
+	err = tx.QueryRow(`SELECT status = 'closed' FROM terms WHERE id = ? LOCK IN SHARE MODE`, termId).Scan(&locked)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, err
+	}
+	res, err := tx.Exec(
+		`INSERT INTO credits (student_address, teacher_address, course_id, course_name, score, term_id, status, tx_hash, locked)
+		 VALUES (?, ?, (SELECT id FROM courses WHERE code = ?), ?, ?, ?, ?, ?, ?)`,
+		studentAddress, teacherAddress, courseName, courseName, score, termId, status, txHash, locked,
 	)
 	if err != nil {
 		return 0, err
 	}
-	return res.LastInsertId()
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	actor := CreditActor{UserId: createdBy, Address: teacherAddress}
+	if err := insertCreditEvent(tx, id, CreditEventRecorded, "", status, actor, "", txHash); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
-// GetCreditsByStudentAddress 按学生地址查询学分列表
+// LinkCreditContractId 交易打包后为记录关联链上学分ID，返回最终保留的行ID
+// 若索引器已按同一链上ID先行建档，则把教师地址与所属学期并入该行并删除本行，避免唯一键冲突与重复记录；
+// 本行的事件改挂到保留行，索引器补记的 recorded 事件删除（以后端录入时的为准）
+func LinkCreditContractId(id, contractCreditId int64, txHash string) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var otherId int64
+	err = tx.QueryRow(`SELECT id FROM credits WHERE contract_credit_id = ? FOR UPDATE`, contractCreditId).Scan(&otherId)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := tx.Exec(`UPDATE credits SET contract_credit_id = ?, tx_hash = ? WHERE id = ?`, contractCreditId, txHash, id); err != nil {
+			return 0, err
+		}
+		otherId = id
+	case err != nil:
+		return 0, err
+	case otherId != id:
+		if _, err := tx.Exec(
+			`UPDATE credits dst JOIN credits src ON src.id = ?
+			 SET dst.teacher_address = src.teacher_address, dst.term_id = src.term_id, dst.locked = src.locked WHERE dst.id = ?`,
+			id, otherId,
+		); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(
+			`DELETE FROM credit_events WHERE credit_id = ? AND event = ? AND actor_id IS NULL`, otherId, CreditEventRecorded,
+		); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`UPDATE credit_events SET credit_id = ? WHERE credit_id = ?`, otherId, id); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(`DELETE FROM credits WHERE id = ?`, id); err != nil {
+			return 0, err
+		}
+	}
+	return otherId, tx.Commit()
+}
+
+// UpdateCreditTxHash 交易被提价替换后同步新的交易哈希
+func UpdateCreditTxHash(id int64, txHash string) error {
+	_, err := utils.DB.Exec(`UPDATE credits SET tx_hash = ? WHERE id = ?`, txHash, id)
+	return err
+}
+
+// GetCreditsByStudentAddress 按学生地址查询学分列表（只含当前版本，被更正的历史版本见时间线）
 func GetCreditsByStudentAddress(studentAddress string) ([]CreditRow, error) {
 	rows, err := utils.DB.Query(
-		`SELECT id, contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash, audit_admin, audit_time, created_at, updated_at 
-		 FROM credits WHERE student_address = ? ORDER BY created_at DESC`,
+		`SELECT `+creditColumns+` FROM credits WHERE student_address = ? AND superseded_by IS NULL ORDER BY created_at DESC`,
 		studentAddress,
 	)
 	if err != nil {
@@ -50,11 +149,10 @@ func GetCreditsByStudentAddress(studentAddress string) ([]CreditRow, error) {
 	return scanCreditRows(rows)
 }
 
-// GetCreditsByTeacherAddress 按教师地址查询其录入的学分列表
+// GetCreditsByTeacherAddress 按教师地址查询其录入的学分列表（只含当前版本）
 func GetCreditsByTeacherAddress(teacherAddress string) ([]CreditRow, error) {
 	rows, err := utils.DB.Query(
-		`SELECT id, contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash, audit_admin, audit_time, created_at, updated_at 
-		 FROM credits WHERE teacher_address = ? ORDER BY created_at DESC`,
+		`SELECT `+creditColumns+` FROM credits WHERE teacher_address = ? AND superseded_by IS NULL ORDER BY created_at DESC`,
 		teacherAddress,
 	)
 	if err != nil {
@@ -64,11 +162,10 @@ func GetCreditsByTeacherAddress(teacherAddress string) ([]CreditRow, error) {
 	return scanCreditRows(rows)
 }
 
-// GetAllCredits 管理员：查询全部学分
+// GetAllCredits 管理员：查询全部学分（只含当前版本）
 func GetAllCredits() ([]CreditRow, error) {
 	rows, err := utils.DB.Query(
-		`SELECT id, contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash, audit_admin, audit_time, created_at, updated_at 
-		 FROM credits ORDER BY created_at DESC`,
+		`SELECT ` + creditColumns + ` FROM credits WHERE superseded_by IS NULL ORDER BY created_at DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -77,11 +174,22 @@ func GetAllCredits() ([]CreditRow, error) {
 	return scanCreditRows(rows)
 }
 
-// GetPendingCredits 待审核学分列表（管理员用，仅含已有关链上ID的记录）
+// GetPendingCredits 待审核学分列表（管理员用，仅含已有关链上ID的记录；合约 creditId 从 0 开始）
 func GetPendingCredits() ([]CreditRow, error) {
 	rows, err := utils.DB.Query(
-		`SELECT id, contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash, audit_admin, audit_time, created_at, updated_at 
-		 FROM credits WHERE status = 'pending' AND contract_credit_id > 0 ORDER BY created_at DESC`,
+		`SELECT ` + creditColumns + ` FROM credits WHERE status = 'pending' AND contract_credit_id IS NOT NULL AND superseded_by IS NULL ORDER BY created_at DESC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditRows(rows)
+}
+
+// GetCreditsWithContractId 所有已关联链上学分ID的记录（链上对账用）
+func GetCreditsWithContractId() ([]CreditRow, error) {
+	rows, err := utils.DB.Query(
+		`SELECT ` + creditColumns + ` FROM credits WHERE contract_credit_id IS NOT NULL ORDER BY id ASC`,
 	)
 	if err != nil {
 		return nil, err
@@ -90,47 +198,279 @@ func GetPendingCredits() ([]CreditRow, error) {
 	return scanCreditRows(rows)
 }
 
-// UpdateCreditStatus 更新审核状态
-func UpdateCreditStatus(id int64, status, auditAdmin string) error {
-	_, err := utils.DB.Exec(
-		`UPDATE credits SET status = ?, audit_admin = ?, audit_time = NOW() WHERE id = ?`,
-		status, auditAdmin, id,
+// MarkCreditApprovedFromChain 链上已审核但本地仍为 pending 时补记为 approved（审核人未知，保留 audit_admin 原值）
+// 仅更新 pending 行，返回受影响行数，便于调用方判断是否真正发生变更
+func MarkCreditApprovedFromChain(id int64) (int64, error) {
+	return transitCredit("id", id, "pending", `status = 'approved', audit_time = NOW()`, nil,
+		CreditEventApproved, "approved", CreditActor{}, chainSyncComment, "")
+}
+
+// chainSyncComment 链上对账补记事件的备注
+const chainSyncComment = "链上对账补记"
+
+// UpsertCreditFromChain 索引器：按链上 CreditRecorded 事件建档（幂等），blockNumber 记入 chain_block 供分叉回退
+// 已有同 contract_credit_id 的行则只补记 chain_block；已有同 tx_hash 但尚未关联链上ID的行则补上ID；否则新建 pending 行
+func UpsertCreditFromChain(contractCreditId int64, studentAddress, teacherAddress, courseName string, score float64, txHash string, blockNumber uint64) error {
+	var exists int
+	err := utils.DB.QueryRow(`SELECT COUNT(1) FROM credits WHERE contract_credit_id = ?`, contractCreditId).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		_, err := utils.DB.Exec(
+			`UPDATE credits SET chain_block = ? WHERE contract_credit_id = ? AND chain_block IS NULL`, blockNumber, contractCreditId,
+		)
+		return err
+	}
+	res, err := utils.DB.Exec(
+		`UPDATE credits SET contract_credit_id = ?, chain_block = ? WHERE tx_hash = ? AND contract_credit_id IS NULL LIMIT 1`,
+		contractCreditId, blockNumber, txHash,
 	)
-	return err
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	res, err = tx.Exec(
+		`INSERT IGNORE INTO credits (contract_credit_id, student_address, teacher_address, course_id, course_name, score, status, tx_hash, chain_block)
+		 VALUES (?, ?, ?, (SELECT id FROM courses WHERE code = ?), ?, ?, 'pending', ?, ?)`,
+		contractCreditId, studentAddress, teacherAddress, courseName, courseName, score, txHash, blockNumber,
+	)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	actor := CreditActor{Address: teacherAddress}
+	if err := insertCreditEvent(tx, id, CreditEventRecorded, "", "pending", actor, "", txHash); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ApproveCreditFromChain 索引器：按链上 CreditApproved 事件把 pending 行置为 approved 并记事件（幂等）
+func ApproveCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	if _, err := transitCredit("contract_credit_id", contractCreditId, "pending",
+		`status = 'approved', audit_admin = ?, audit_time = NOW()`, []interface{}{auditAdmin},
+		CreditEventApproved, "approved", CreditActor{Address: auditAdmin}, "", txHash); err != nil {
+		return err
+	}
+	return stampCreditEventBlock(contractCreditId, CreditEventApproved, txHash, blockNumber)
+}
+
+// GetCreditsWithTxHash 所有带上链交易哈希的记录（按回执修复链上ID用）
+func GetCreditsWithTxHash() ([]CreditRow, error) {
+	rows, err := utils.DB.Query(
+		`SELECT ` + creditColumns + ` FROM credits WHERE tx_hash IS NOT NULL AND tx_hash <> '' ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanCreditRows(rows)
+}
+
+// RelinkContractCreditIds 批量改写 contract_credit_id（行ID -> 链上ID），在同一事务内执行
+// 先按 ContractCreditIdConflicts 检出冲突的行并跳过（其余照常写入），返回冲突行ID -> 说明；
+// 不冲突的行先统一置空再写入，避免旧的错误关联之间互相触发唯一键冲突
+func RelinkContractCreditIds(links map[int64]int64) (map[int64]string, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	conflicts, err := contractCreditIdConflicts(tx, links, true)
+	if err != nil {
+		return nil, err
+	}
+	for id := range links {
+		if _, ok := conflicts[id]; ok {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE credits SET contract_credit_id = NULL WHERE id = ?`, id); err != nil {
+			return nil, err
+		}
+	}
+	for id, contractCreditId := range links {
+		if _, ok := conflicts[id]; ok {
+			continue
+		}
+		if _, err := tx.Exec(`UPDATE credits SET contract_credit_id = ? WHERE id = ?`, contractCreditId, id); err != nil {
+			return nil, fmt.Errorf("记录 %d 写入链上ID %d 失败: %v", id, contractCreditId, err)
+		}
+	}
+	return conflicts, tx.Commit()
+}
+
+// ContractCreditIdConflicts 检查批量改写链上ID会违反唯一键的行，返回行ID -> 说明：
+// 目标链上ID被多条待改写记录同时指向、被不在本次修复范围内的记录占用，或占用者本身因冲突未改写
+func ContractCreditIdConflicts(links map[int64]int64) (map[int64]string, error) {
+	return contractCreditIdConflicts(utils.DB, links, false)
+}
+
+// rowQuerier *sql.DB 与 *sql.Tx 共有的单行查询
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func contractCreditIdConflicts(q rowQuerier, links map[int64]int64, lock bool) (map[int64]string, error) {
+	query := `SELECT id FROM credits WHERE contract_credit_id = ?`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+	conflicts := make(map[int64]string)
+	owners := make(map[int64][]int64) // 链上ID -> 待改写指向它的行
+	for id, contractCreditId := range links {
+		owners[contractCreditId] = append(owners[contractCreditId], id)
+	}
+	holders := make(map[int64]int64) // 行ID -> 目标链上ID当前占用者（在 links 中，改写后才会腾出）
+	for contractCreditId, ids := range owners {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		if len(ids) > 1 {
+			for _, id := range ids {
+				conflicts[id] = fmt.Sprintf("链上ID %d 同时被记录 %v 指向", contractCreditId, ids)
+			}
+			continue
+		}
+		var holder int64
+		err := q.QueryRow(query, contractCreditId).Scan(&holder)
+		if err == sql.ErrNoRows || (err == nil && holder == ids[0]) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := links[holder]; !ok {
+			conflicts[ids[0]] = fmt.Sprintf("链上ID %d 已被记录 %d 占用（不在本次修复范围内）", contractCreditId, holder)
+			continue
+		}
+		holders[ids[0]] = holder
+	}
+	// 占用者本身冲突不改写时，目标链上ID不会腾出，冲突沿占用链传递
+	for changed := true; changed; {
+		changed = false
+		for id, holder := range holders {
+			if _, ok := conflicts[id]; ok {
+				continue
+			}
+			if _, ok := conflicts[holder]; ok {
+				conflicts[id] = fmt.Sprintf("链上ID %d 的占用者记录 %d 存在冲突、未改写", links[id], holder)
+				changed = true
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// ApproveCredit 审核通过（仅 pending 行）并记 approved 事件，返回受影响行数
+func ApproveCredit(id int64, actor CreditActor, comment, txHash string) (int64, error) {
+	return transitCredit("id", id, "pending",
+		`status = 'approved', audit_admin = ?, audit_time = NOW()`, []interface{}{actor.Address},
+		CreditEventApproved, "approved", actor, comment, txHash)
+}
+
+// RejectCredit 驳回：记录驳回交易哈希与原因原文（仅 pending 行）并记 rejected 事件，返回受影响行数
+// comment 为空时事件备注取驳回原因
+func RejectCredit(id int64, actor CreditActor, txHash, reason, comment string) (int64, error) {
+	if comment == "" {
+		comment = reason
+	}
+	return transitCredit("id", id, "pending",
+		`status = 'rejected', audit_admin = ?, audit_time = NOW(), reject_tx_hash = ?, reject_reason = ?`,
+		[]interface{}{actor.Address, txHash, reason},
+		CreditEventRejected, "rejected", actor, comment, txHash)
+}
+
+// MarkCreditRejectedFromChain 链上已驳回但本地仍为 pending 时补记为 rejected（原因原文未知，保持为空）
+func MarkCreditRejectedFromChain(id int64) (int64, error) {
+	return transitCredit("id", id, "pending", `status = 'rejected', audit_time = NOW()`, nil,
+		CreditEventRejected, "rejected", CreditActor{}, chainSyncComment, "")
+}
+
+// RejectCreditFromChain 索引器：按链上 CreditRejected 事件把 pending 行置为 rejected 并补记驳回交易（幂等）
+func RejectCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	if _, err := transitCredit("contract_credit_id", contractCreditId, "pending",
+		`status = 'rejected', audit_admin = ?, audit_time = NOW(), reject_tx_hash = ?`, []interface{}{auditAdmin, txHash},
+		CreditEventRejected, "rejected", CreditActor{Address: auditAdmin}, "", txHash); err != nil {
+		return err
+	}
+	return stampCreditEventBlock(contractCreditId, CreditEventRejected, txHash, blockNumber)
+}
+
+// RevokeCredit 撤销已审核的学分：记录撤销交易哈希、理由与撤销人（仅 approved 行）并记 revoked 事件，返回受影响行数
+// comment 为空时事件备注取撤销理由
+func RevokeCredit(id int64, actor CreditActor, txHash, reason, comment string) (int64, error) {
+	if comment == "" {
+		comment = reason
+	}
+	return transitCredit("id", id, "approved",
+		`status = 'revoked', revoked_by = ?, revoked_at = NOW(), revoke_tx_hash = ?, revoke_reason = ?`,
+		[]interface{}{actor.Address, txHash, reason},
+		CreditEventRevoked, "revoked", actor, comment, txHash)
+}
+
+// MarkCreditRevokedFromChain 链上已撤销但本地仍为 approved 时补记为 revoked（理由原文未知，保持为空）
+func MarkCreditRevokedFromChain(id int64) (int64, error) {
+	return transitCredit("id", id, "approved", `status = 'revoked', revoked_at = NOW()`, nil,
+		CreditEventRevoked, "revoked", CreditActor{}, chainSyncComment, "")
+}
+
+// RevokeCreditFromChain 索引器：按链上 CreditRevoked 事件把 approved 行置为 revoked 并补记撤销交易（幂等）
+func RevokeCreditFromChain(contractCreditId int64, admin, txHash string, blockNumber uint64) error {
+	if _, err := transitCredit("contract_credit_id", contractCreditId, "approved",
+		`status = 'revoked', revoked_by = ?, revoked_at = NOW(), revoke_tx_hash = ?`, []interface{}{admin, txHash},
+		CreditEventRevoked, "revoked", CreditActor{Address: admin}, "", txHash); err != nil {
+		return err
+	}
+	return stampCreditEventBlock(contractCreditId, CreditEventRevoked, txHash, blockNumber)
 }
 
 // GetCreditById 按主键查一条
 func GetCreditById(id int64) (*CreditRow, error) {
-	var row CreditRow
-	err := utils.DB.QueryRow(
-		`SELECT id, contract_credit_id, student_address, teacher_address, course_name, score, status, tx_hash, audit_admin, audit_time, created_at, updated_at 
-		 FROM credits WHERE id = ?`,
-		id,
-	).Scan(
-		&row.Id, &row.ContractCreditId, &row.StudentAddress, &row.TeacherAddress, &row.CourseName, &row.Score,
-		&row.Status, &row.TxHash, &row.AuditAdmin, &row.AuditTime, &row.CreatedAt, &row.UpdatedAt,
-	)
+	row, err := scanCredit(utils.DB.QueryRow(`SELECT `+creditColumns+` FROM credits WHERE id = ?`, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	if err != nil {
-		return nil, err
+	return row, err
+}
+
+// GetCreditByContractId 按链上学分ID查一条
+func GetCreditByContractId(contractCreditId int64) (*CreditRow, error) {
+	row, err := scanCredit(utils.DB.QueryRow(`SELECT `+creditColumns+` FROM credits WHERE contract_credit_id = ?`, contractCreditId))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return row, err
+}
+
+// GetCreditByTxHash 按录入交易哈希查一条（防止同一笔交易重复落库）
+func GetCreditByTxHash(txHash string) (*CreditRow, error) {
+	row, err := scanCredit(utils.DB.QueryRow(`SELECT `+creditColumns+` FROM credits WHERE tx_hash = ? LIMIT 1`, txHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-	return &row, nil
+	return row, err
 }
 
 func scanCreditRows(rows *sql.Rows) ([]CreditRow, error) {
 	var list []CreditRow
 	for rows.Next() {
-		var row CreditRow
-		err := rows.Scan(
-			&row.Id, &row.ContractCreditId, &row.StudentAddress, &row.TeacherAddress, &row.CourseName, &row.Score,
-			&row.Status, &row.TxHash, &row.AuditAdmin, &row.AuditTime, &row.CreatedAt, &row.UpdatedAt,
-		)
+		row, err := scanCredit(rows)
 		if err != nil {
 			return nil, err
 		}
-		list = append(list, row)
+		list = append(list, *row)
 	}
 	return list, rows.Err()
 }
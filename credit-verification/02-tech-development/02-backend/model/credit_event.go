@@ -0,0 +1,120 @@
+// model/credit_event.go 学分状态流转记录：每次录入/审核/驳回/撤销/更正追加一条，不覆盖历史
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 学分事件类型
+const (
+	CreditEventRecorded  = "recorded"
+	CreditEventApproved  = "approved"
+	CreditEventRejected  = "rejected"
+	CreditEventRevoked   = "revoked"
+	CreditEventCorrected = "corrected"
+	CreditEventFailed    = "failed"  // 录入交易执行失败
+	CreditEventReorged   = "reorged" // 链上分叉回退：撤销来自被丢弃区块的状态流转
+)
+
+// CreditEvent 学分时间线中的一条
+type CreditEvent struct {
+	Id           int64          `json:"id"`
+	CreditId     int64          `json:"credit_id"`
+	Event        string         `json:"event"`
+	FromStatus   sql.NullString `json:"from_status"` // 录入时为空
+	ToStatus     string         `json:"to_status"`
+	ActorId      sql.NullInt64  `json:"actor_id"` // 为空表示由索引器/链上对账补记
+	ActorAddress sql.NullString `json:"actor_address"`
+	Comment      sql.NullString `json:"comment"`
+	TxHash       sql.NullString `json:"tx_hash"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// CreditActor 操作人：UserId 为 0 表示链上发起（索引器、对账）
+type CreditActor struct {
+	UserId  uint64
+	Address string
+}
+
+const creditEventColumns = `id, credit_id, event, from_status, to_status, actor_id, actor_address, comment, tx_hash, created_at`
+
+// execer *sql.DB 与 *sql.Tx 共有的写操作
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertCreditEvent(ex execer, creditId int64, event, fromStatus, toStatus string, actor CreditActor, comment, txHash string) error {
+	_, err := ex.Exec(
+		`INSERT INTO credit_events (credit_id, event, from_status, to_status, actor_id, actor_address, comment, tx_hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		creditId, event,
+		sql.NullString{String: fromStatus, Valid: fromStatus != ""}, toStatus,
+		sql.NullInt64{Int64: int64(actor.UserId), Valid: actor.UserId != 0},
+		sql.NullString{String: actor.Address, Valid: actor.Address != ""},
+		sql.NullString{String: comment, Valid: comment != ""},
+		sql.NullString{String: txHash, Valid: txHash != ""},
+	)
+	return err
+}
+
+// transitCredit 在同一事务中锁定学分行、校验当前状态为 fromStatus、执行更新并追加事件
+// keyColumn 为 id 或 contract_credit_id；set 为 UPDATE 的 SET 子句（须含 status）。状态不符、记录不存在或已被更正取代时返回 0
+// 所属学期已关闭时后台用户发起的变更返回 ErrCreditLocked；链上发起的（索引器、对账）仍照链上事实同步
+func transitCredit(keyColumn string, key int64, fromStatus, set string, setArgs []interface{},
+	event, toStatus string, actor CreditActor, comment, txHash string) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	var status string
+	var supersededBy sql.NullInt64
+	var locked bool
+	err = tx.QueryRow(
+		`SELECT id, status, superseded_by, locked FROM credits WHERE `+keyColumn+` = ? FOR UPDATE`, key,
+	).Scan(&id, &status, &supersededBy, &locked)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if status != fromStatus || supersededBy.Valid {
+		return 0, nil
+	}
+	if locked && actor.UserId != 0 {
+		return 0, ErrCreditLocked
+	}
+	if _, err := tx.Exec(`UPDATE credits SET `+set+` WHERE id = ?`, append(setArgs, id)...); err != nil {
+		return 0, err
+	}
+	if err := insertCreditEvent(tx, id, event, fromStatus, toStatus, actor, comment, txHash); err != nil {
+		return 0, err
+	}
+	return 1, tx.Commit()
+}
+
+// ListCreditEvents 学分时间线（按发生顺序）
+func ListCreditEvents(creditId int64) ([]CreditEvent, error) {
+	rows, err := utils.DB.Query(`SELECT `+creditEventColumns+` FROM credit_events WHERE credit_id = ? ORDER BY id ASC`, creditId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []CreditEvent{}
+	for rows.Next() {
+		var e CreditEvent
+		if err := rows.Scan(
+			&e.Id, &e.CreditId, &e.Event, &e.FromStatus, &e.ToStatus, &e.ActorId, &e.ActorAddress,
+			&e.Comment, &e.TxHash, &e.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,108 @@
+// model/credit_job.go 异步录入学分任务：记录提交的交易，打包后由后台任务关联链上学分ID
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 录入任务状态
+const (
+	CreditJobSubmitted = "submitted" // 交易已提交，等待打包
+	CreditJobLinked    = "linked"    // 已打包并关联链上学分ID
+	CreditJobFailed    = "failed"    // 交易执行失败或无法解析链上学分ID
+)
+
+// CreditJob 录入任务
+type CreditJob struct {
+	Id               int64          `json:"id"`
+	CreditId         int64          `json:"credit_id"` // credits 表主键
+	TxHash           string         `json:"tx_hash"`
+	Status           string         `json:"status"`
+	ContractCreditId sql.NullInt64  `json:"contract_credit_id"`
+	Error            sql.NullString `json:"error"`
+	CreatedBy        uint64         `json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+const creditJobColumns = `id, credit_id, tx_hash, status, contract_credit_id, error, created_by, created_at, updated_at`
+
+// CreateCreditJob 新建录入任务
+func CreateCreditJob(creditId int64, txHash string, createdBy uint64) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO credit_jobs (credit_id, tx_hash, status, created_by) VALUES (?, ?, 'submitted', ?)`,
+		creditId, txHash, createdBy,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetCreditJob 按ID查询任务，不存在返回 nil
+func GetCreditJob(id int64) (*CreditJob, error) {
+	var job CreditJob
+	err := utils.DB.QueryRow(`SELECT `+creditJobColumns+` FROM credit_jobs WHERE id = ?`, id).Scan(
+		&job.Id, &job.CreditId, &job.TxHash, &job.Status, &job.ContractCreditId, &job.Error,
+		&job.CreatedBy, &job.CreatedAt, &job.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// GetSubmittedCreditJobs 等待打包的任务（后台任务轮询用）
+func GetSubmittedCreditJobs() ([]CreditJob, error) {
+	rows, err := utils.DB.Query(`SELECT ` + creditJobColumns + ` FROM credit_jobs WHERE status = 'submitted' ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []CreditJob
+	for rows.Next() {
+		var job CreditJob
+		if err := rows.Scan(
+			&job.Id, &job.CreditId, &job.TxHash, &job.Status, &job.ContractCreditId, &job.Error,
+			&job.CreatedBy, &job.CreatedAt, &job.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, job)
+	}
+	return list, rows.Err()
+}
+
+// UpdateCreditJobTx 交易被替换后更新任务与学分记录的交易哈希
+func UpdateCreditJobTx(job *CreditJob, txHash string) error {
+	if _, err := utils.DB.Exec(`UPDATE credit_jobs SET tx_hash = ? WHERE id = ?`, txHash, job.Id); err != nil {
+		return err
+	}
+	job.TxHash = txHash
+	return UpdateCreditTxHash(job.CreditId, txHash)
+}
+
+// FinishCreditJob 任务完成：记录最终学分行ID与链上学分ID
+func FinishCreditJob(id, creditId, contractCreditId int64) error {
+	_, err := utils.DB.Exec(
+		`UPDATE credit_jobs SET status = 'linked', credit_id = ?, contract_credit_id = ?, error = NULL WHERE id = ?`,
+		creditId, contractCreditId, id,
+	)
+	return err
+}
+
+// FailCreditJob 任务失败：记录原因，并把仍为 pending 的学分记录标记为 failed（记 failed 事件）
+func FailCreditJob(job *CreditJob, reason string) error {
+	if _, err := utils.DB.Exec(`UPDATE credit_jobs SET status = 'failed', error = ? WHERE id = ?`, reason, job.Id); err != nil {
+		return err
+	}
+	_, err := transitCredit("id", job.CreditId, "pending", `status = 'failed'`, nil,
+		CreditEventFailed, "failed", CreditActor{}, reason, job.TxHash)
+	return err
+}
@@ -0,0 +1,127 @@
+// model/credit_reorg.go 链上分叉回退：撤销来自被丢弃区块的建档关联与状态流转，由索引器重扫新链后重新写入
+package model
+
+import (
+	"database/sql"
+	"fmt"
+
+	"campus-credit-backend/utils"
+)
+
+// stampCreditEventBlock 索引器处理某条链上事件后，在对应的学分事件上补记所在区块（后端按回执已写入、索引器未再写的也一并补记）
+func stampCreditEventBlock(contractCreditId int64, event, txHash string, blockNumber uint64) error {
+	_, err := utils.DB.Exec(
+		`UPDATE credit_events e JOIN credits c ON c.id = e.credit_id SET e.block_number = ?
+		 WHERE c.contract_credit_id = ? AND e.event = ? AND e.tx_hash = ? AND e.block_number IS NULL`,
+		blockNumber, contractCreditId, event, txHash,
+	)
+	return err
+}
+
+// RewindCreditsFromChain 索引器检测到分叉、从 fromBlock 重扫前调用（幂等）：
+// block_number >= fromBlock 的学分事件视为未发生，学分状态回到其中最早一条的 from_status，更正关联一并解除；
+// chain_block >= fromBlock 的行解除 contract_credit_id，等录入交易在新链打包后由索引器重新关联或建档。
+// 每条受影响的学分追加一条 reorged 事件，被撤销事件的 block_number 置空
+func RewindCreditsFromChain(fromBlock uint64) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	target := make(map[int64]string) // 学分ID -> 回退后的状态
+	var affected []int64
+	var corrected []int64
+	rows, err := tx.Query(
+		`SELECT credit_id, event, from_status FROM credit_events WHERE block_number >= ? ORDER BY id ASC FOR UPDATE`, fromBlock,
+	)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var creditId int64
+		var event string
+		var from sql.NullString
+		if err := rows.Scan(&creditId, &event, &from); err != nil {
+			rows.Close()
+			return err
+		}
+		if _, ok := target[creditId]; !ok && from.Valid {
+			target[creditId] = from.String
+			affected = append(affected, creditId)
+		}
+		if event == CreditEventCorrected {
+			corrected = append(corrected, creditId)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	unlink := make(map[int64]bool)
+	rows, err = tx.Query(`SELECT id FROM credits WHERE chain_block >= ? FOR UPDATE`, fromBlock)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		unlink[id] = true
+		if _, ok := target[id]; !ok {
+			affected = append(affected, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// 解除被丢弃区块中的更正关联：原记录重新成为当前版本
+	for _, id := range corrected {
+		if _, err := tx.Exec(
+			`UPDATE credits dst JOIN credits src ON src.superseded_by = dst.id SET dst.supersedes = NULL WHERE src.id = ?`, id,
+		); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE credits SET superseded_by = NULL WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range affected {
+		var status string
+		if err := tx.QueryRow(`SELECT status FROM credits WHERE id = ?`, id).Scan(&status); err != nil {
+			return err
+		}
+		to, ok := target[id]
+		if !ok {
+			to = status
+		}
+		set := `status = ?`
+		switch to {
+		case "pending":
+			set += `, audit_admin = NULL, audit_time = NULL, reject_tx_hash = NULL, revoke_tx_hash = NULL, revoked_by = NULL, revoked_at = NULL`
+		case "approved":
+			set += `, revoke_tx_hash = NULL, revoked_by = NULL, revoked_at = NULL`
+		}
+		comment := fmt.Sprintf("链上分叉，撤销区块 %d 及之后的状态流转", fromBlock)
+		if unlink[id] {
+			set += `, contract_credit_id = NULL, chain_block = NULL`
+			comment = fmt.Sprintf("链上分叉，录入所在区块（>= %d）已被丢弃，解除链上ID关联", fromBlock)
+		}
+		if _, err := tx.Exec(`UPDATE credits SET `+set+` WHERE id = ?`, to, id); err != nil {
+			return err
+		}
+		if err := insertCreditEvent(tx, id, CreditEventReorged, status, to, CreditActor{}, comment, ""); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE credit_events SET block_number = NULL WHERE block_number >= ?`, fromBlock); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,147 @@
+// model/enrollment.go 开课（学期 + 课程）的选课名单与任课安排：录入学分须教师任教且学生已选该开课
+package model
+
+import (
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// Enrollment 学生选课
+type Enrollment struct {
+	Id             int64     `json:"id"`
+	TermId         int64     `json:"term_id"`
+	CourseId       int64     `json:"course_id"`
+	StudentAddress string    `json:"student_address"` // 与链上 studentId 一致（钱包地址或学号）
+	ImportedBy     uint64    `json:"imported_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TeachingAssignment 教师任课
+type TeachingAssignment struct {
+	Id         int64     `json:"id"`
+	TermId     int64     `json:"term_id"`
+	CourseId   int64     `json:"course_id"`
+	TeacherId  uint64    `json:"teacher_id"`
+	Username   string    `json:"username"`
+	ImportedBy uint64    `json:"imported_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AddEnrollment 导入一条选课，已存在时返回 false
+func AddEnrollment(termId, courseId int64, studentAddress string, importedBy uint64) (bool, error) {
+	res, err := utils.DB.Exec(
+		`INSERT IGNORE INTO enrollments (term_id, course_id, student_address, imported_by) VALUES (?, ?, ?, ?)`,
+		termId, courseId, studentAddress, importedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// AddTeachingAssignment 导入一条任课安排，已存在时返回 false
+func AddTeachingAssignment(termId, courseId int64, teacherId, importedBy uint64) (bool, error) {
+	res, err := utils.DB.Exec(
+		`INSERT IGNORE INTO teaching_assignments (term_id, course_id, teacher_id, imported_by) VALUES (?, ?, ?, ?)`,
+		termId, courseId, teacherId, importedBy,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// IsEnrolled 学生是否选了该开课（地址不区分大小写）
+func IsEnrolled(termId, courseId int64, studentAddress string) (bool, error) {
+	var n int
+	err := utils.DB.QueryRow(
+		`SELECT COUNT(1) FROM enrollments WHERE term_id = ? AND course_id = ? AND student_address = ?`,
+		termId, courseId, studentAddress,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// IsTeaching 教师是否任教该开课
+func IsTeaching(termId, courseId int64, teacherId uint64) (bool, error) {
+	var n int
+	err := utils.DB.QueryRow(
+		`SELECT COUNT(1) FROM teaching_assignments WHERE term_id = ? AND course_id = ? AND teacher_id = ?`,
+		termId, courseId, teacherId,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// ListEnrollments 选课名单（term_id、course_id 为 0 时不过滤）
+func ListEnrollments(termId, courseId int64) ([]Enrollment, error) {
+	query, args := offeringFilter(
+		`SELECT id, term_id, course_id, student_address, imported_by, created_at FROM enrollments WHERE 1 = 1`, termId, courseId)
+	rows, err := utils.DB.Query(query+` ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Enrollment{}
+	for rows.Next() {
+		var e Enrollment
+		if err := rows.Scan(&e.Id, &e.TermId, &e.CourseId, &e.StudentAddress, &e.ImportedBy, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// ListTeachingAssignments 任课安排（term_id、course_id 为 0 时不过滤），附教师用户名
+func ListTeachingAssignments(termId, courseId int64) ([]TeachingAssignment, error) {
+	query, args := offeringFilter(
+		`SELECT ta.id, ta.term_id, ta.course_id, ta.teacher_id, IFNULL(u.username, ''), ta.imported_by, ta.created_at
+		 FROM teaching_assignments ta LEFT JOIN users u ON u.id = ta.teacher_id WHERE 1 = 1`, termId, courseId)
+	rows, err := utils.DB.Query(query+` ORDER BY ta.id ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []TeachingAssignment{}
+	for rows.Next() {
+		var a TeachingAssignment
+		if err := rows.Scan(&a.Id, &a.TermId, &a.CourseId, &a.TeacherId, &a.Username, &a.ImportedBy, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, a)
+	}
+	return list, rows.Err()
+}
+
+// DeleteEnrollment 删除一条选课，返回受影响行数
+func DeleteEnrollment(id int64) (int64, error) {
+	res, err := utils.DB.Exec(`DELETE FROM enrollments WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// DeleteTeachingAssignment 删除一条任课安排，返回受影响行数
+func DeleteTeachingAssignment(id int64) (int64, error) {
+	res, err := utils.DB.Exec(`DELETE FROM teaching_assignments WHERE id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func offeringFilter(query string, termId, courseId int64) (string, []interface{}) {
+	var args []interface{}
+	if termId > 0 {
+		query += ` AND term_id = ?`
+		args = append(args, termId)
+	}
+	if courseId > 0 {
+		query += ` AND course_id = ?`
+		args = append(args, courseId)
+	}
+	return query, args
+}
@@ -0,0 +1,278 @@
+// model/permission.go 角色与权限：roles / role_permissions 表，内置角色初始化与内存缓存
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 权限点
+const (
+	PermCreditReadOwn = "credit:read_own"   // 查看本人学分
+	PermCreditReadAll = "credit:read_all"   // 查看全部学分、待审核列表与他人录入任务
+	PermCreditRecord  = "credit:record"     // 录入学分
+	PermCreditApprove = "credit:approve"    // 审核通过
+	PermCreditReject  = "credit:reject"     // 驳回
+	PermCreditRevoke  = "credit:revoke"     // 撤销已审核学分
+	PermCreditSync    = "credit:sync"       // 链上/本地学分同步
+	PermCourseManage  = "course:manage"     // 维护课程目录
+	PermTermManage    = "term:manage"       // 维护学期、开通补录、关闭学期
+	PermEnrollManage  = "enrollment:manage" // 导入选课名单与任课安排
+	PermRoleAssign    = "role:assign"       // 链上分配角色
+	PermRoleRead      = "role:read"         // 查询链上角色
+	PermRoleManage    = "role:manage"       // 管理角色定义与权限
+	PermTxRead        = "tx:read"           // 查看交易队列与台账
+	PermUserReadAll   = "user:read_all"     // 查看全部用户的绑定审计等
+	PermAll           = "*"                 // 全部权限（super_admin）
+)
+
+// AllPermissions 可分配的权限点及说明
+var AllPermissions = map[string]string{
+	PermCreditReadOwn: "查看本人学分",
+	PermCreditReadAll: "查看全部学分与待审核列表",
+	PermCreditRecord:  "录入学分",
+	PermCreditApprove: "审核通过学分",
+	PermCreditReject:  "驳回学分",
+	PermCreditRevoke:  "撤销已审核学分",
+	PermCreditSync:    "同步链上学分",
+	PermCourseManage:  "维护课程目录",
+	PermTermManage:    "维护学期与成绩录入窗口",
+	PermEnrollManage:  "导入选课名单与任课安排",
+	PermRoleAssign:    "分配链上角色",
+	PermRoleRead:      "查询链上角色",
+	PermRoleManage:    "管理角色定义与权限",
+	PermTxRead:        "查看链上交易队列与台账",
+	PermUserReadAll:   "查看全部用户绑定审计",
+	PermAll:           "全部权限",
+}
+
+// Role 角色定义
+type Role struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Builtin     bool      `json:"builtin"` // 内置角色不可删除
+	Permissions []string  `json:"permissions"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// builtinRoles 内置角色（原 student/teacher/admin 三种角色迁移而来，另增 teacher_admin/super_admin/auditor/credit_reviewer）；
+// teacher_admin 是链上同时持有教师与管理员权限位时推导出的角色，不含 *，super_admin 只能由超级管理员经 /users/:id/role 分配
+var builtinRoles = []Role{
+	{Name: "student", Description: "学生", Permissions: []string{PermCreditReadOwn}},
+	{Name: "teacher", Description: "教师", Permissions: []string{PermCreditReadOwn, PermCreditRecord, PermCreditSync}},
+	{Name: "admin", Description: "管理员", Permissions: []string{PermCreditReadAll, PermCreditApprove, PermCreditReject, PermCreditRevoke, PermCreditSync, PermCourseManage, PermTermManage, PermEnrollManage, PermRoleAssign, PermRoleRead, PermRoleManage, PermTxRead, PermUserReadAll}},
+	{Name: "teacher_admin", Description: "教师兼管理员", Permissions: []string{PermCreditReadOwn, PermCreditRecord, PermCreditReadAll, PermCreditApprove, PermCreditReject, PermCreditRevoke, PermCreditSync, PermCourseManage, PermTermManage, PermEnrollManage, PermRoleAssign, PermRoleRead, PermRoleManage, PermTxRead, PermUserReadAll}},
+	{Name: "super_admin", Description: "超级管理员", Permissions: []string{PermAll}},
+	{Name: "auditor", Description: "审计员（只读）", Permissions: []string{PermCreditReadAll, PermRoleRead, PermTxRead, PermUserReadAll}},
+	{Name: "credit_reviewer", Description: "学分审核员（全校，不按院系限定）", Permissions: []string{PermCreditReadAll, PermCreditApprove, PermCreditReject, PermCreditSync}},
+}
+
+var (
+	permCache     map[string]map[string]bool
+	permCacheLock sync.RWMutex
+)
+
+// InitPermissions 写入缺失的内置角色（已有角色的权限不覆盖，便于管理员调整），并加载权限缓存
+func InitPermissions() error {
+	for _, r := range builtinRoles {
+		res, err := utils.DB.Exec(
+			`INSERT IGNORE INTO roles (name, description, builtin) VALUES (?, ?, 1)`,
+			r.Name, r.Description,
+		)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		for _, p := range r.Permissions {
+			if _, err := utils.DB.Exec(`INSERT IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`, r.Name, p); err != nil {
+				return err
+			}
+		}
+	}
+	return reloadPermissions()
+}
+
+func reloadPermissions() error {
+	rows, err := utils.DB.Query(`SELECT role, permission FROM role_permissions`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cache := make(map[string]map[string]bool)
+	for rows.Next() {
+		var role, perm string
+		if err := rows.Scan(&role, &perm); err != nil {
+			return err
+		}
+		if cache[role] == nil {
+			cache[role] = make(map[string]bool)
+		}
+		cache[role][perm] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	permCacheLock.Lock()
+	permCache = cache
+	permCacheLock.Unlock()
+	return nil
+}
+
+// RoleHasPermission 角色是否拥有某权限（* 代表全部）
+func RoleHasPermission(role, perm string) bool {
+	role = strings.ToLower(strings.TrimSpace(role))
+	permCacheLock.RLock()
+	defer permCacheLock.RUnlock()
+	perms := permCache[role]
+	return perms[perm] || perms[PermAll]
+}
+
+// RolePermissions 角色的全部权限（已排序）
+func RolePermissions(role string) []string {
+	role = strings.ToLower(strings.TrimSpace(role))
+	permCacheLock.RLock()
+	defer permCacheLock.RUnlock()
+	perms := make([]string, 0, len(permCache[role]))
+	for p := range permCache[role] {
+		perms = append(perms, p)
+	}
+	sort.Strings(perms)
+	return perms
+}
+
+// ValidPermission 是否为已定义的权限点
+func ValidPermission(perm string) bool {
+	_, ok := AllPermissions[perm]
+	return ok
+}
+
+// ListRoles 全部角色及其权限
+func ListRoles() ([]Role, error) {
+	rows, err := utils.DB.Query(`SELECT name, description, builtin, created_at FROM roles ORDER BY builtin DESC, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []Role
+	for rows.Next() {
+		var r Role
+		if err := rows.Scan(&r.Name, &r.Description, &r.Builtin, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	permCacheLock.RLock()
+	for i := range list {
+		for p := range permCache[list[i].Name] {
+			list[i].Permissions = append(list[i].Permissions, p)
+		}
+		sort.Strings(list[i].Permissions)
+	}
+	permCacheLock.RUnlock()
+	return list, nil
+}
+
+// GetRoleDef 按名称查角色定义，不存在返回 nil
+func GetRoleDef(name string) (*Role, error) {
+	var r Role
+	err := utils.DB.QueryRow(
+		`SELECT name, description, builtin, created_at FROM roles WHERE name = ?`, name,
+	).Scan(&r.Name, &r.Description, &r.Builtin, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateRoleDef 新建角色
+func CreateRoleDef(name, description string, perms []string) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`INSERT INTO roles (name, description, builtin) VALUES (?, ?, 0)`, name, description); err != nil {
+		return err
+	}
+	if err := setRolePermissions(tx, name, perms); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return reloadPermissions()
+}
+
+// UpdateRoleDef 更新角色说明与权限（整体替换）；权限变化后递增该角色用户的 token_version
+func UpdateRoleDef(name, description string, perms []string) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`UPDATE roles SET description = ? WHERE name = ?`, description, name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role = ?`, name); err != nil {
+		return err
+	}
+	if err := setRolePermissions(tx, name, perms); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE users SET token_version = token_version + 1 WHERE role = ?`, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return reloadPermissions()
+}
+
+// DeleteRoleDef 删除非内置且无人使用的角色
+func DeleteRoleDef(name string) error {
+	var users int
+	if err := utils.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ?`, name).Scan(&users); err != nil {
+		return err
+	}
+	if users > 0 {
+		return fmt.Errorf("仍有 %d 个用户使用该角色", users)
+	}
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role = ?`, name); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM roles WHERE name = ? AND builtin = 0`, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return reloadPermissions()
+}
+
+func setRolePermissions(tx *sql.Tx, role string, perms []string) error {
+	for _, p := range perms {
+		if _, err := tx.Exec(`INSERT IGNORE INTO role_permissions (role, permission) VALUES (?, ?)`, role, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// model/refresh_token.go 刷新 Token：签发、轮换、吊销（只存摘要）
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// RefreshToken 刷新 Token 记录
+type RefreshToken struct {
+	Id        int64
+	UserId    uint64
+	TokenHash string
+	FamilyId  string
+	ExpiresAt time.Time
+	RevokedAt sql.NullTime
+}
+
+// CreateRefreshToken 保存新签发的刷新 Token
+func CreateRefreshToken(userId uint64, tokenHash, familyId string, expiresAt time.Time, userAgent, ip string) (int64, error) {
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	res, err := utils.DB.Exec(
+		`INSERT INTO refresh_tokens (user_id, token_hash, family_id, expires_at, user_agent, ip) VALUES (?, ?, ?, ?, ?, ?)`,
+		userId, tokenHash, familyId, expiresAt, userAgent, ip,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetRefreshTokenByHash 按摘要查询
+func GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error) {
+	var t RefreshToken
+	err := utils.DB.QueryRow(
+		`SELECT id, user_id, token_hash, family_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = ?`,
+		tokenHash,
+	).Scan(&t.Id, &t.UserId, &t.TokenHash, &t.FamilyId, &t.ExpiresAt, &t.RevokedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RotateRefreshToken 吊销旧 Token 并记录替换它的新 Token；旧 Token 已被吊销（并发重复刷新）时返回 false
+func RotateRefreshToken(oldId, newId int64) (bool, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = ? WHERE id = ? AND revoked_at IS NULL`,
+		newId, oldId,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RevokeRefreshToken 吊销单个 Token（登出当前会话）
+func RevokeRefreshToken(userId uint64, tokenHash string) error {
+	_, err := utils.DB.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND token_hash = ? AND revoked_at IS NULL`,
+		userId, tokenHash,
+	)
+	return err
+}
+
+// RevokeRefreshFamily 吊销同一登录派生的全部 Token（检测到已轮换 Token 被重用时）
+func RevokeRefreshFamily(familyId string) error {
+	_, err := utils.DB.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = ? AND revoked_at IS NULL`,
+		familyId,
+	)
+	return err
+}
+
+// RevokeUserRefreshTokens 吊销用户全部 Token（登出所有会话、改密码）
+func RevokeUserRefreshTokens(userId uint64) error {
+	_, err := utils.DB.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`,
+		userId,
+	)
+	return err
+}
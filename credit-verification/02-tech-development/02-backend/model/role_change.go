@@ -0,0 +1,385 @@
+// model/role_change.go 链上角色变更记录：assignRole / revokeRole 交易提交后登记，打包成功后再在同一事务中写 users.role
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 角色变更状态
+const (
+	RoleChangeSubmitted = "submitted" // 交易已提交，等待打包
+	RoleChangeApplied   = "applied"   // 已打包，数据库与缓存已同步
+	RoleChangeFailed    = "failed"    // 交易执行失败或已失效，数据库未改动
+)
+
+// 角色变更动作
+const (
+	RoleActionAssign = "assign"
+	RoleActionRevoke = "revoke"
+)
+
+// RoleChange 角色变更记录
+type RoleChange struct {
+	Id          int64          `json:"id"`
+	UserAddress string         `json:"user_address"`
+	Action      string         `json:"action"`     // assign / revoke
+	ChainRole   string         `json:"chain_role"` // assignRole / revokeRole 的 role 参数
+	DbRole      sql.NullString `json:"db_role"`    // 打包后 users.role 的最终取值
+	Reason      sql.NullString `json:"reason"`     // 撤销原因
+	TxHash      string         `json:"tx_hash"`
+	Status      string         `json:"status"`
+	OperatorId  uint64         `json:"operator_id"`
+	Error       sql.NullString `json:"error"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+}
+
+const roleChangeColumns = `id, user_address, action, chain_role, db_role, reason, tx_hash, status, operator_id, error, created_at, updated_at`
+
+func scanRoleChange(row interface{ Scan(...interface{}) error }) (*RoleChange, error) {
+	var rc RoleChange
+	err := row.Scan(
+		&rc.Id, &rc.UserAddress, &rc.Action, &rc.ChainRole, &rc.DbRole, &rc.Reason, &rc.TxHash, &rc.Status,
+		&rc.OperatorId, &rc.Error, &rc.CreatedAt, &rc.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rc, nil
+}
+
+// ChainFlagsRequired 本地角色需要的合约权限位：能录入学分需 isTeacher，能审核需 isAdmin
+func ChainFlagsRequired(role string) (teacher, admin bool) {
+	return RoleHasPermission(role, PermCreditRecord), RoleHasPermission(role, PermCreditApprove)
+}
+
+// RoleFromChainFlags 按链上权限位推导内置角色；两者都有时为 teacher_admin，从不推导出持有 * 的 super_admin
+func RoleFromChainFlags(isTeacher, isAdmin bool) string {
+	switch {
+	case isTeacher && isAdmin:
+		return "teacher_admin"
+	case isTeacher:
+		return "teacher"
+	case isAdmin:
+		return "admin"
+	}
+	return "student"
+}
+
+// dbRoleAfterAssign 链上分配 chainRole 后 users.role 的取值，分配只增加权限位、从不降级：
+// 当前角色已具备该权限位（如 super_admin 被分配 admin）或分配的是 student（合约不改权限位）时保持不变，
+// 否则按原有权限位加上新权限位取内置角色（如 credit_reviewer 被分配 teacher 得到 teacher_admin）
+func dbRoleAfterAssign(current, chainRole string) string {
+	teacher, admin := ChainFlagsRequired(current)
+	switch chainRole {
+	case "teacher":
+		if teacher {
+			return current
+		}
+		teacher = true
+	case "admin":
+		if admin {
+			return current
+		}
+		admin = true
+	default:
+		if current != "" {
+			return current
+		}
+	}
+	return RoleFromChainFlags(teacher, admin)
+}
+
+// dbRoleAfterRevoke 链上撤销 chainRole 后 users.role 的取值：当前角色依赖该权限位时降级为剩余权限位对应的角色，否则不变
+func dbRoleAfterRevoke(current, chainRole string) string {
+	teacher, admin := ChainFlagsRequired(current)
+	switch {
+	case chainRole == "teacher" && teacher:
+		teacher = false
+	case chainRole == "admin" && admin:
+		admin = false
+	default:
+		return current
+	}
+	return RoleFromChainFlags(teacher, admin)
+}
+
+// dbRoleForChainFlags 按地址当前的链上权限位校正 users.role：缺少的权限位按 dbRoleAfterAssign 补上，多出的按 dbRoleAfterRevoke 去掉，
+// 权限位已一致时保持不变（auditor、credit_reviewer 等自定义角色不会被改写）
+func dbRoleForChainFlags(current string, isTeacher, isAdmin bool) string {
+	role := current
+	teacher, admin := ChainFlagsRequired(current)
+	if isTeacher && !teacher {
+		role = dbRoleAfterAssign(role, "teacher")
+	}
+	if isAdmin && !admin {
+		role = dbRoleAfterAssign(role, "admin")
+	}
+	if !isTeacher && teacher {
+		role = dbRoleAfterRevoke(role, "teacher")
+	}
+	if !isAdmin && admin {
+		role = dbRoleAfterRevoke(role, "admin")
+	}
+	return role
+}
+
+// CreateRoleChange 登记已提交的 assignRole / revokeRole 交易（reason 仅撤销时填写）
+func CreateRoleChange(address, action, chainRole, reason, txHash string, operatorId uint64) (int64, error) {
+	var r sql.NullString
+	if reason != "" {
+		r = sql.NullString{String: reason, Valid: true}
+	}
+	res, err := utils.DB.Exec(
+		`INSERT INTO role_changes (user_address, action, chain_role, reason, tx_hash, status, operator_id) VALUES (?, ?, ?, ?, ?, 'submitted', ?)`,
+		address, action, chainRole, r, txHash, operatorId,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetRoleChange 按ID查询，不存在返回 nil
+func GetRoleChange(id int64) (*RoleChange, error) {
+	rc, err := scanRoleChange(utils.DB.QueryRow(`SELECT `+roleChangeColumns+` FROM role_changes WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rc, err
+}
+
+// GetRoleChangeByTxHash 按交易哈希查询（钱包签名交易去重），不存在返回 nil
+func GetRoleChangeByTxHash(txHash string) (*RoleChange, error) {
+	rc, err := scanRoleChange(utils.DB.QueryRow(`SELECT `+roleChangeColumns+` FROM role_changes WHERE tx_hash = ?`, txHash))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return rc, err
+}
+
+// GetSubmittedRoleChanges 待确认的角色变更（按提交顺序，保证同一地址多次变更按序生效）
+func GetSubmittedRoleChanges() ([]RoleChange, error) {
+	rows, err := utils.DB.Query(`SELECT ` + roleChangeColumns + ` FROM role_changes WHERE status = 'submitted' ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []RoleChange
+	for rows.Next() {
+		rc, err := scanRoleChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *rc)
+	}
+	return list, rows.Err()
+}
+
+// ListRoleChanges 角色变更记录（address 为空时查全部），最新在前
+func ListRoleChanges(address string, limit int) ([]RoleChange, error) {
+	query := `SELECT ` + roleChangeColumns + ` FROM role_changes`
+	args := []interface{}{}
+	if address != "" {
+		query += ` WHERE LOWER(user_address) = LOWER(?)`
+		args = append(args, address)
+	}
+	query += ` ORDER BY id DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := utils.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := make([]RoleChange, 0)
+	for rows.Next() {
+		rc, err := scanRoleChange(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *rc)
+	}
+	return list, rows.Err()
+}
+
+// UpdateRoleChangeTx 交易被提价替换后更新哈希
+func UpdateRoleChangeTx(rc *RoleChange, txHash string) error {
+	if _, err := utils.DB.Exec(`UPDATE role_changes SET tx_hash = ? WHERE id = ?`, txHash, rc.Id); err != nil {
+		return err
+	}
+	rc.TxHash = txHash
+	return nil
+}
+
+// FailRoleChange 交易失败：仅记录原因，users.role 保持不变
+func FailRoleChange(id int64, reason string) error {
+	_, err := utils.DB.Exec(`UPDATE role_changes SET status = 'failed', error = ? WHERE id = ? AND status = 'submitted'`, reason, id)
+	return err
+}
+
+// ApplyRoleChange 交易打包成功后在同一事务中写 users.role 并标记 applied，返回最终的本地角色
+// 分配：地址无对应用户时建 wallet_ 用户，角色变化时递增 token_version；
+// 撤销：按剩余权限位降级，并无条件递增 token_version、吊销刷新 Token，使该用户所有会话失效
+func ApplyRoleChange(rc *RoleChange) (string, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var userId uint64
+	var current string
+	err = tx.QueryRow(
+		`SELECT id, role FROM users WHERE LOWER(TRIM(address)) = LOWER(TRIM(?)) LIMIT 1 FOR UPDATE`, rc.UserAddress,
+	).Scan(&userId, &current)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+	revoke := rc.Action == RoleActionRevoke
+	var role string
+	if revoke {
+		role = dbRoleAfterRevoke(current, rc.ChainRole)
+	} else {
+		role = dbRoleAfterAssign(current, rc.ChainRole)
+	}
+
+	res, err := tx.Exec(
+		`UPDATE role_changes SET status = 'applied', db_role = ?, error = NULL WHERE id = ? AND status = 'submitted'`,
+		role, rc.Id,
+	)
+	if err != nil {
+		return "", err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return role, nil // 已被其他实例处理
+	}
+
+	switch {
+	case userId == 0 && revoke:
+		// 地址无本地用户，只记录链上撤销
+	case userId == 0:
+		if err := insertWalletUser(tx, rc.UserAddress, role); err != nil {
+			return "", err
+		}
+	case revoke:
+		if _, err := tx.Exec(
+			`UPDATE users SET token_version = token_version + 1, role = ?, updated_at = NOW() WHERE id = ?`,
+			role, userId,
+		); err != nil {
+			return "", err
+		}
+		if _, err := tx.Exec(
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`, userId,
+		); err != nil {
+			return "", err
+		}
+	default:
+		if _, err := tx.Exec(
+			`UPDATE users SET token_version = token_version + (role <> ?), role = ?, updated_at = NOW() WHERE id = ?`,
+			role, role, userId,
+		); err != nil {
+			return "", err
+		}
+	}
+	return role, tx.Commit()
+}
+
+// insertWalletUser 事务内创建 wallet_ 用户（字段与 CreateWalletUser 一致）
+func insertWalletUser(tx *sql.Tx, address, role string) error {
+	username := "wallet_" + address
+	if len(username) > 50 {
+		username = username[:50]
+	}
+	hashPwd, err := utils.HashPassword("wallet-nologin")
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		"INSERT INTO users (username, password, address, role) VALUES (?, ?, ?, ?)",
+		username, hashPwd, address, role,
+	); err != nil {
+		return fmt.Errorf("创建 wallet_ 用户失败: %v", err)
+	}
+	return nil
+}
+
+// ListUsersWithAddress 已绑定钱包地址的用户（角色漂移检查用）
+func ListUsersWithAddress() ([]User, error) {
+	rows, err := utils.DB.Query(
+		`SELECT id, username, address, role, token_version, created_at, updated_at FROM users
+		 WHERE address IS NOT NULL AND address <> '' ORDER BY id ASC`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var list []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.Id, &u.Username, &u.Address, &u.Role, &u.TokenVersion, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, u)
+	}
+	return list, rows.Err()
+}
+
+// SetUserRole 直接设置用户角色（按链上修复数据库），角色变化时递增 token_version
+func SetUserRole(userId uint64, role string) error {
+	_, err := utils.DB.Exec(
+		`UPDATE users SET token_version = token_version + (role <> ?), role = ?, updated_at = NOW() WHERE id = ?`,
+		role, role, userId,
+	)
+	return err
+}
+
+// SyncUserRoleFromChain 索引器：按地址当前的链上权限位同步 users.role（幂等），重放旧的 RoleAssigned 不会恢复已撤销的角色
+// 地址无本地用户且有权限位时建 wallet_ 用户；失去权限位时与 ApplyRoleChange 的撤销一致，递增 token_version 并吊销刷新 Token
+func SyncUserRoleFromChain(address string, isTeacher, isAdmin bool) error {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var userId uint64
+	var current string
+	err = tx.QueryRow(
+		`SELECT id, role FROM users WHERE LOWER(TRIM(address)) = LOWER(TRIM(?)) LIMIT 1 FOR UPDATE`, address,
+	).Scan(&userId, &current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if userId == 0 {
+		if !isTeacher && !isAdmin {
+			return nil
+		}
+		if err := insertWalletUser(tx, address, RoleFromChainFlags(isTeacher, isAdmin)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	role := dbRoleForChainFlags(current, isTeacher, isAdmin)
+	if role == current {
+		return nil
+	}
+	if _, err := tx.Exec(
+		`UPDATE users SET token_version = token_version + 1, role = ?, updated_at = NOW() WHERE id = ?`, role, userId,
+	); err != nil {
+		return err
+	}
+	if teacher, admin := ChainFlagsRequired(current); (teacher && !isTeacher) || (admin && !isAdmin) {
+		if _, err := tx.Exec(
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`, userId,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
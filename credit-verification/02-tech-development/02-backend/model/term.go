@@ -0,0 +1,211 @@
+// model/term.go 学期：起止日期与成绩录入窗口，窗口外录入需管理员开通补录；学期关闭后其学分全部锁定
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// 学期状态
+const (
+	TermOpen   = "open"
+	TermClosed = "closed" // 已关闭：不可再录入，学分锁定
+)
+
+// ErrCreditLocked 学分所属学期已关闭
+var ErrCreditLocked = errors.New("学分所属学期已关闭，记录已锁定")
+
+// Term 学期，日期均按天计（含首尾两天）
+type Term struct {
+	Id           int64         `json:"id"`
+	Code         string        `json:"code"`
+	Name         string        `json:"name"`
+	StartDate    time.Time     `json:"start_date"`
+	EndDate      time.Time     `json:"end_date"`
+	GradingStart time.Time     `json:"grading_start"` // 成绩录入窗口
+	GradingEnd   time.Time     `json:"grading_end"`
+	Status       string        `json:"status"`
+	ClosedBy     sql.NullInt64 `json:"closed_by"`
+	ClosedAt     sql.NullTime  `json:"closed_at"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// GradingOpen 当前日期是否在成绩录入窗口内
+func (t *Term) GradingOpen(now time.Time) bool {
+	today := now.Format("2006-01-02")
+	return today >= t.GradingStart.Format("2006-01-02") && today <= t.GradingEnd.Format("2006-01-02")
+}
+
+const termColumns = `id, code, name, start_date, end_date, grading_start, grading_end, status, closed_by, closed_at, created_at, updated_at`
+
+func scanTerm(row interface{ Scan(...interface{}) error }) (*Term, error) {
+	var t Term
+	err := row.Scan(
+		&t.Id, &t.Code, &t.Name, &t.StartDate, &t.EndDate, &t.GradingStart, &t.GradingEnd,
+		&t.Status, &t.ClosedBy, &t.ClosedAt, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// CreateTerm 新建学期（状态为 open），返回主键
+func CreateTerm(t *Term) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO terms (code, name, start_date, end_date, grading_start, grading_end, status) VALUES (?, ?, ?, ?, ?, ?, 'open')`,
+		t.Code, t.Name, t.StartDate, t.EndDate, t.GradingStart, t.GradingEnd,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateTerm 更新学期名称与日期（仅 open 学期），返回受影响行数
+func UpdateTerm(t *Term) (int64, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE terms SET name = ?, start_date = ?, end_date = ?, grading_start = ?, grading_end = ? WHERE id = ? AND status = 'open'`,
+		t.Name, t.StartDate, t.EndDate, t.GradingStart, t.GradingEnd, t.Id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// CloseTerm 关闭学期并在同一事务中锁定该学期全部学分，返回锁定的记录数；学期已关闭时返回 -1
+func CloseTerm(id int64, closedBy uint64) (int64, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(
+		`UPDATE terms SET status = 'closed', closed_by = ?, closed_at = NOW() WHERE id = ? AND status = 'open'`,
+		closedBy, id,
+	)
+	if err != nil {
+		return 0, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return -1, nil
+	}
+	res, err = tx.Exec(`UPDATE credits SET locked = 1 WHERE term_id = ?`, id)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
+// GetTermById 按主键查询，不存在返回 nil
+func GetTermById(id int64) (*Term, error) {
+	t, err := scanTerm(utils.DB.QueryRow(`SELECT `+termColumns+` FROM terms WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// GetTermByCode 按学期代码查询，不存在返回 nil
+func GetTermByCode(code string) (*Term, error) {
+	t, err := scanTerm(utils.DB.QueryRow(`SELECT `+termColumns+` FROM terms WHERE code = ?`, code))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListTerms 学期列表（按开始日期倒序），status 为空时不过滤
+func ListTerms(status string) ([]Term, error) {
+	query := `SELECT ` + termColumns + ` FROM terms`
+	var args []interface{}
+	if status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, status)
+	}
+	rows, err := utils.DB.Query(query+` ORDER BY start_date DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []Term{}
+	for rows.Next() {
+		t, err := scanTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *t)
+	}
+	return list, rows.Err()
+}
+
+// TermOverride 管理员为教师开通的窗口外补录授权
+type TermOverride struct {
+	Id        int64        `json:"id"`
+	TermId    int64        `json:"term_id"`
+	TeacherId uint64       `json:"teacher_id"`
+	Reason    string       `json:"reason"`
+	GrantedBy uint64       `json:"granted_by"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+const termOverrideColumns = `id, term_id, teacher_id, reason, granted_by, expires_at, revoked_at, created_at`
+
+// CreateTermOverride 开通补录授权，返回主键
+func CreateTermOverride(termId int64, teacherId uint64, reason string, grantedBy uint64, expiresAt time.Time) (int64, error) {
+	res, err := utils.DB.Exec(
+		`INSERT INTO term_overrides (term_id, teacher_id, reason, granted_by, expires_at) VALUES (?, ?, ?, ?, ?)`,
+		termId, teacherId, reason, grantedBy, expiresAt,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// RevokeTermOverride 提前收回补录授权，返回受影响行数
+func RevokeTermOverride(termId, id int64) (int64, error) {
+	res, err := utils.DB.Exec(
+		`UPDATE term_overrides SET revoked_at = NOW() WHERE id = ? AND term_id = ? AND revoked_at IS NULL`, id, termId,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// HasActiveTermOverride 教师在该学期是否有未过期、未收回的补录授权
+func HasActiveTermOverride(termId int64, teacherId uint64) (bool, error) {
+	var n int
+	err := utils.DB.QueryRow(
+		`SELECT COUNT(1) FROM term_overrides WHERE term_id = ? AND teacher_id = ? AND revoked_at IS NULL AND expires_at > NOW()`,
+		termId, teacherId,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// ListTermOverrides 学期的补录授权（含已过期、已收回）
+func ListTermOverrides(termId int64) ([]TermOverride, error) {
+	rows, err := utils.DB.Query(`SELECT `+termOverrideColumns+` FROM term_overrides WHERE term_id = ? ORDER BY id DESC`, termId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []TermOverride{}
+	for rows.Next() {
+		var o TermOverride
+		if err := rows.Scan(&o.Id, &o.TermId, &o.TeacherId, &o.Reason, &o.GrantedBy, &o.ExpiresAt, &o.RevokedAt, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
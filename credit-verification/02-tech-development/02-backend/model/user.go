@@ -11,13 +11,14 @@ import (
 
 // User 用户模型（对应users表）
 type User struct {
-	Id        uint64         `json:"id"`
-	Username  string         `json:"username"`
-	Password  string         `json:"-"`       // 序列化时隐藏密码
-	Address   sql.NullString `json:"address"` // 以太坊地址（允许为空）
-	Role      string         `json:"role"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
+	Id           uint64         `json:"id"`
+	Username     string         `json:"username"`
+	Password     string         `json:"-"`       // 序列化时隐藏密码
+	Address      sql.NullString `json:"address"` // 以太坊地址（允许为空）
+	Role         string         `json:"role"`
+	TokenVersion int            `json:"-"` // 签入 JWT，角色/密码变更或全部登出时递增
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
 }
 
 // CreateUser 创建用户（注册）
@@ -41,11 +42,11 @@ func (u *User) CreateUser() error {
 func GetUserByUsername(username string) (*User, error) {
 	var user User
 	err := utils.DB.QueryRow(
-		"SELECT id, username, password, address, role, created_at, updated_at FROM users WHERE username = ?",
+		"SELECT id, username, password, address, role, token_version, created_at, updated_at FROM users WHERE username = ?",
 		username,
 	).Scan(
 		&user.Id, &user.Username, &user.Password, &user.Address,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil // 无此用户
@@ -61,7 +62,7 @@ func GetUserByAddress(address string) (*User, error) {
 	}
 	var user User
 	err := utils.DB.QueryRow(
-		`SELECT id, username, password, address, role, created_at, updated_at 
+		`SELECT id, username, password, address, role, token_version, created_at, updated_at 
 		 FROM users 
 		 WHERE LOWER(TRIM(address)) = LOWER(TRIM(?)) 
 		 ORDER BY CASE LOWER(TRIM(role)) WHEN 'admin' THEN 1 WHEN 'teacher' THEN 2 ELSE 3 END 
@@ -69,7 +70,7 @@ func GetUserByAddress(address string) (*User, error) {
 		address,
 	).Scan(
 		&user.Id, &user.Username, &user.Password, &user.Address,
-		&user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Role, &user.TokenVersion, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -81,11 +82,11 @@ func GetUserByAddress(address string) (*User, error) {
 func GetUserById(userId uint64) (*User, error) {
 	var user User
 	err := utils.DB.QueryRow(
-		"SELECT id, username, address, role, created_at, updated_at FROM users WHERE id = ?",
+		"SELECT id, username, address, role, token_version, created_at, updated_at FROM users WHERE id = ?",
 		userId,
 	).Scan(
 		&user.Id, &user.Username, &user.Address, &user.Role,
-		&user.CreatedAt, &user.UpdatedAt,
+		&user.TokenVersion, &user.CreatedAt, &user.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -93,15 +94,43 @@ func GetUserById(userId uint64) (*User, error) {
 	return &user, err
 }
 
-// UpdateUser 更新用户信息
+// UpdateUser 更新用户信息（角色变化时递增 token_version，使旧 Token 失效；SET 按顺序求值，须在改 role 之前比较）
 func (u *User) UpdateUser() error {
 	_, err := utils.DB.Exec(
-		"UPDATE users SET address = ?, role = ?, updated_at = NOW() WHERE id = ?",
-		u.Address, u.Role, u.Id,
+		"UPDATE users SET token_version = token_version + (role <> ?), address = ?, role = ?, updated_at = NOW() WHERE id = ?",
+		u.Role, u.Address, u.Role, u.Id,
 	)
 	return err
 }
 
+// UpdatePassword 修改密码并递增 token_version
+func (u *User) UpdatePassword(password string) error {
+	hashPwd, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = utils.DB.Exec(
+		"UPDATE users SET password = ?, token_version = token_version + 1, updated_at = NOW() WHERE id = ?",
+		hashPwd, u.Id,
+	)
+	return err
+}
+
+// BumpTokenVersion 递增 token_version（登出所有会话）
+func BumpTokenVersion(userId uint64) error {
+	_, err := utils.DB.Exec("UPDATE users SET token_version = token_version + 1 WHERE id = ?", userId)
+	return err
+}
+
+// GetUserAuthState 鉴权中间件用：当前角色与 token_version；用户不存在时 exists 为 false
+func GetUserAuthState(userId uint64) (role string, version int, exists bool, err error) {
+	err = utils.DB.QueryRow("SELECT role, token_version FROM users WHERE id = ?", userId).Scan(&role, &version)
+	if err == sql.ErrNoRows {
+		return "", 0, false, nil
+	}
+	return role, version, err == nil, err
+}
+
 // UpdateAddress 仅更新当前用户的钱包地址（用于绑定）
 func (u *User) UpdateAddress(address string) error {
 	_, err := utils.DB.Exec(
@@ -111,18 +140,6 @@ func (u *User) UpdateAddress(address string) error {
 	return err
 }
 
-// FreeAddressFromWalletUser 解除被 wallet_ 占用的地址（删除该条以便绑到其他账号）
-func FreeAddressFromWalletUser(address string) (int64, error) {
-	res, err := utils.DB.Exec(
-		"DELETE FROM users WHERE LOWER(TRIM(address)) = LOWER(TRIM(?)) AND username LIKE 'wallet_%'",
-		address,
-	)
-	if err != nil {
-		return 0, err
-	}
-	return res.RowsAffected()
-}
-
 // CreateWalletUser 钱包用户首次登录时创建（仅地址+角色，无密码登录）
 func CreateWalletUser(address, role string) (*User, error) {
 	username := "wallet_" + address
@@ -0,0 +1,75 @@
+// model/user_role_log.go 本地角色设置审计：管理员直接为其他用户设置 users.role（不经链上），每次一条
+package model
+
+import (
+	"time"
+
+	"campus-credit-backend/utils"
+)
+
+// UserRoleLog 本地角色设置记录
+type UserRoleLog struct {
+	Id         int64     `json:"id"`
+	UserId     uint64    `json:"user_id"`
+	OldRole    string    `json:"old_role"`
+	NewRole    string    `json:"new_role"`
+	Reason     string    `json:"reason"`
+	OperatorId uint64    `json:"operator_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AssignUserRole 管理员为用户设置角色：在同一事务中改 users.role 并记审计，递增 token_version、吊销刷新 Token，使该用户所有会话失效；
+// 返回原角色；角色未变化时不写库
+func AssignUserRole(userId uint64, role, reason string, operatorId uint64) (string, error) {
+	tx, err := utils.DB.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var current string
+	if err := tx.QueryRow(`SELECT role FROM users WHERE id = ? FOR UPDATE`, userId).Scan(&current); err != nil {
+		return "", err
+	}
+	if current == role {
+		return current, nil
+	}
+	if _, err := tx.Exec(
+		`UPDATE users SET token_version = token_version + 1, role = ?, updated_at = NOW() WHERE id = ?`, role, userId,
+	); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = ? AND revoked_at IS NULL`, userId,
+	); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO user_role_logs (user_id, old_role, new_role, reason, operator_id) VALUES (?, ?, ?, ?, ?)`,
+		userId, current, role, reason, operatorId,
+	); err != nil {
+		return "", err
+	}
+	return current, tx.Commit()
+}
+
+// ListUserRoleLogs 用户的角色设置记录，最新在前
+func ListUserRoleLogs(userId uint64, limit int) ([]UserRoleLog, error) {
+	rows, err := utils.DB.Query(
+		`SELECT id, user_id, old_role, new_role, reason, operator_id, created_at FROM user_role_logs
+		 WHERE user_id = ? ORDER BY id DESC LIMIT ?`, userId, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []UserRoleLog{}
+	for rows.Next() {
+		var l UserRoleLog
+		if err := rows.Scan(&l.Id, &l.UserId, &l.OldRole, &l.NewRole, &l.Reason, &l.OperatorId, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
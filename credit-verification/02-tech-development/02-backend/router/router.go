@@ -4,18 +4,24 @@ package router
 import (
 	"campus-credit-backend/controller"
 	"campus-credit-backend/middleware"
+	"campus-credit-backend/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 // InitRouter 初始化路由
 func InitRouter(r *gin.Engine) {
+	// JWKS：供其他校园服务验证本服务签发的 Token
+	r.GET("/.well-known/jwks.json", controller.JWKS)
+
 	// 公开接口（无需登录）
 	public := r.Group("/api")
 	{
 		// 用户注册/登录
 		public.POST("/user/register", controller.UserRegister)
 		public.POST("/user/login", controller.UserLogin)
+		public.GET("/user/nonce", controller.UserNonce)
+		public.POST("/user/refresh", controller.UserRefresh)
 	}
 
 	// 需登录的接口（全局鉴权）
@@ -27,34 +33,114 @@ func InitRouter(r *gin.Engine) {
 		{
 			user.GET("/info", controller.UserInfo)
 			user.POST("/update", controller.UserUpdate)
+			user.POST("/logout", controller.UserLogout)
+			user.POST("/logout-all", controller.UserLogoutAll)
+			user.POST("/password", controller.UserChangePassword)
+			user.GET("/bind-nonce", controller.BindNonce)
 			user.POST("/bind-address", controller.BindAddress)
+			user.POST("/unbind-address", controller.UnbindAddress)
+			user.GET("/bind-logs", controller.BindLogs)
 		}
 
-		// 角色管理（仅admin可访问）
+		// 链上角色分配/查询
 		role := auth.Group("/role")
-		role.Use(middleware.RoleMiddleware("admin"))
 		{
-			role.POST("/assign", controller.AssignRole)
-			role.GET("/get", controller.GetRole)
+			role.POST("/assign", middleware.RequirePermission(model.PermRoleAssign), controller.AssignRole)
+			role.GET("/get", middleware.RequirePermission(model.PermRoleRead), controller.GetRole)
+			role.POST("/revoke", middleware.RequirePermission(model.PermRoleAssign), controller.RevokeRole)
+			role.GET("/changes", middleware.RequirePermission(model.PermRoleRead), controller.RoleChangeList)
+			role.GET("/change/:id", middleware.RequirePermission(model.PermRoleRead), controller.RoleChangeStatus)
+			role.GET("/cache/stats", middleware.RequirePermission(model.PermRoleRead), controller.RoleCacheStats)
+			role.GET("/drift", middleware.RequirePermission(model.PermRoleRead), controller.RoleDrift)
+			role.POST("/drift/:userId/repair", middleware.RequirePermission(model.PermRoleAssign), controller.RoleRepair)
+		}
+
+		// 角色定义与权限管理
+		roleDefs := auth.Group("")
+		roleDefs.Use(middleware.RequirePermission(model.PermRoleManage))
+		{
+			roleDefs.GET("/permissions", controller.PermissionList)
+			roleDefs.GET("/roles", controller.RoleDefList)
+			roleDefs.POST("/roles", controller.RoleDefCreate)
+			roleDefs.PUT("/roles/:name", controller.RoleDefUpdate)
+			roleDefs.DELETE("/roles/:name", controller.RoleDefDelete)
+			roleDefs.POST("/users/:id/role", controller.UserRoleSet)
+			roleDefs.GET("/users/:id/role-logs", controller.UserRoleLogs)
+		}
+
+		// 链上交易：提交钱包签名交易（登录即可，提交时按方法校验权限）；按哈希查状态、队列与台账列表需 tx:read（含方法参数与发送方）
+		auth.POST("/tx/submit", controller.TxSubmit)
+		txAdmin := auth.Group("/tx")
+		txAdmin.Use(middleware.RequirePermission(model.PermTxRead))
+		{
+			txAdmin.GET("/queue", controller.TxQueueList)
+			txAdmin.GET("/list", controller.TxList)
+			txAdmin.GET("/:hash", controller.TxDetail)
+		}
+
+		// 课程目录：登录即可查询，增删改需 course:manage
+		auth.GET("/courses", controller.CourseList)
+		courseAdmin := auth.Group("/courses")
+		courseAdmin.Use(middleware.RequirePermission(model.PermCourseManage))
+		{
+			courseAdmin.POST("", controller.CourseCreate)
+			courseAdmin.PUT("/:id", controller.CourseUpdate)
+			courseAdmin.DELETE("/:id", controller.CourseDelete)
+		}
+
+		// 学期：登录即可查询，维护、开通补录与关闭需 term:manage
+		auth.GET("/terms", controller.TermList)
+		termAdmin := auth.Group("/terms")
+		termAdmin.Use(middleware.RequirePermission(model.PermTermManage))
+		{
+			termAdmin.POST("", controller.TermCreate)
+			termAdmin.PUT("/:id", controller.TermUpdate)
+			termAdmin.POST("/:id/close", controller.TermClose)
+			termAdmin.GET("/:id/overrides", controller.TermOverrideList)
+			termAdmin.POST("/:id/overrides", controller.TermOverrideCreate)
+			termAdmin.DELETE("/:id/overrides/:overrideId", controller.TermOverrideRevoke)
+		}
+
+		// 选课名单与任课安排：导入、查询、删除均需 enrollment:manage
+		offering := auth.Group("")
+		offering.Use(middleware.RequirePermission(model.PermEnrollManage))
+		{
+			offering.POST("/enrollments/import", controller.EnrollmentImport)
+			offering.GET("/enrollments", controller.EnrollmentList)
+			offering.DELETE("/enrollments/:id", controller.EnrollmentDelete)
+			offering.POST("/teaching-assignments/import", controller.TeachingImport)
+			offering.GET("/teaching-assignments", controller.TeachingList)
+			offering.DELETE("/teaching-assignments/:id", controller.TeachingDelete)
 		}
 
-		// 学分：录入仅教师，审核/待审核仅管理员，列表按角色
+		// 学分：录入需 credit:record，审核/驳回需 credit:approve / credit:reject，列表按权限
 		credit := auth.Group("/credit")
 		{
 			credit.GET("/list", controller.CreditList)
-			credit.POST("/sync", controller.CreditSync)
+			credit.POST("/sync", middleware.RequirePermission(model.PermCreditSync), controller.CreditSync)
+			credit.GET("/job/:id", controller.CreditJobStatus)
+			credit.GET("/job/:id/stream", controller.CreditJobStream)
+			credit.GET("/timeline/:id", controller.CreditTimeline)
+			credit.GET("/corrections", controller.CreditCorrectionList)
 		}
 		creditTeacher := auth.Group("/credit")
-		creditTeacher.Use(middleware.RoleMiddleware("teacher"))
+		creditTeacher.Use(middleware.RequirePermission(model.PermCreditRecord))
 		{
 			creditTeacher.POST("/record", controller.CreditRecord)
+			creditTeacher.POST("/record/prepare", controller.CreditRecordPrepare)
+			creditTeacher.POST("/record/submit", controller.CreditRecordSubmit)
+			creditTeacher.POST("/correction", controller.CreditCorrectionCreate)
 		}
 		creditAdmin := auth.Group("/credit")
-		creditAdmin.Use(middleware.RoleMiddleware("admin"))
 		{
-			creditAdmin.POST("/approve", controller.CreditApprove)
-			creditAdmin.POST("/reject", controller.CreditReject)
-			creditAdmin.GET("/pending", controller.CreditPending)
+			creditAdmin.POST("/approve", middleware.RequirePermission(model.PermCreditApprove), controller.CreditApprove)
+			creditAdmin.POST("/reject", middleware.RequirePermission(model.PermCreditReject), controller.CreditReject)
+			creditAdmin.POST("/approve/batch", middleware.RequirePermission(model.PermCreditApprove), controller.CreditApproveBatch)
+			creditAdmin.POST("/reject/batch", middleware.RequirePermission(model.PermCreditReject), controller.CreditRejectBatch)
+			creditAdmin.POST("/revoke", middleware.RequirePermission(model.PermCreditRevoke), controller.CreditRevoke)
+			creditAdmin.POST("/correction/:id/approve", middleware.RequirePermission(model.PermCreditApprove), controller.CreditCorrectionApprove)
+			creditAdmin.POST("/correction/:id/reject", middleware.RequirePermission(model.PermCreditApprove), controller.CreditCorrectionReject)
+			creditAdmin.GET("/pending", middleware.RequirePermission(model.PermCreditApprove, model.PermCreditReadAll), controller.CreditPending)
 		}
 	}
 }
@@ -0,0 +1,131 @@
+// service/credit_audits.go 学分审核回执任务：等待审核交易打包，按回执中逐条触发的事件写入学分状态
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StartCreditAudits 启动审核交易轮询（重启后继续处理等待打包的审核）
+func StartCreditAudits(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processCreditAudits(ctx)
+			}
+		}
+	}()
+}
+
+func processCreditAudits(ctx context.Context) {
+	list, err := model.GetSubmittedCreditAudits()
+	if err != nil {
+		log.Printf("[CreditAudits] 查询审核登记失败: %v", err)
+		return
+	}
+	done := make(map[string]bool)
+	for _, a := range list {
+		if done[a.TxHash] {
+			continue
+		}
+		done[a.TxHash] = true
+		if err := processCreditAuditTx(ctx, a.TxHash); err != nil {
+			log.Printf("[CreditAudits] 处理交易 %s 失败: %v", a.TxHash, err)
+		}
+	}
+}
+
+// processCreditAuditTx 查回执：未打包则跳过；失效或执行失败标记失败；成功则按回执事件逐条写入
+func processCreditAuditTx(ctx context.Context, txHash string) error {
+	receipt, hash, dropped := findReceipt(ctx, txHash)
+	if receipt == nil {
+		if dropped {
+			return finishCreditAuditTx(txHash, model.CreditAuditFailed, "交易未被打包（已失效）")
+		}
+		return nil
+	}
+	if hash != txHash {
+		if err := model.UpdateCreditAuditTx(txHash, hash); err != nil {
+			return err
+		}
+	}
+	return ApplyCreditAuditReceipt(receipt)
+}
+
+// ApplyCreditAuditReceipt 按已打包的回执处理该交易登记的全部学分：执行失败则全部标记失败；
+// 合约逐条触发事件的学分写入状态，未触发的（合约跳过）标记 skipped。回执解析失败时返回错误，登记保持 submitted 待重试
+func ApplyCreditAuditReceipt(receipt *types.Receipt) error {
+	txHash := receipt.TxHash.Hex()
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return finishCreditAuditTx(txHash, model.CreditAuditFailed, "交易执行失败（reverted）")
+	}
+	audits, err := model.GetCreditAuditsByTx(txHash)
+	if err != nil {
+		return err
+	}
+	touched := make(map[string]map[uint64]bool)
+	for i := range audits {
+		a := &audits[i]
+		if a.Status != model.CreditAuditSubmitted {
+			continue
+		}
+		ids, ok := touched[a.Action]
+		if !ok {
+			if ids, err = parseCreditAuditEvents(receipt, a.Action); err != nil {
+				return err
+			}
+			touched[a.Action] = ids
+		}
+		if !ids[uint64(a.ContractCreditId)] {
+			err = model.FinishCreditAudit(a.Id, model.CreditAuditSkipped, "链上该学分状态已变化，合约未执行该操作（本地状态由事件索引器同步）")
+		} else {
+			err = model.ApplyCreditAudit(a, txHash)
+		}
+		if err != nil {
+			return fmt.Errorf("学分 %d: %v", a.CreditId, err)
+		}
+	}
+	return nil
+}
+
+// parseCreditAuditEvents 回执中该审核动作逐条触发的链上学分ID
+func parseCreditAuditEvents(receipt *types.Receipt, action string) (map[uint64]bool, error) {
+	switch action {
+	case model.CreditEventApproved:
+		return utils.ParseCreditAuditIds(receipt, true)
+	case model.CreditEventRejected:
+		return utils.ParseCreditAuditIds(receipt, false)
+	case model.CreditEventRevoked:
+		return utils.ParseCreditRevokedIds(receipt)
+	}
+	return nil, fmt.Errorf("不支持的审核动作: %s", action)
+}
+
+// finishCreditAuditTx 把同一交易中仍在等待的登记全部结束为 status
+func finishCreditAuditTx(txHash, status, reason string) error {
+	audits, err := model.GetCreditAuditsByTx(txHash)
+	if err != nil {
+		return err
+	}
+	for _, a := range audits {
+		if a.Status != model.CreditAuditSubmitted {
+			continue
+		}
+		if err := model.FinishCreditAudit(a.Id, status, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
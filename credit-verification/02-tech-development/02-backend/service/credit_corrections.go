@@ -0,0 +1,67 @@
+// service/credit_corrections.go 学分更正后台任务：等待 supersedeCredit 交易打包，从回执事件取新记录ID并关联新旧记录
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StartCreditCorrections 启动更正任务轮询（重启后继续处理等待打包的更正）
+func StartCreditCorrections(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processCreditCorrections(ctx)
+			}
+		}
+	}()
+}
+
+func processCreditCorrections(ctx context.Context) {
+	list, err := model.GetSubmittedCreditCorrections()
+	if err != nil {
+		log.Printf("[CreditCorrections] 查询更正失败: %v", err)
+		return
+	}
+	for i := range list {
+		if err := processCreditCorrection(ctx, &list[i]); err != nil {
+			log.Printf("[CreditCorrections] 处理更正 %d 失败: %v", list[i].Id, err)
+		}
+	}
+}
+
+// processCreditCorrection 查回执：未打包则跳过；失效或执行失败标记失败；成功则按 CreditCorrected 事件关联新记录
+func processCreditCorrection(ctx context.Context, corr *model.CreditCorrection) error {
+	receipt, hash, dropped := findReceipt(ctx, corr.TxHash.String)
+	if receipt == nil {
+		if dropped {
+			return model.FailCreditCorrection(corr.Id, "交易未被打包（已失效）")
+		}
+		return nil
+	}
+	if hash != corr.TxHash.String {
+		if err := model.UpdateCreditCorrectionTx(corr.Id, hash); err != nil {
+			return err
+		}
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return model.FailCreditCorrection(corr.Id, "交易执行失败（reverted）")
+	}
+	_, newContractId, err := utils.ParseCreditCorrected(receipt)
+	if err != nil {
+		return model.FailCreditCorrection(corr.Id, err.Error())
+	}
+	_, err = model.ApplyCreditCorrection(corr, int64(newContractId), hash)
+	return err
+}
@@ -0,0 +1,92 @@
+// service/credit_jobs.go 异步录入学分后台任务：等待 recordCredit 交易打包，从回执事件关联链上学分ID
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StartCreditJobs 启动录入任务轮询（重启后继续处理未完成的任务）
+func StartCreditJobs(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processCreditJobs(ctx)
+			}
+		}
+	}()
+}
+
+func processCreditJobs(ctx context.Context) {
+	jobs, err := model.GetSubmittedCreditJobs()
+	if err != nil {
+		log.Printf("[CreditJobs] 查询任务失败: %v", err)
+		return
+	}
+	for i := range jobs {
+		if err := processCreditJob(ctx, &jobs[i]); err != nil {
+			log.Printf("[CreditJobs] 处理任务 %d 失败: %v", jobs[i].Id, err)
+		}
+	}
+}
+
+// processCreditJob 查回执：未打包则跳过；打包失败标记任务失败；成功则解析 creditId 并关联
+func processCreditJob(ctx context.Context, job *model.CreditJob) error {
+	receipt, hash, dropped := findReceipt(ctx, job.TxHash)
+	if receipt == nil {
+		if dropped {
+			return model.FailCreditJob(job, "交易未被打包（已失效）")
+		}
+		return nil
+	}
+	if hash != job.TxHash {
+		if err := model.UpdateCreditJobTx(job, hash); err != nil {
+			return err
+		}
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return model.FailCreditJob(job, "交易执行失败（reverted）")
+	}
+	contractCreditId, err := utils.ParseCreditRecordedId(receipt)
+	if err != nil {
+		return model.FailCreditJob(job, err.Error())
+	}
+	creditId, err := model.LinkCreditContractId(job.CreditId, int64(contractCreditId), hash)
+	if err != nil {
+		return err
+	}
+	return model.FinishCreditJob(job.Id, creditId, int64(contractCreditId))
+}
+
+// findReceipt 沿台账 replaced_by 链查找已打包的那笔交易（原交易或提价替换后的交易）
+// 返回回执与其交易哈希；整条链都未打包且最新一笔已在台账中判定失效时 dropped 为 true
+func findReceipt(ctx context.Context, txHash string) (*types.Receipt, string, bool) {
+	hash := txHash
+	for i := 0; i < 16 && hash != ""; i++ {
+		receipt, err := utils.EthClient.TransactionReceipt(ctx, common.HexToHash(hash))
+		if err == nil && receipt != nil {
+			return receipt, hash, false
+		}
+		ledger, err := model.GetChainTxByHash(hash)
+		if err != nil || ledger == nil {
+			return nil, hash, false
+		}
+		if !ledger.ReplacedBy.Valid {
+			return nil, hash, ledger.Status == model.ChainTxFailed
+		}
+		hash = ledger.ReplacedBy.String
+	}
+	return nil, hash, false
+}
@@ -0,0 +1,337 @@
+// service/indexer.go 合约事件索引器：轮询 CreditRecorded / CreditApproved / CreditRejected / CreditRevoked / CreditCorrected / RoleAssigned / RoleRevoked 日志并幂等写入 MySQL
+// 角色事件按地址当前的链上权限位同步本地角色，并使该地址的链上角色缓存失效
+// 直接发往合约的交易（不经过后端接口）也能被 CreditList 看到
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// IndexerBackend 索引器所需的链访问能力；*ethclient.Client 与 go-ethereum simulated 后端的 Client 均满足
+type IndexerBackend interface {
+	bind.ContractCaller
+	ethereum.LogFilterer
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// IndexerOptions 索引器参数
+type IndexerOptions struct {
+	Name         string        // 游标名，对应 chain_cursors.name
+	StartBlock   uint64        // 无游标时的起始区块
+	ReorgDepth   uint64        // 确认深度：只处理 head-ReorgDepth 之前的区块；检测到分叉时回退同样深度重扫
+	BatchSize    uint64        // 单次 FilterLogs 的区块跨度
+	PollInterval time.Duration // 轮询间隔
+	Store        IndexerStore  // 落库实现，为空时使用 model 包（MySQL）
+}
+
+// IndexerStore 索引器的全部落库操作；各方法须幂等，重放同一日志不产生重复记录
+type IndexerStore interface {
+	GetChainCursor(name string) (*model.ChainCursor, error)
+	SaveChainCursor(name string, blockNumber uint64, blockHash string) error
+	UpsertCreditFromChain(contractCreditId int64, studentAddress, teacherAddress, courseName string, score float64, txHash string, blockNumber uint64) error
+	ApproveCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error
+	RejectCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error
+	RevokeCreditFromChain(contractCreditId int64, admin, txHash string, blockNumber uint64) error
+	LinkCreditCorrectionFromChain(originalContractId, newContractId int64, admin, txHash string, blockNumber uint64) error
+	// RewindCreditsFromChain 分叉回退：撤销来自 fromBlock 及之后区块的建档关联与状态流转，随后从 fromBlock 按新链重扫
+	RewindCreditsFromChain(fromBlock uint64) error
+	SyncUserRoleFromChain(address string, isTeacher, isAdmin bool) error
+}
+
+// modelStore 基于 model 包的 IndexerStore 实现
+type modelStore struct{}
+
+func (modelStore) GetChainCursor(name string) (*model.ChainCursor, error) {
+	return model.GetChainCursor(name)
+}
+
+func (modelStore) SaveChainCursor(name string, blockNumber uint64, blockHash string) error {
+	return model.SaveChainCursor(name, blockNumber, blockHash)
+}
+
+func (modelStore) UpsertCreditFromChain(contractCreditId int64, studentAddress, teacherAddress, courseName string, score float64, txHash string, blockNumber uint64) error {
+	return model.UpsertCreditFromChain(contractCreditId, studentAddress, teacherAddress, courseName, score, txHash, blockNumber)
+}
+
+func (modelStore) ApproveCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	return model.ApproveCreditFromChain(contractCreditId, auditAdmin, txHash, blockNumber)
+}
+
+func (modelStore) RejectCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	return model.RejectCreditFromChain(contractCreditId, auditAdmin, txHash, blockNumber)
+}
+
+func (modelStore) RevokeCreditFromChain(contractCreditId int64, admin, txHash string, blockNumber uint64) error {
+	return model.RevokeCreditFromChain(contractCreditId, admin, txHash, blockNumber)
+}
+
+func (modelStore) LinkCreditCorrectionFromChain(originalContractId, newContractId int64, admin, txHash string, blockNumber uint64) error {
+	return model.LinkCreditCorrectionFromChain(originalContractId, newContractId, admin, txHash, blockNumber)
+}
+
+func (modelStore) RewindCreditsFromChain(fromBlock uint64) error {
+	return model.RewindCreditsFromChain(fromBlock)
+}
+
+func (modelStore) SyncUserRoleFromChain(address string, isTeacher, isAdmin bool) error {
+	return model.SyncUserRoleFromChain(address, isTeacher, isAdmin)
+}
+
+// Indexer 合约事件索引器
+type Indexer struct {
+	backend  IndexerBackend
+	address  common.Address
+	abi      abi.ABI
+	contract *bind.BoundContract
+	store    IndexerStore
+	opts     IndexerOptions
+}
+
+// NewIndexer 创建索引器
+func NewIndexer(backend IndexerBackend, address common.Address, contractABI abi.ABI, opts IndexerOptions) *Indexer {
+	if opts.Name == "" {
+		opts.Name = "credit_contract"
+	}
+	if opts.BatchSize == 0 {
+		opts.BatchSize = 1000
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 5 * time.Second
+	}
+	if opts.Store == nil {
+		opts.Store = modelStore{}
+	}
+	return &Indexer{
+		backend:  backend,
+		address:  address,
+		abi:      contractABI,
+		contract: bind.NewBoundContract(address, contractABI, backend, nil, backend),
+		store:    opts.Store,
+		opts:     opts,
+	}
+}
+
+// StartIndexer 按配置启动索引器（使用全局 EthClient 与 CreditContractABI），未启用时直接返回
+func StartIndexer(ctx context.Context) {
+	cfg := utils.GlobalConfig.Indexer
+	if !cfg.Enabled {
+		return
+	}
+	ix := NewIndexer(utils.EthClient, common.HexToAddress(utils.GlobalConfig.Ethereum.CreditContractAddr), utils.CreditContractABI, IndexerOptions{
+		StartBlock:   cfg.StartBlock,
+		ReorgDepth:   cfg.ReorgDepth,
+		BatchSize:    cfg.BatchSize,
+		PollInterval: time.Duration(cfg.PollSeconds) * time.Second,
+	})
+	go ix.Run(ctx)
+	log.Println("合约事件索引器已启动")
+}
+
+// Run 循环轮询直到 ctx 取消
+func (ix *Indexer) Run(ctx context.Context) {
+	ticker := time.NewTicker(ix.opts.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := ix.Poll(ctx); err != nil {
+			log.Printf("[Indexer] 轮询失败: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll 执行一轮索引：校验游标区块哈希（分叉则回退），再按批次拉取日志直到安全高度
+func (ix *Indexer) Poll(ctx context.Context) error {
+	head, err := ix.backend.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("获取最新区块失败: %v", err)
+	}
+	headNum := head.Number.Uint64()
+	if headNum < ix.opts.ReorgDepth {
+		return nil
+	}
+	safe := headNum - ix.opts.ReorgDepth
+
+	from, err := ix.nextBlock(ctx)
+	if err != nil {
+		return err
+	}
+	for from <= safe {
+		to := from + ix.opts.BatchSize - 1
+		if to > safe {
+			to = safe
+		}
+		if err := ix.indexRange(ctx, from, to); err != nil {
+			return err
+		}
+		header, err := ix.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(to))
+		if err != nil {
+			return fmt.Errorf("获取区块 %d 失败: %v", to, err)
+		}
+		if err := ix.store.SaveChainCursor(ix.opts.Name, to, header.Hash().Hex()); err != nil {
+			return fmt.Errorf("保存游标失败: %v", err)
+		}
+		from = to + 1
+	}
+	return nil
+}
+
+// nextBlock 计算本轮起始区块；游标所在区块哈希与链上不一致说明发生分叉，回退 ReorgDepth 个区块，
+// 先撤销来自被丢弃区块的落库结果再重扫
+func (ix *Indexer) nextBlock(ctx context.Context) (uint64, error) {
+	cur, err := ix.store.GetChainCursor(ix.opts.Name)
+	if err != nil {
+		return 0, fmt.Errorf("读取游标失败: %v", err)
+	}
+	if cur == nil {
+		return ix.opts.StartBlock, nil
+	}
+	header, err := ix.backend.HeaderByNumber(ctx, new(big.Int).SetUint64(cur.BlockNumber))
+	if err == nil && strings.EqualFold(header.Hash().Hex(), cur.BlockHash) {
+		return cur.BlockNumber + 1, nil
+	}
+	rewind := ix.opts.StartBlock
+	if cur.BlockNumber > ix.opts.ReorgDepth && cur.BlockNumber-ix.opts.ReorgDepth > rewind {
+		rewind = cur.BlockNumber - ix.opts.ReorgDepth
+	}
+	log.Printf("[Indexer] 检测到区块 %d 分叉，回退到 %d 重新索引", cur.BlockNumber, rewind)
+	if err := ix.store.RewindCreditsFromChain(rewind); err != nil {
+		return 0, fmt.Errorf("分叉回退失败: %v", err)
+	}
+	return rewind, nil
+}
+
+// indexRange 拉取 [from, to] 区间内的学分与角色事件并逐条处理
+func (ix *Indexer) indexRange(ctx context.Context, from, to uint64) error {
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(from),
+		ToBlock:   new(big.Int).SetUint64(to),
+		Addresses: []common.Address{ix.address},
+		Topics: [][]common.Hash{{
+			ix.abi.Events["CreditRecorded"].ID,
+			ix.abi.Events["CreditApproved"].ID,
+			ix.abi.Events["CreditRejected"].ID,
+			ix.abi.Events["CreditRevoked"].ID,
+			ix.abi.Events["CreditCorrected"].ID,
+			ix.abi.Events["RoleAssigned"].ID,
+			ix.abi.Events["RoleRevoked"].ID,
+		}},
+	}
+	logs, err := ix.backend.FilterLogs(ctx, query)
+	if err != nil {
+		return fmt.Errorf("拉取区块 %d-%d 日志失败: %v", from, to, err)
+	}
+	for _, lg := range logs {
+		if lg.Removed || len(lg.Topics) == 0 {
+			continue
+		}
+		if err := ix.handleLog(ctx, lg); err != nil {
+			return fmt.Errorf("处理日志 %s#%d 失败: %v", lg.TxHash.Hex(), lg.Index, err)
+		}
+	}
+	return nil
+}
+
+func (ix *Indexer) handleLog(ctx context.Context, lg types.Log) error {
+	switch lg.Topics[0] {
+	case ix.abi.Events["CreditRecorded"].ID:
+		return ix.handleCreditRecorded(ctx, lg)
+	case ix.abi.Events["CreditApproved"].ID:
+		if len(lg.Topics) < 3 {
+			return fmt.Errorf("CreditApproved 日志 topic 数量异常")
+		}
+		creditId := new(big.Int).SetBytes(lg.Topics[1].Bytes()).Int64()
+		admin := strings.ToLower(common.BytesToAddress(lg.Topics[2].Bytes()).Hex())
+		return ix.store.ApproveCreditFromChain(creditId, admin, lg.TxHash.Hex(), lg.BlockNumber)
+	case ix.abi.Events["CreditRejected"].ID:
+		// 链上只有原因哈希，原因原文由 /credit/reject 或 /tx/submit 写入
+		if len(lg.Topics) < 3 {
+			return fmt.Errorf("CreditRejected 日志 topic 数量异常")
+		}
+		creditId := new(big.Int).SetBytes(lg.Topics[1].Bytes()).Int64()
+		admin := strings.ToLower(common.BytesToAddress(lg.Topics[2].Bytes()).Hex())
+		return ix.store.RejectCreditFromChain(creditId, admin, lg.TxHash.Hex(), lg.BlockNumber)
+	case ix.abi.Events["CreditRevoked"].ID:
+		// 撤销理由原文由 /credit/revoke 或 /tx/submit 写入
+		if len(lg.Topics) < 3 {
+			return fmt.Errorf("CreditRevoked 日志 topic 数量异常")
+		}
+		creditId := new(big.Int).SetBytes(lg.Topics[1].Bytes()).Int64()
+		admin := strings.ToLower(common.BytesToAddress(lg.Topics[2].Bytes()).Hex())
+		return ix.store.RevokeCreditFromChain(creditId, admin, lg.TxHash.Hex(), lg.BlockNumber)
+	case ix.abi.Events["CreditCorrected"].ID:
+		// 新记录已由同一交易中先触发的 CreditRecorded 建档
+		if len(lg.Topics) < 4 {
+			return fmt.Errorf("CreditCorrected 日志 topic 数量异常")
+		}
+		originalId := new(big.Int).SetBytes(lg.Topics[1].Bytes()).Int64()
+		newId := new(big.Int).SetBytes(lg.Topics[2].Bytes()).Int64()
+		admin := strings.ToLower(common.BytesToAddress(lg.Topics[3].Bytes()).Hex())
+		return ix.store.LinkCreditCorrectionFromChain(originalId, newId, admin, lg.TxHash.Hex(), lg.BlockNumber)
+	case ix.abi.Events["RoleAssigned"].ID, ix.abi.Events["RoleRevoked"].ID:
+		if len(lg.Topics) < 2 {
+			return fmt.Errorf("角色事件日志 topic 数量异常")
+		}
+		return ix.syncRole(ctx, common.BytesToAddress(lg.Topics[1].Bytes()))
+	}
+	return nil
+}
+
+// syncRole 角色事件不直接按事件内容写库，而是读取地址在最新区块的 isTeacher / isAdmin 后同步，
+// 这样重放（重扫或分叉回退）旧的 RoleAssigned 不会恢复之后已被撤销的角色
+func (ix *Indexer) syncRole(ctx context.Context, user common.Address) error {
+	addr := strings.ToLower(user.Hex())
+	utils.DefaultRoleCache.Invalidate(addr)
+	opts := &bind.CallOpts{Context: ctx}
+	var flags [2]bool
+	for i, method := range []string{"isTeacher", "isAdmin"} {
+		var out []interface{}
+		if err := ix.contract.Call(opts, &out, method, user); err != nil {
+			return fmt.Errorf("调用%s失败: %v", method, err)
+		}
+		if len(out) == 0 {
+			return fmt.Errorf("%s返回为空", method)
+		}
+		flags[i] = *abi.ConvertType(out[0], new(bool)).(*bool)
+	}
+	return ix.store.SyncUserRoleFromChain(addr, flags[0], flags[1])
+}
+
+// handleCreditRecorded studentId 为 indexed string，日志中仅有哈希，需按事件所在区块回查 getCreditById 取原文
+func (ix *Indexer) handleCreditRecorded(ctx context.Context, lg types.Log) error {
+	if len(lg.Topics) < 4 {
+		return fmt.Errorf("CreditRecorded 日志 topic 数量异常")
+	}
+	creditId := new(big.Int).SetBytes(lg.Topics[1].Bytes())
+	teacher := strings.ToLower(common.BytesToAddress(lg.Topics[3].Bytes()).Hex())
+
+	var out []interface{}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(lg.BlockNumber)}
+	if err := ix.contract.Call(opts, &out, "getCreditById", creditId); err != nil {
+		return fmt.Errorf("调用getCreditById失败: %v", err)
+	}
+	if len(out) == 0 || out[0] == nil {
+		return fmt.Errorf("getCreditById返回为空")
+	}
+	credit := *abi.ConvertType(out[0], new(utils.ChainCredit)).(*utils.ChainCredit)
+	return ix.store.UpsertCreditFromChain(
+		creditId.Int64(), credit.StudentId, teacher, credit.CourseName, float64(credit.Score), lg.TxHash.Hex(), lg.BlockNumber,
+	)
+}
@@ -0,0 +1,386 @@
+// service/indexer_test.go 索引器测试：go-ethereum simulated 后端部署合约，内存落库，覆盖录入、审核与分叉回退
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+const (
+	testABIFile      = "../contract/abi/credit_contract.json"
+	testArtifactFile = "../../03-frontend/src/assets/abi/credit_contract.json" // Hardhat 编译产物，含部署字节码
+)
+
+// memCredit 内存中的一条学分
+type memCredit struct {
+	ContractId int64 // 未关联链上ID时为 -1
+	Student    string
+	Teacher    string
+	Course     string
+	Score      float64
+	Status     string
+	Approved   string // 审核人地址，未审核为空
+	TxHash     string
+	ChainBlock uint64 // 建档或关联链上ID所在区块，0 表示未由索引器确认
+	events     []memEvent
+}
+
+// memEvent 内存中的一条状态流转
+type memEvent struct {
+	Event  string
+	From   string
+	TxHash string
+	Block  uint64
+}
+
+// memStore 内存版 IndexerStore，语义与 model 包（MySQL）一致：已建档的链上ID不覆盖，状态按 from 状态流转，分叉时按区块回退
+type memStore struct {
+	cursor *model.ChainCursor
+	rows   []*memCredit
+	roles  map[string][2]bool // 地址 -> 同步时的 isTeacher / isAdmin
+}
+
+func newMemStore() *memStore {
+	return &memStore{roles: make(map[string][2]bool)}
+}
+
+// credit 按链上ID查找，未关联时返回 nil
+func (s *memStore) credit(contractCreditId int64) *memCredit {
+	for _, c := range s.rows {
+		if c.ContractId == contractCreditId {
+			return c
+		}
+	}
+	return nil
+}
+
+func (s *memStore) GetChainCursor(name string) (*model.ChainCursor, error) {
+	return s.cursor, nil
+}
+
+func (s *memStore) SaveChainCursor(name string, blockNumber uint64, blockHash string) error {
+	s.cursor = &model.ChainCursor{Name: name, BlockNumber: blockNumber, BlockHash: blockHash}
+	return nil
+}
+
+func (s *memStore) UpsertCreditFromChain(contractCreditId int64, studentAddress, teacherAddress, courseName string, score float64, txHash string, blockNumber uint64) error {
+	if c := s.credit(contractCreditId); c != nil {
+		if c.ChainBlock == 0 {
+			c.ChainBlock = blockNumber
+		}
+		return nil
+	}
+	for _, c := range s.rows {
+		if c.TxHash == txHash && c.ContractId < 0 {
+			c.ContractId, c.ChainBlock = contractCreditId, blockNumber
+			return nil
+		}
+	}
+	s.rows = append(s.rows, &memCredit{
+		ContractId: contractCreditId, Student: studentAddress, Teacher: teacherAddress, Course: courseName, Score: score,
+		Status: "pending", TxHash: txHash, ChainBlock: blockNumber,
+		events: []memEvent{{Event: model.CreditEventRecorded, TxHash: txHash}},
+	})
+	return nil
+}
+
+// transit 与 model.transitCredit 一致：仅当前状态为 from 时流转，随后补记事件所在区块
+func (s *memStore) transit(contractCreditId int64, from, to, event, txHash string, blockNumber uint64) *memCredit {
+	c := s.credit(contractCreditId)
+	if c == nil {
+		return nil
+	}
+	if c.Status == from {
+		c.Status = to
+		c.events = append(c.events, memEvent{Event: event, From: from, TxHash: txHash})
+	}
+	for i := range c.events {
+		if e := &c.events[i]; e.Event == event && e.TxHash == txHash && e.Block == 0 {
+			e.Block = blockNumber
+		}
+	}
+	return c
+}
+
+func (s *memStore) ApproveCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	if c := s.transit(contractCreditId, "pending", "approved", model.CreditEventApproved, txHash, blockNumber); c != nil && c.Status == "approved" && c.Approved == "" {
+		c.Approved = auditAdmin
+	}
+	return nil
+}
+
+func (s *memStore) RejectCreditFromChain(contractCreditId int64, auditAdmin, txHash string, blockNumber uint64) error {
+	s.transit(contractCreditId, "pending", "rejected", model.CreditEventRejected, txHash, blockNumber)
+	return nil
+}
+
+func (s *memStore) RevokeCreditFromChain(contractCreditId int64, admin, txHash string, blockNumber uint64) error {
+	s.transit(contractCreditId, "approved", "revoked", model.CreditEventRevoked, txHash, blockNumber)
+	return nil
+}
+
+func (s *memStore) LinkCreditCorrectionFromChain(originalContractId, newContractId int64, admin, txHash string, blockNumber uint64) error {
+	return nil
+}
+
+// RewindCreditsFromChain 与 model.RewindCreditsFromChain 一致：状态回到回退区块及之后最早一条事件的 from，解除该区间内的链上ID关联
+func (s *memStore) RewindCreditsFromChain(fromBlock uint64) error {
+	for _, c := range s.rows {
+		to := ""
+		for i := range c.events {
+			if e := &c.events[i]; e.Block != 0 && e.Block >= fromBlock {
+				if to == "" && e.From != "" {
+					to = e.From
+				}
+				e.Block = 0
+			}
+		}
+		if to != "" {
+			c.Status = to
+			if to == "pending" {
+				c.Approved = ""
+			}
+		}
+		if c.ChainBlock != 0 && c.ChainBlock >= fromBlock {
+			c.ContractId, c.ChainBlock = -1, 0
+		}
+	}
+	return nil
+}
+
+func (s *memStore) SyncUserRoleFromChain(address string, isTeacher, isAdmin bool) error {
+	s.roles[address] = [2]bool{isTeacher, isAdmin}
+	return nil
+}
+
+// testChain 部署了学分合约的模拟链，部署者同时是教师与管理员
+type testChain struct {
+	sim      *simulated.Backend
+	auth     *bind.TransactOpts
+	address  common.Address
+	abi      abi.ABI
+	contract *bind.BoundContract
+}
+
+func newTestChain(t *testing.T) *testChain {
+	t.Helper()
+	raw, err := os.ReadFile(testABIFile)
+	if err != nil {
+		t.Fatalf("读取ABI失败: %v", err)
+	}
+	var contractABI abi.ABI
+	if err := json.Unmarshal(raw, &contractABI); err != nil {
+		t.Fatalf("解析ABI失败: %v", err)
+	}
+	raw, err = os.ReadFile(testArtifactFile)
+	if err != nil {
+		t.Fatalf("读取合约产物失败: %v", err)
+	}
+	var artifact struct {
+		Bytecode string `json:"bytecode"`
+	}
+	if err := json.Unmarshal(raw, &artifact); err != nil {
+		t.Fatalf("解析合约产物失败: %v", err)
+	}
+
+	key, _ := crypto.GenerateKey()
+	auth, err := bind.NewKeyedTransactorWithChainID(key, big.NewInt(1337))
+	if err != nil {
+		t.Fatal(err)
+	}
+	balance, _ := new(big.Int).SetString("100000000000000000000", 10)
+	sim := simulated.NewBackend(types.GenesisAlloc{auth.From: {Balance: balance}})
+	t.Cleanup(func() { sim.Close() })
+
+	address, _, contract, err := bind.DeployContract(auth, contractABI, common.FromHex(artifact.Bytecode), sim.Client())
+	if err != nil {
+		t.Fatalf("部署合约失败: %v", err)
+	}
+	sim.Commit()
+	return &testChain{sim: sim, auth: auth, address: address, abi: contractABI, contract: contract}
+}
+
+// transact 发送交易并出块
+func (tc *testChain) transact(t *testing.T, method string, args ...interface{}) {
+	t.Helper()
+	if _, err := tc.contract.Transact(tc.auth, method, args...); err != nil {
+		t.Fatalf("调用 %s 失败: %v", method, err)
+	}
+	tc.sim.Commit()
+}
+
+func (tc *testChain) indexer(store IndexerStore) *Indexer {
+	return NewIndexer(tc.sim.Client(), tc.address, tc.abi, IndexerOptions{Name: "test", ReorgDepth: 1, Store: store})
+}
+
+func (tc *testChain) blockHash(t *testing.T, number uint64) string {
+	t.Helper()
+	header, err := tc.sim.Client().HeaderByNumber(context.Background(), new(big.Int).SetUint64(number))
+	if err != nil {
+		t.Fatalf("获取区块 %d 失败: %v", number, err)
+	}
+	return header.Hash().Hex()
+}
+
+func TestIndexerRecordAndApprove(t *testing.T) {
+	tc := newTestChain(t)
+	store := newMemStore()
+	ix := tc.indexer(store)
+	ctx := context.Background()
+
+	tc.transact(t, "recordCredit", "stu-001", "CS101", uint8(90)) // 区块 2
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	if len(store.rows) != 0 {
+		t.Fatalf("未达确认深度的区块不应被索引，得到 %d 条", len(store.rows))
+	}
+
+	tc.sim.Commit() // 区块 3，区块 2 达到确认深度
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	c := store.credit(0)
+	if c == nil {
+		t.Fatal("未索引到 CreditRecorded")
+	}
+	teacher := strings.ToLower(tc.auth.From.Hex())
+	if c.Student != "stu-001" || c.Course != "CS101" || c.Score != 90 || c.Teacher != teacher {
+		t.Fatalf("学分内容不符: %+v", c)
+	}
+	if store.cursor == nil || store.cursor.BlockNumber != 2 || store.cursor.BlockHash != tc.blockHash(t, 2) {
+		t.Fatalf("游标不符: %+v", store.cursor)
+	}
+
+	tc.transact(t, "approveCredit", big.NewInt(0)) // 区块 4
+	tc.sim.Commit()
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	if c.Status != "approved" || c.Approved != teacher {
+		t.Fatalf("审核结果不符: %s %q", c.Status, c.Approved)
+	}
+	// 重复轮询不应重复处理
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	if len(store.rows) != 1 || len(c.events) != 2 || store.cursor.BlockNumber != 4 {
+		t.Fatalf("重复轮询后状态不符: %d 条, %d 个事件, 游标 %+v", len(store.rows), len(c.events), store.cursor)
+	}
+}
+
+func TestIndexerReorgRewind(t *testing.T) {
+	tc := newTestChain(t)
+	store := newMemStore()
+	ix := tc.indexer(store)
+	ctx := context.Background()
+
+	parent := tc.blockHash(t, 1)
+	tc.transact(t, "recordCredit", "stu-001", "CS101", uint8(60)) // 区块 2
+	tc.transact(t, "approveCredit", big.NewInt(0))                // 区块 3
+	tc.sim.Commit()
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	orphanedCredit := store.credit(0)
+	if orphanedCredit == nil || orphanedCredit.Score != 60 || orphanedCredit.Status != "approved" {
+		t.Fatalf("分叉前学分不符: %+v", orphanedCredit)
+	}
+	orphaned := store.cursor.BlockHash
+
+	// 从区块 1 分叉：被丢弃的录入交易会回到交易池，以同 nonce 加价替换为另一门课，新链更长
+	// （被丢弃的审核交易是否重新打包取决于交易池，重扫结果须与新链一致）
+	if err := tc.sim.Fork(common.HexToHash(parent)); err != nil {
+		t.Fatalf("分叉失败: %v", err)
+	}
+	replace := *tc.auth
+	replace.Nonce = big.NewInt(1)
+	replace.GasTipCap = big.NewInt(10_000_000_000)
+	replace.GasFeeCap = big.NewInt(100_000_000_000)
+	if _, err := tc.contract.Transact(&replace, "recordCredit", "stu-001", "CS102", uint8(85)); err != nil {
+		t.Fatalf("替换录入交易失败: %v", err)
+	}
+	tc.sim.Commit() // 区块 2'
+	tc.sim.Commit()
+	tc.sim.Commit()
+	tc.sim.Commit()
+	if tc.blockHash(t, 3) == orphaned {
+		t.Fatal("分叉未生效")
+	}
+
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	var out []interface{}
+	if err := tc.contract.Call(nil, &out, "getCreditById", big.NewInt(0)); err != nil {
+		t.Fatal(err)
+	}
+	chain := *abi.ConvertType(out[0], new(utils.ChainCredit)).(*utils.ChainCredit)
+	c := store.credit(0)
+	if c == nil || c.Course != "CS102" || c.Score != 85 || (c.Status == "approved") != chain.IsApproved {
+		t.Fatalf("回退重扫后学分应以新链为准: %+v, 链上已审核 %v", c, chain.IsApproved)
+	}
+	// 被丢弃区块中的建档解除关联，审核回退为 pending
+	if orphanedCredit.ContractId != -1 || orphanedCredit.Status != "pending" || orphanedCredit.Course != "CS101" {
+		t.Fatalf("被丢弃的学分应解除关联并回退审核: %+v", orphanedCredit)
+	}
+	if len(store.rows) != 2 {
+		t.Fatalf("行数不符: %d", len(store.rows))
+	}
+	head, err := tc.sim.Client().BlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.cursor.BlockNumber != head-1 || store.cursor.BlockHash != tc.blockHash(t, head-1) {
+		t.Fatalf("游标应指向新链: %+v", store.cursor)
+	}
+}
+
+func TestIndexerRoleSyncUsesChainFlags(t *testing.T) {
+	tc := newTestChain(t)
+	store := newMemStore()
+	ix := tc.indexer(store)
+	ctx := context.Background()
+
+	teacher := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	tc.transact(t, "assignRole", teacher, "teacher")
+	// 部署者已是教师兼管理员，再分配 student 不改变其权限位
+	tc.transact(t, "assignRole", tc.auth.From, "student")
+	tc.sim.Commit()
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	want := map[string][2]bool{
+		strings.ToLower(teacher.Hex()):      {true, false},
+		strings.ToLower(tc.auth.From.Hex()): {true, true},
+	}
+	for addr, flags := range want {
+		if got, ok := store.roles[addr]; !ok || got != flags {
+			t.Fatalf("%s 同步的权限位不符: %v, 期望 %v", addr, got, flags)
+		}
+	}
+
+	// 清空游标重扫，结果不变
+	store.cursor = nil
+	store.roles = make(map[string][2]bool)
+	if err := ix.Poll(ctx); err != nil {
+		t.Fatalf("Poll 失败: %v", err)
+	}
+	if got := store.roles[strings.ToLower(tc.auth.From.Hex())]; got != [2]bool{true, true} {
+		t.Fatalf("重扫后权限位不符: %v", got)
+	}
+}
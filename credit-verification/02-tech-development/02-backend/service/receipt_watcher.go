@@ -0,0 +1,60 @@
+// service/receipt_watcher.go 链上交易台账：交易队列状态变更落库 + 后台回执巡检
+// 取代请求内阻塞等待打包；重启前发出、尚未确认的交易也由巡检补全状态
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// StartTxLedger 注册交易队列回调（写 chain_transactions）并启动回执巡检
+func StartTxLedger(ctx context.Context) {
+	utils.DefaultTxQueue.OnChange(func(tx utils.QueuedTx) {
+		if err := model.SaveQueuedTx(tx); err != nil {
+			log.Printf("[TxLedger] 记录交易 %s 失败: %v", tx.Hash, err)
+		}
+	})
+	go runReceiptWatcher(ctx, 3*time.Second)
+}
+
+func runReceiptWatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkSubmittedTxs(ctx)
+		}
+	}
+}
+
+// checkSubmittedTxs 逐条查询 submitted 交易的回执，打包后写入区块号与执行结果
+func checkSubmittedTxs(ctx context.Context) {
+	list, err := model.GetSubmittedChainTxs()
+	if err != nil {
+		log.Printf("[TxLedger] 查询待确认交易失败: %v", err)
+		return
+	}
+	for _, tx := range list {
+		receipt, err := utils.EthClient.TransactionReceipt(ctx, common.HexToHash(tx.TxHash))
+		if err != nil || receipt == nil {
+			continue
+		}
+		status := model.ChainTxMined
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			status = model.ChainTxReverted
+		}
+		if err := model.UpdateChainTxReceipt(tx.TxHash, status, receipt.BlockNumber.Uint64(), receipt.GasUsed); err != nil {
+			log.Printf("[TxLedger] 更新交易 %s 失败: %v", tx.TxHash, err)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+// service/role_service.go 角色服务：统一维护 users.role、本地角色缓存与合约 isTeacher/isAdmin 三处角色
+// assignRole / revokeRole 交易提交后登记 role_changes，打包成功才在同一事务中写数据库并刷新缓存；另提供漂移报告与逐用户修复
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"campus-credit-backend/model"
+	"campus-credit-backend/utils"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// 漂移修复方向
+const (
+	RepairFromChain = "chain" // 以链上为准改数据库
+	RepairFromDB    = "db"    // 以数据库为准补发 assignRole / revokeRole
+)
+
+// ErrRoleHeld 分配 student 不会去掉链上已有的教师/管理员权限
+var ErrRoleHeld = errors.New("该地址在链上已有教师/管理员权限，请先调用撤销接口")
+
+// RepairRevokeReason 按数据库修复漂移时撤销多余权限位记录的原因
+const RepairRevokeReason = "角色漂移修复：本地角色不需要该链上权限"
+
+// chainRoles assignRole 可分配的链上角色
+var chainRoles = map[string]bool{"teacher": true, "admin": true, "student": true}
+
+// IsChainRole 是否为合约可识别的角色
+func IsChainRole(role string) bool {
+	return chainRoles[role]
+}
+
+// IsRevocableRole 是否为 revokeRole 可撤销的角色（student 在链上无权限位）
+func IsRevocableRole(role string) bool {
+	return role == "teacher" || role == "admin"
+}
+
+// StartRoleChanges 启动角色变更轮询（重启后继续处理未确认的变更）
+func StartRoleChanges(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				processRoleChanges(ctx)
+			}
+		}
+	}()
+}
+
+func processRoleChanges(ctx context.Context) {
+	list, err := model.GetSubmittedRoleChanges()
+	if err != nil {
+		log.Printf("[RoleService] 查询角色变更失败: %v", err)
+		return
+	}
+	for i := range list {
+		if err := processRoleChange(ctx, &list[i]); err != nil {
+			log.Printf("[RoleService] 处理角色变更 %d 失败: %v", list[i].Id, err)
+		}
+	}
+}
+
+// processRoleChange 查回执：未打包跳过；失败只标记变更；成功则写数据库并按链上最新状态刷新缓存
+func processRoleChange(ctx context.Context, rc *model.RoleChange) error {
+	receipt, hash, dropped := findReceipt(ctx, rc.TxHash)
+	if receipt == nil {
+		if dropped {
+			return model.FailRoleChange(rc.Id, "交易未被打包（已失效）")
+		}
+		return nil
+	}
+	if hash != rc.TxHash {
+		if err := model.UpdateRoleChangeTx(rc, hash); err != nil {
+			return err
+		}
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return model.FailRoleChange(rc.Id, "交易执行失败（reverted）")
+	}
+	if _, err := model.ApplyRoleChange(rc); err != nil {
+		return err
+	}
+	// 回执中的角色事件先使缓存失效，再按回执成功后的链上状态写入
+	utils.InvalidateRolesFromReceipt(receipt)
+	if flags, err := utils.GetChainRoleFlags(rc.UserAddress); err == nil {
+		utils.DefaultRoleCache.Set(rc.UserAddress, flags.Role())
+	}
+	return nil
+}
+
+// AssignRole 后端签名发送 assignRole 并登记变更，返回变更ID与交易哈希；数据库与缓存在打包后更新
+func AssignRole(address, role string, operatorId uint64) (int64, string, error) {
+	if !common.IsHexAddress(address) {
+		return 0, "", fmt.Errorf("无效的以太坊地址: %s", address)
+	}
+	if !IsChainRole(role) {
+		return 0, "", fmt.Errorf("合约不支持的角色: %s", role)
+	}
+	address = common.HexToAddress(address).Hex()
+	if role == "student" {
+		flags, err := utils.GetChainRoleFlags(address)
+		if err != nil {
+			return 0, "", err
+		}
+		if flags.IsTeacher || flags.IsAdmin {
+			return 0, "", ErrRoleHeld
+		}
+	}
+	txHash, err := utils.AssignRole(address, role)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := model.CreateRoleChange(address, model.RoleActionAssign, role, "", txHash, operatorId)
+	if err != nil {
+		return 0, txHash, fmt.Errorf("交易已提交（%s），登记角色变更失败: %v", txHash, err)
+	}
+	return id, txHash, nil
+}
+
+// CheckRevoke 撤销前校验：角色可撤销、原因非空、链上确有该权限位、不撤销后端签名地址自身的权限
+func CheckRevoke(address, role, reason string) error {
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("无效的以太坊地址: %s", address)
+	}
+	if !IsRevocableRole(role) {
+		return fmt.Errorf("只能撤销 teacher 或 admin: %s", role)
+	}
+	if reason == "" {
+		return errors.New("撤销原因不能为空")
+	}
+	if utf8.RuneCountInString(reason) > 256 {
+		return errors.New("撤销原因不能超过256个字符")
+	}
+	if signer, err := utils.GetSignerAddress(); err == nil && signer == common.HexToAddress(address) {
+		return errors.New("不能撤销后端签名地址的链上权限")
+	}
+	flags, err := utils.GetChainRoleFlags(address)
+	if err != nil {
+		return err
+	}
+	if (role == "teacher" && !flags.IsTeacher) || (role == "admin" && !flags.IsAdmin) {
+		return fmt.Errorf("该地址在链上没有 %s 权限", role)
+	}
+	return nil
+}
+
+// RevokeRole 后端签名发送 revokeRole 并登记变更（记录操作人与原因）；
+// 缓存立即清除，打包后降级 users.role 并使该用户所有 Token 失效
+func RevokeRole(address, role, reason string, operatorId uint64) (int64, string, error) {
+	if err := CheckRevoke(address, role, reason); err != nil {
+		return 0, "", err
+	}
+	address = common.HexToAddress(address).Hex()
+	txHash, err := utils.RevokeRole(address, role)
+	if err != nil {
+		return 0, "", err
+	}
+	id, err := model.CreateRoleChange(address, model.RoleActionRevoke, role, reason, txHash, operatorId)
+	if err != nil {
+		return 0, txHash, fmt.Errorf("交易已提交（%s），登记角色变更失败: %v", txHash, err)
+	}
+	return id, txHash, nil
+}
+
+// TrackRoleChange 钱包签名模式：登记已广播的 assignRole / revokeRole 交易（同一哈希只登记一次）
+func TrackRoleChange(address common.Address, action, role, reason, txHash string, operatorId uint64) (int64, error) {
+	existing, err := model.GetRoleChangeByTxHash(txHash)
+	if err != nil {
+		return 0, err
+	}
+	if existing != nil {
+		return existing.Id, nil
+	}
+	if action == model.RoleActionRevoke {
+		utils.DefaultRoleCache.Invalidate(address.Hex())
+	}
+	return model.CreateRoleChange(address.Hex(), action, role, reason, txHash, operatorId)
+}
+
+// RoleDrift 单个用户的本地角色与链上权限位对比
+type RoleDrift struct {
+	UserId    uint64   `json:"user_id"`
+	Username  string   `json:"username"`
+	Address   string   `json:"address"`
+	DbRole    string   `json:"db_role"`
+	ChainRole string   `json:"chain_role"` // getRole 结果，无权限为 student
+	IsTeacher bool     `json:"is_teacher"`
+	IsAdmin   bool     `json:"is_admin"`
+	Missing   []string `json:"missing"` // 本地角色需要但链上没有的权限位
+	Extra     []string `json:"extra"`   // 链上有但本地角色不需要的权限位
+	Error     string   `json:"error,omitempty"`
+}
+
+// Drifted 是否存在漂移（读取链上失败也计入，便于排查）
+func (d *RoleDrift) Drifted() bool {
+	return len(d.Missing) > 0 || len(d.Extra) > 0 || d.Error != ""
+}
+
+// CheckRoleDrift 对比单个用户：本地角色有 credit:record 需 isTeacher，有 credit:approve 需 isAdmin
+func CheckRoleDrift(user *model.User) *RoleDrift {
+	d := &RoleDrift{UserId: user.Id, Username: user.Username, Address: user.Address.String, DbRole: user.Role}
+	flags, err := utils.GetChainRoleFlags(user.Address.String)
+	if err != nil {
+		d.Error = err.Error()
+		return d
+	}
+	d.ChainRole, d.IsTeacher, d.IsAdmin = flags.Role(), flags.IsTeacher, flags.IsAdmin
+	needTeacher, needAdmin := model.ChainFlagsRequired(user.Role)
+	for _, f := range []struct {
+		name       string
+		need, have bool
+	}{{"teacher", needTeacher, flags.IsTeacher}, {"admin", needAdmin, flags.IsAdmin}} {
+		switch {
+		case f.need && !f.have:
+			d.Missing = append(d.Missing, f.name)
+		case !f.need && f.have:
+			d.Extra = append(d.Extra, f.name)
+		}
+	}
+	return d
+}
+
+// RoleDriftReport 已绑定地址用户中本地角色与链上不一致的列表
+func RoleDriftReport() ([]*RoleDrift, error) {
+	users, err := model.ListUsersWithAddress()
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*RoleDrift, 0)
+	for i := range users {
+		if d := CheckRoleDrift(&users[i]); d.Drifted() {
+			list = append(list, d)
+		}
+	}
+	return list, nil
+}
+
+// RepairResult 修复结果：按链上修复返回新的本地角色；按数据库修复返回补发的分配/撤销
+type RepairResult struct {
+	Source  string         `json:"source"`
+	DbRole  string         `json:"db_role,omitempty"`
+	Changes []RepairRoleTx `json:"changes,omitempty"`
+	Drift   *RoleDrift     `json:"drift"`
+}
+
+// RepairRoleTx 补发的 assignRole / revokeRole
+type RepairRoleTx struct {
+	Action   string `json:"action"`
+	Role     string `json:"role"`
+	ChangeId int64  `json:"change_id"`
+	TxHash   string `json:"tx_hash"`
+}
+
+// RepairRoleDrift 修复单个用户：chain 以链上为准改 users.role；
+// db 为本地角色缺少的权限位补发 assignRole、多出的权限位补发 revokeRole（打包后生效）
+func RepairRoleDrift(user *model.User, source string, operatorId uint64) (*RepairResult, error) {
+	d := CheckRoleDrift(user)
+	if d.Error != "" {
+		return nil, errors.New(d.Error)
+	}
+	res := &RepairResult{Source: source, Drift: d}
+	switch source {
+	case RepairFromChain:
+		role := model.RoleFromChainFlags(d.IsTeacher, d.IsAdmin)
+		if len(d.Missing) == 0 && len(d.Extra) == 0 {
+			role = user.Role
+		}
+		if err := model.SetUserRole(user.Id, role); err != nil {
+			return nil, err
+		}
+		utils.DefaultRoleCache.Set(d.Address, d.ChainRole)
+		res.DbRole = role
+	case RepairFromDB:
+		for _, role := range d.Missing {
+			id, txHash, err := AssignRole(d.Address, role, operatorId)
+			if err != nil {
+				return nil, err
+			}
+			res.Changes = append(res.Changes, RepairRoleTx{Action: model.RoleActionAssign, Role: role, ChangeId: id, TxHash: txHash})
+		}
+		for _, role := range d.Extra {
+			id, txHash, err := RevokeRole(d.Address, role, RepairRevokeReason, operatorId)
+			if err != nil {
+				return nil, err
+			}
+			res.Changes = append(res.Changes, RepairRoleTx{Action: model.RoleActionRevoke, Role: role, ChangeId: id, TxHash: txHash})
+		}
+	default:
+		return nil, fmt.Errorf("未知的修复方向: %s（chain 或 db）", source)
+	}
+	return res, nil
+}
@@ -23,10 +23,45 @@ var GlobalConfig struct {
 		RpcUrl             string `mapstructure:"rpc_url"`
 		CreditContractAddr string `mapstructure:"credit_contract_addr"`
 		PrivateKey         string `mapstructure:"private_key"`
+		TxStuckSeconds     int    `mapstructure:"tx_stuck_seconds"` // 交易超过该时长未打包视为卡住，提价重发
+		FeeBumpPercent     int    `mapstructure:"fee_bump_percent"` // 重发时手续费上调百分比（至少 10）
+		// 手续费与 gas
+		FeeStrategy    string             `mapstructure:"fee_strategy"`    // legacy / eip1559 / fixed
+		GasPriceGwei   float64            `mapstructure:"gas_price_gwei"`  // fixed 策略的 gasPrice
+		MaxFeeGwei     float64            `mapstructure:"max_fee_gwei"`    // 单位 gas 价格上限，超出拒绝发送（0 不限）
+		GasMultiplier  float64            `mapstructure:"gas_multiplier"`  // EstimateGas 结果的安全系数
+		GasMultipliers map[string]float64 `mapstructure:"gas_multipliers"` // 按合约方法覆盖安全系数
+		Signer         struct {
+			Type          string `mapstructure:"type"`           // private_key/keystore/remote/wallet
+			KeystoreFile  string `mapstructure:"keystore_file"`  // keystore JSON 路径
+			PasswordEnv   string `mapstructure:"password_env"`   // 存放 keystore 密码的环境变量名
+			RemoteUrl     string `mapstructure:"remote_url"`     // 远程签名器 JSON-RPC 地址
+			RemoteAddress string `mapstructure:"remote_address"` // 远程签名器中使用的账户
+		} `mapstructure:"signer"`
 	} `mapstructure:"ethereum"`
+	Indexer struct {
+		Enabled     bool   `mapstructure:"enabled"`
+		StartBlock  uint64 `mapstructure:"start_block"`
+		ReorgDepth  uint64 `mapstructure:"reorg_depth"`
+		BatchSize   uint64 `mapstructure:"batch_size"`
+		PollSeconds int    `mapstructure:"poll_seconds"`
+	} `mapstructure:"indexer"`
+	RoleCache struct {
+		TtlSeconds int `mapstructure:"ttl_seconds"` // 链上角色缓存有效期
+		MaxEntries int `mapstructure:"max_entries"` // 缓存条目上限，超出按最久未使用淘汰
+	} `mapstructure:"role_cache"`
+	Siwe struct {
+		Domain          string `mapstructure:"domain"`            // 前端站点 host[:port]，须与 SIWE 消息 domain 一致
+		Uri             string `mapstructure:"uri"`               // 前端站点 URI
+		Statement       string `mapstructure:"statement"`         // 钱包中展示的说明文字
+		NonceTtlSeconds int    `mapstructure:"nonce_ttl_seconds"` // nonce 有效期
+	} `mapstructure:"siwe"`
 	JWT struct {
-		Secret      string `mapstructure:"secret"`
-		ExpireHours int    `mapstructure:"expire_hours"`
+		KeyDir              string `mapstructure:"key_dir"`               // 签名密钥目录：<kid>.pem 私钥，<kid>.pub.pem 仅验证的公钥
+		ActiveKid           string `mapstructure:"active_kid"`            // 当前签名所用私钥的 kid
+		ExpireHours         int    `mapstructure:"expire_hours"`          // 旧配置：未设置 refresh_expire_hours 时作为刷新 Token 有效期
+		AccessExpireMinutes int    `mapstructure:"access_expire_minutes"` // 访问 Token 有效期
+		RefreshExpireHours  int    `mapstructure:"refresh_expire_hours"`  // 刷新 Token 有效期
 	} `mapstructure:"jwt"`
 }
 
@@ -42,5 +77,8 @@ func InitConfig() {
 	if err := viper.Unmarshal(&GlobalConfig); err != nil {
 		log.Fatalf("解析配置失败: %v", err)
 	}
+	if GlobalConfig.Siwe.Domain == "" || GlobalConfig.Siwe.Uri == "" {
+		log.Println("警告: 未配置 siwe.domain / siwe.uri，钱包签名登录与绑定将被拒绝")
+	}
 	log.Println("配置初始化完成")
 }
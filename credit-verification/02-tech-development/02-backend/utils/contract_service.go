@@ -3,16 +3,12 @@ package utils
 import (
 	"fmt"
 	"math/big"
-	"sync"
 
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind/v2"
 	"github.com/ethereum/go-ethereum/common"
-)
-
-// 新增：本地角色缓存（解决合约查询兼容问题，线程安全）
-var (
-	roleCache = make(map[string]string)
-	cacheLock sync.RWMutex // 读写锁，保证多请求安全
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 )
 
 // ========== 角色管理相关（现在调用 CreditContract 的 assignRole/getRole） ==========
@@ -23,23 +19,25 @@ func AssignRole(userAddress string, role string) (string, error) {
 	}
 	addr := common.HexToAddress(userAddress)
 
-	// 2. 获取交易选项
-	transactOpts, err := GetTransactOpts()
+	// 2. 经交易队列调用合约assignRole方法（串行分配 nonce）
+	// 缓存与数据库在交易打包后由角色服务（service/role_service.go）更新
+	tx, err := SendContractTx("assignRole", addr, role)
 	if err != nil {
-		return "", fmt.Errorf("获取交易选项失败: %v", err)
+		return "", err
 	}
+	return tx.Hash().Hex(), nil
+}
 
-	// 3. 调用合约assignRole方法
-	tx, err := CreditContractInstance.Transact(transactOpts, "assignRole", addr, role)
+// RevokeRole 经交易队列调用合约 revokeRole（仅 teacher/admin），并清除该地址的本地角色缓存
+func RevokeRole(userAddress string, role string) (string, error) {
+	if !common.IsHexAddress(userAddress) {
+		return "", fmt.Errorf("无效的以太坊地址: %s", userAddress)
+	}
+	tx, err := SendContractTx("revokeRole", common.HexToAddress(userAddress), role)
 	if err != nil {
-		return "", fmt.Errorf("调用assignRole失败: %v", err)
+		return "", err
 	}
-
-	// 4. 新增：本地缓存角色（核心降级逻辑，一行代码）
-	cacheLock.Lock()
-	roleCache[userAddress] = role
-	cacheLock.Unlock()
-
+	DefaultRoleCache.Invalidate(userAddress)
 	return tx.Hash().Hex(), nil
 }
 
@@ -49,31 +47,38 @@ func GetRole(userAddress string) (string, error) {
 		return "", fmt.Errorf("无效的以太坊地址: %s", userAddress)
 	}
 
-	// 2. 优先读本地缓存（核心：直接返回缓存值，跳过合约调用）
-	cacheLock.RLock()
-	role, exists := roleCache[userAddress]
-	cacheLock.RUnlock()
-	if exists {
+	// 2. 优先读本地缓存（键已归一化，大小写不同的写法命中同一条）
+	if role, ok := DefaultRoleCache.Get(userAddress); ok {
 		return role, nil
 	}
 
-	// 3. 缓存无则从链上查询（用于钱包登录）
-	return GetRoleFromChain(userAddress)
+	// 3. 缓存无则从链上查询（用于钱包登录）；只缓存成功读到的结果
+	role, ok := readRoleFromChain(userAddress)
+	if ok {
+		DefaultRoleCache.Set(userAddress, role)
+	}
+	return role, nil
 }
 
 // GetRoleFromChain 从合约读取地址对应角色（用于钱包登录）
-// bind.Call 的 result 需为 *[]interface{}，再从首元素取 string；避免 panic 导致 500
 func GetRoleFromChain(userAddress string) (string, error) {
 	if !common.IsHexAddress(userAddress) {
 		return "", fmt.Errorf("无效的以太坊地址: %s", userAddress)
 	}
+	role, _ := readRoleFromChain(userAddress)
+	return role, nil
+}
+
+// readRoleFromChain 调用 getRole；合约未初始化或调用失败时降级为 student 且 ok 为 false
+// bind.Call 的 result 需为 *[]interface{}，再从首元素取 string；避免 panic 导致 500
+func readRoleFromChain(userAddress string) (string, bool) {
 	if CreditContractInstance == nil {
-		return "student", nil
+		return "student", false
 	}
 	var out []interface{}
 	err := CreditContractInstance.Call(&bind.CallOpts{}, &out, "getRole", common.HexToAddress(userAddress))
 	if err != nil || len(out) == 0 {
-		return "student", nil
+		return "student", false
 	}
 	var role string
 	if s, ok := out[0].(string); ok {
@@ -82,7 +87,74 @@ func GetRoleFromChain(userAddress string) (string, error) {
 	if role == "" {
 		role = "student"
 	}
-	return role, nil
+	return role, true
+}
+
+// InvalidateRolesFromReceipt 回执中本合约的 RoleAssigned / RoleRevoked 事件使对应地址的角色缓存失效，返回涉及的地址
+func InvalidateRolesFromReceipt(receipt *types.Receipt) []common.Address {
+	if receipt == nil {
+		return nil
+	}
+	contractAddr := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	var addrs []common.Address
+	for _, lg := range receipt.Logs {
+		if lg.Address != contractAddr || len(lg.Topics) < 2 || !IsRoleEventTopic(lg.Topics[0]) {
+			continue
+		}
+		addr := common.BytesToAddress(lg.Topics[1].Bytes())
+		DefaultRoleCache.Invalidate(addr.Hex())
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// IsRoleEventTopic 是否为 RoleAssigned / RoleRevoked 事件签名
+func IsRoleEventTopic(topic common.Hash) bool {
+	return topic == CreditContractABI.Events["RoleAssigned"].ID || topic == CreditContractABI.Events["RoleRevoked"].ID
+}
+
+// ChainRoleFlags 合约 isTeacher / isAdmin 两个权限位（getRole 只返回其一：教师优先）
+type ChainRoleFlags struct {
+	IsTeacher bool `json:"is_teacher"`
+	IsAdmin   bool `json:"is_admin"`
+}
+
+// GetChainRoleFlags 读取地址在合约上的 isTeacher / isAdmin；与 GetRoleFromChain 不同，调用失败时返回错误
+func GetChainRoleFlags(userAddress string) (*ChainRoleFlags, error) {
+	if !common.IsHexAddress(userAddress) {
+		return nil, fmt.Errorf("无效的以太坊地址: %s", userAddress)
+	}
+	if CreditContractInstance == nil {
+		return nil, fmt.Errorf("合约未初始化")
+	}
+	addr := common.HexToAddress(userAddress)
+	var flags ChainRoleFlags
+	for method, dst := range map[string]*bool{"isTeacher": &flags.IsTeacher, "isAdmin": &flags.IsAdmin} {
+		var out []interface{}
+		if err := CreditContractInstance.Call(&bind.CallOpts{}, &out, method, addr); err != nil {
+			return nil, fmt.Errorf("调用%s失败: %v", method, err)
+		}
+		if len(out) == 0 {
+			return nil, fmt.Errorf("%s返回为空", method)
+		}
+		v, ok := out[0].(bool)
+		if !ok {
+			return nil, fmt.Errorf("%s类型异常: %T", method, out[0])
+		}
+		*dst = v
+	}
+	return &flags, nil
+}
+
+// Role getRole 语义的角色名：teacher 优先于 admin，都没有为 student
+func (f *ChainRoleFlags) Role() string {
+	switch {
+	case f.IsTeacher:
+		return "teacher"
+	case f.IsAdmin:
+		return "admin"
+	}
+	return "student"
 }
 
 // ========== 学分管理相关 ==========
@@ -116,50 +188,205 @@ func GetNextCreditId() (uint64, error) {
 	}
 }
 
-func RecordCredit(userAddress string, courseName string, score float64) (string, error) {
+// RecordCredit 提交录入学分交易（不等待打包）；打包后由后台任务用 ParseCreditRecordedId 从回执事件解析 creditId
+func RecordCredit(userAddress string, courseName string, score float64) (*types.Transaction, error) {
 	if score < 0 || score > 100 {
-		return "", fmt.Errorf("学分值超出范围（0-100）: %v", score)
+		return nil, fmt.Errorf("学分值超出范围（0-100）: %v", score)
 	}
 	scoreUint8 := uint8(score)
 	if courseName == "" || userAddress == "" {
-		return "", fmt.Errorf("课程名/学生学号不能为空")
+		return nil, fmt.Errorf("课程名/学生学号不能为空")
 	}
 
-	transactOpts, err := GetTransactOpts()
+	return SendContractTx("recordCredit", userAddress, courseName, scoreUint8)
+}
+
+// ParseCreditRecordedId 从回执中解析本合约 CreditRecorded 事件的 creditId（indexed，位于 Topics[1]）
+func ParseCreditRecordedId(receipt *types.Receipt) (uint64, error) {
+	if receipt == nil {
+		return 0, fmt.Errorf("回执为空")
+	}
+	eventId := CreditContractABI.Events["CreditRecorded"].ID
+	contractAddr := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	for _, lg := range receipt.Logs {
+		if lg.Address != contractAddr || len(lg.Topics) < 2 || lg.Topics[0] != eventId {
+			continue
+		}
+		return new(big.Int).SetBytes(lg.Topics[1].Bytes()).Uint64(), nil
+	}
+	return 0, fmt.Errorf("回执中未找到CreditRecorded事件: %s", receipt.TxHash.Hex())
+}
+
+// SupersedeCredit 提交更正交易：以新的课程名/成绩生成取代原记录的新链上记录（不等待打包，打包后用 ParseCreditCorrected 解析新ID）
+func SupersedeCredit(originalId uint64, courseName string, score float64) (string, error) {
+	if score < 0 || score > 100 {
+		return "", fmt.Errorf("学分值超出范围（0-100）: %v", score)
+	}
+	if courseName == "" {
+		return "", fmt.Errorf("课程名不能为空")
+	}
+	tx, err := SendContractTx("supersedeCredit", new(big.Int).SetUint64(originalId), courseName, uint8(score))
 	if err != nil {
-		return "", fmt.Errorf("获取交易选项失败: %v", err)
+		return "", err
 	}
+	return tx.Hash().Hex(), nil
+}
 
-	tx, err := CreditContractInstance.Transact(
-		transactOpts,
-		"recordCredit",
-		userAddress,
-		courseName,
-		scoreUint8,
-	)
+// ParseCreditCorrected 从回执中解析 CreditCorrected 事件的原记录ID与新记录ID（均为 indexed，位于 Topics[1]、Topics[2]）
+func ParseCreditCorrected(receipt *types.Receipt) (uint64, uint64, error) {
+	if receipt == nil {
+		return 0, 0, fmt.Errorf("回执为空")
+	}
+	eventId := CreditContractABI.Events["CreditCorrected"].ID
+	contractAddr := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	for _, lg := range receipt.Logs {
+		if lg.Address != contractAddr || len(lg.Topics) < 3 || lg.Topics[0] != eventId {
+			continue
+		}
+		return new(big.Int).SetBytes(lg.Topics[1].Bytes()).Uint64(), new(big.Int).SetBytes(lg.Topics[2].Bytes()).Uint64(), nil
+	}
+	return 0, 0, fmt.Errorf("回执中未找到CreditCorrected事件: %s", receipt.TxHash.Hex())
+}
+
+// ParseCreditAuditIds 从回执中解析本合约逐条触发的 CreditApproved（approved 为 true）或 CreditRejected 事件的 creditId
+// approveCredits / rejectCredits 会跳过链上已非待审核的学分，未出现在结果中的即被跳过
+func ParseCreditAuditIds(receipt *types.Receipt, approved bool) (map[uint64]bool, error) {
+	if approved {
+		return parseCreditEventIds(receipt, "CreditApproved")
+	}
+	return parseCreditEventIds(receipt, "CreditRejected")
+}
+
+// ParseCreditRevokedIds 从回执中解析本合约触发的 CreditRevoked 事件的 creditId
+func ParseCreditRevokedIds(receipt *types.Receipt) (map[uint64]bool, error) {
+	return parseCreditEventIds(receipt, "CreditRevoked")
+}
+
+// parseCreditEventIds 回执中本合约触发的指定事件（首个 indexed 参数为 creditId）的 creditId 集合
+func parseCreditEventIds(receipt *types.Receipt, event string) (map[uint64]bool, error) {
+	if receipt == nil {
+		return nil, fmt.Errorf("回执为空")
+	}
+	eventId := CreditContractABI.Events[event].ID
+	contractAddr := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	ids := make(map[uint64]bool)
+	for _, lg := range receipt.Logs {
+		if lg.Address != contractAddr || len(lg.Topics) < 2 || lg.Topics[0] != eventId {
+			continue
+		}
+		ids[new(big.Int).SetBytes(lg.Topics[1].Bytes()).Uint64()] = true
+	}
+	return ids, nil
+}
+
+// AuditCredit 提交审核结果：approved 为 true 调 approveCredit，否则调 rejectCredit 并附驳回原因的 keccak256
+func AuditCredit(creditId uint64, approved bool, reason string) (string, error) {
+	creditIdInt := new(big.Int).SetUint64(creditId)
+
+	var tx *types.Transaction
+	var err error
+	if approved {
+		// 注意：合约方法名是 approveCredit，不是 auditCredit
+		tx, err = SendContractTx("approveCredit", creditIdInt)
+	} else {
+		if reason == "" {
+			return "", fmt.Errorf("驳回原因不能为空")
+		}
+		tx, err = SendContractTx("rejectCredit", creditIdInt, RejectReasonHash(reason))
+	}
 	if err != nil {
-		return "", fmt.Errorf("调用recordCredit失败: %v", err)
+		return "", err
 	}
 
 	return tx.Hash().Hex(), nil
 }
 
-func AuditCredit(creditId uint64, approved bool) (string, error) {
-	creditIdInt := big.NewInt(int64(creditId))
-	transactOpts, err := GetTransactOpts()
-	if err != nil {
-		return "", fmt.Errorf("获取交易选项失败: %v", err)
+// AuditCredits 批量提交审核结果：approved 为 true 调 approveCredits，否则调 rejectCredits（整批共用驳回原因）
+// 合约跳过链上已审核/已驳回的学分而不回滚整批
+func AuditCredits(creditIds []uint64, approved bool, reason string) (string, error) {
+	ids := make([]*big.Int, len(creditIds))
+	for i, id := range creditIds {
+		ids[i] = new(big.Int).SetUint64(id)
 	}
 
-	// 注意：合约方法名是 approveCredit，不是 auditCredit
-	tx, err := CreditContractInstance.Transact(transactOpts, "approveCredit", creditIdInt)
+	var tx *types.Transaction
+	var err error
+	if approved {
+		tx, err = SendContractTx("approveCredits", ids)
+	} else {
+		if reason == "" {
+			return "", fmt.Errorf("驳回原因不能为空")
+		}
+		tx, err = SendContractTx("rejectCredits", ids, RejectReasonHash(reason))
+	}
 	if err != nil {
-		return "", fmt.Errorf("调用approveCredit失败: %v", err)
+		return "", err
 	}
+	return tx.Hash().Hex(), nil
+}
+
+// RejectReasonHash 驳回原因/撤销理由原文（UTF-8）的 keccak256，链上只存该哈希，可用数据库中的原文复核
+func RejectReasonHash(reason string) [32]byte {
+	return crypto.Keccak256Hash([]byte(reason))
+}
 
+// RevokeCredit 撤销已审核的学分：调 revokeCredit 并附撤销理由的 keccak256
+func RevokeCredit(creditId uint64, reason string) (string, error) {
+	if reason == "" {
+		return "", fmt.Errorf("撤销理由不能为空")
+	}
+	tx, err := SendContractTx("revokeCredit", new(big.Int).SetUint64(creditId), RejectReasonHash(reason))
+	if err != nil {
+		return "", err
+	}
 	return tx.Hash().Hex(), nil
 }
 
+// GetCreditRejectionFromChain 读取合约 isRejected / rejectReasonHash
+func GetCreditRejectionFromChain(creditId uint64) (bool, [32]byte, error) {
+	return readCreditFlagWithHash(creditId, "isRejected", "rejectReasonHash")
+}
+
+// GetCreditRevocationFromChain 读取合约 isRevoked / revokeReasonHash
+func GetCreditRevocationFromChain(creditId uint64) (bool, [32]byte, error) {
+	return readCreditFlagWithHash(creditId, "isRevoked", "revokeReasonHash")
+}
+
+// readCreditFlagWithHash 读取学分的状态标记，标记为 true 时再读取对应的理由哈希
+func readCreditFlagWithHash(creditId uint64, flagMethod, hashMethod string) (bool, [32]byte, error) {
+	if CreditContractInstance == nil {
+		return false, [32]byte{}, fmt.Errorf("合约未初始化")
+	}
+	id := new(big.Int).SetUint64(creditId)
+	var out []interface{}
+	if err := CreditContractInstance.Call(&bind.CallOpts{}, &out, flagMethod, id); err != nil {
+		return false, [32]byte{}, fmt.Errorf("调用%s失败: %v", flagMethod, err)
+	}
+	flag, ok := firstOut(out).(bool)
+	if !ok {
+		return false, [32]byte{}, fmt.Errorf("%s返回异常", flagMethod)
+	}
+	if !flag {
+		return false, [32]byte{}, nil
+	}
+	out = nil
+	if err := CreditContractInstance.Call(&bind.CallOpts{}, &out, hashMethod, id); err != nil {
+		return true, [32]byte{}, fmt.Errorf("调用%s失败: %v", hashMethod, err)
+	}
+	hash, ok := firstOut(out).([32]byte)
+	if !ok {
+		return true, [32]byte{}, fmt.Errorf("%s返回异常", hashMethod)
+	}
+	return true, hash, nil
+}
+
+func firstOut(out []interface{}) interface{} {
+	if len(out) == 0 {
+		return nil
+	}
+	return out[0]
+}
+
 func GetUserCredits(userAddress string) ([]map[string]interface{}, error) {
 	_ = common.HexToAddress(userAddress)
 	var result []interface{}
@@ -202,3 +429,31 @@ func GetUserCredits(userAddress string) ([]map[string]interface{}, error) {
 
 	return credits, nil
 }
+
+// ChainCredit 链上学分结构（与合约 Credit 结构体字段顺序、类型一致，便于 abi.ConvertType 直接转换）
+type ChainCredit struct {
+	CreditId       *big.Int       `json:"credit_id"`
+	StudentId      string         `json:"student_id"`
+	CourseName     string         `json:"course_name"`
+	Score          uint8          `json:"score"`
+	TeacherAddress common.Address `json:"teacher_address"`
+	IsApproved     bool           `json:"is_approved"`
+	Exists         bool           `json:"exists"`
+}
+
+// GetCreditByIdFromChain 按链上学分ID读取合约 getCreditById（不存在时合约会 revert，返回错误）
+func GetCreditByIdFromChain(creditId uint64) (*ChainCredit, error) {
+	if CreditContractInstance == nil {
+		return nil, fmt.Errorf("合约未初始化")
+	}
+	var out []interface{}
+	err := CreditContractInstance.Call(&bind.CallOpts{}, &out, "getCreditById", new(big.Int).SetUint64(creditId))
+	if err != nil {
+		return nil, fmt.Errorf("调用getCreditById失败: %v", err)
+	}
+	if len(out) == 0 || out[0] == nil {
+		return nil, fmt.Errorf("getCreditById返回为空")
+	}
+	credit := *abi.ConvertType(out[0], new(ChainCredit)).(*ChainCredit)
+	return &credit, nil
+}
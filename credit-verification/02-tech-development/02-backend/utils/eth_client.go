@@ -6,14 +6,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"log"
-	"math/big"
-	"strings"
-	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
@@ -54,56 +51,51 @@ func InitEthClient() {
 	CreditContractInstance = bind.NewBoundContract(creditContractAddr, CreditContractABI, client, client, client)
 
 	log.Println("合约实例化成功（仅CreditContract）")
+
+	// 4. 初始化后端签名器
+	InitSigner()
 }
 
-// GetTransactOpts 保留原有逻辑（无需修改）
+// GetTransactOpts 构造后端签名账户的交易选项（含手续费），签名交给 BackendSigner
+// 不设置 Nonce：nonce 由 TxQueue 串行分配（直接用本选项 Transact 时 bind 会自行取 PendingNonceAt，并发下可能重复）
 func GetTransactOpts() (*bind.TransactOpts, error) {
-	privateKeyStr := GlobalConfig.Ethereum.PrivateKey
-	if strings.HasPrefix(privateKeyStr, "0x") {
-		privateKeyStr = privateKeyStr[2:]
-	}
-	privateKey, err := crypto.HexToECDSA(privateKeyStr)
-	if err != nil {
-		return nil, fmt.Errorf("解析私钥失败: %v", err)
-	}
-
-	fromAddr := crypto.PubkeyToAddress(privateKey.PublicKey)
-	nonce, err := EthClient.PendingNonceAt(context.Background(), fromAddr)
-	if err != nil {
-		return nil, fmt.Errorf("获取Nonce失败: %v", err)
+	if BackendSigner == nil {
+		return nil, ErrWalletSignerMode
 	}
+	fromAddr := BackendSigner.Address()
 
 	chainID, err := EthClient.ChainID(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("获取链ID失败: %v", err)
 	}
 
-	transactOpts, err := bind.NewKeyedTransactorWithChainID(privateKey, chainID)
-	if err != nil {
-		return nil, fmt.Errorf("创建交易选项失败: %v", err)
+	transactOpts := &bind.TransactOpts{
+		From: fromAddr,
+		Signer: func(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if addr != fromAddr {
+				return nil, bind.ErrNotAuthorized
+			}
+			return BackendSigner.SignTx(context.Background(), tx, chainID)
+		},
+		Context: context.Background(),
 	}
 
-	transactOpts.Nonce = big.NewInt(int64(nonce))
-	transactOpts.From = fromAddr
-	transactOpts.GasLimit = uint64(300000)
-	transactOpts.GasPrice = big.NewInt(1000000000)
+	// 手续费按 ethereum.fee_strategy 填充；GasLimit 由发送方按方法估算（TxQueue.Send）
+	strategy, err := NewFeeStrategy(GlobalConfig.Ethereum.FeeStrategy)
+	if err != nil {
+		return nil, err
+	}
+	if err := strategy.Apply(context.Background(), transactOpts); err != nil {
+		return nil, err
+	}
 
 	return transactOpts, nil
 }
 
-// WaitTxMined 等待交易被打包，便于随后查询链上状态（如 getStudentCredits）
-func WaitTxMined(ctx context.Context, txHashHex string, maxWait time.Duration) error {
-	if EthClient == nil {
-		return fmt.Errorf("以太坊客户端未初始化")
-	}
-	hash := common.HexToHash(txHashHex)
-	deadline := time.Now().Add(maxWait)
-	for time.Now().Before(deadline) {
-		receipt, err := EthClient.TransactionReceipt(ctx, hash)
-		if err == nil && receipt != nil {
-			return nil
-		}
-		time.Sleep(500 * time.Millisecond)
+// GetSignerAddress 后端发交易所用账户地址（钱包模式下无后端账户）
+func GetSignerAddress() (common.Address, error) {
+	if BackendSigner == nil {
+		return common.Address{}, ErrWalletSignerMode
 	}
-	return fmt.Errorf("等待交易打包超时: %s", txHashHex)
+	return BackendSigner.Address(), nil
 }
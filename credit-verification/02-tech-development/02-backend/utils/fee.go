@@ -0,0 +1,138 @@
+// utils/fee.go 交易手续费策略（legacy / eip1559 / fixed）与按方法估算 gas
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// 手续费策略名（ethereum.fee_strategy）
+const (
+	FeeStrategyLegacy  = "legacy"  // 节点 SuggestGasPrice
+	FeeStrategyEIP1559 = "eip1559" // SuggestGasTipCap + 最新区块 baseFee
+	FeeStrategyFixed   = "fixed"   // 固定 gas_price_gwei
+)
+
+// FeeStrategy 为交易选项填充手续费字段
+type FeeStrategy interface {
+	Name() string
+	Apply(ctx context.Context, opts *bind.TransactOpts) error
+}
+
+// NewFeeStrategy 按名称创建策略，空字符串视为 legacy
+func NewFeeStrategy(name string) (FeeStrategy, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", FeeStrategyLegacy:
+		return legacyFee{}, nil
+	case FeeStrategyEIP1559:
+		return eip1559Fee{}, nil
+	case FeeStrategyFixed:
+		gwei := GlobalConfig.Ethereum.GasPriceGwei
+		if gwei <= 0 {
+			gwei = 1
+		}
+		return fixedFee{gasPrice: gweiToWei(gwei)}, nil
+	}
+	return nil, fmt.Errorf("未知的手续费策略: %s（可选 legacy/eip1559/fixed）", name)
+}
+
+type legacyFee struct{}
+
+func (legacyFee) Name() string { return FeeStrategyLegacy }
+
+func (legacyFee) Apply(ctx context.Context, opts *bind.TransactOpts) error {
+	gasPrice, err := EthClient.SuggestGasPrice(ctx)
+	if err != nil {
+		return fmt.Errorf("获取建议gasPrice失败: %v", err)
+	}
+	if err := CheckFeeCeiling(gasPrice); err != nil {
+		return err
+	}
+	opts.GasPrice = gasPrice
+	return nil
+}
+
+type eip1559Fee struct{}
+
+func (eip1559Fee) Name() string { return FeeStrategyEIP1559 }
+
+// Apply maxFeePerGas = 2 * baseFee + tip，可容忍连续几个区块 baseFee 上涨
+func (eip1559Fee) Apply(ctx context.Context, opts *bind.TransactOpts) error {
+	tip, err := EthClient.SuggestGasTipCap(ctx)
+	if err != nil {
+		return fmt.Errorf("获取建议小费失败: %v", err)
+	}
+	head, err := EthClient.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("获取最新区块失败: %v", err)
+	}
+	if head.BaseFee == nil {
+		return fmt.Errorf("当前链不支持 EIP-1559（区块无 baseFee），请改用 legacy 策略")
+	}
+	feeCap := new(big.Int).Add(new(big.Int).Mul(head.BaseFee, big.NewInt(2)), tip)
+	if err := CheckFeeCeiling(feeCap); err != nil {
+		return err
+	}
+	opts.GasTipCap = tip
+	opts.GasFeeCap = feeCap
+	return nil
+}
+
+type fixedFee struct {
+	gasPrice *big.Int
+}
+
+func (fixedFee) Name() string { return FeeStrategyFixed }
+
+func (f fixedFee) Apply(ctx context.Context, opts *bind.TransactOpts) error {
+	if err := CheckFeeCeiling(f.gasPrice); err != nil {
+		return err
+	}
+	opts.GasPrice = new(big.Int).Set(f.gasPrice)
+	return nil
+}
+
+// CheckFeeCeiling 单位 gas 价格（gasPrice 或 maxFeePerGas）超过 max_fee_gwei 时拒绝发送；未配置则不限制
+func CheckFeeCeiling(feePerGas *big.Int) error {
+	ceilingGwei := GlobalConfig.Ethereum.MaxFeeGwei
+	if ceilingGwei <= 0 {
+		return nil
+	}
+	if ceiling := gweiToWei(ceilingGwei); feePerGas.Cmp(ceiling) > 0 {
+		return fmt.Errorf("手续费 %s wei/gas 超过上限 %v gwei，已拒绝发送", feePerGas, ceilingGwei)
+	}
+	return nil
+}
+
+// EstimateGasLimit 按方法与参数估算 gas，并乘以安全系数（gas_multipliers 中按方法名覆盖，默认 gas_multiplier）
+func EstimateGasLimit(ctx context.Context, from common.Address, method string, args ...interface{}) (uint64, error) {
+	data, err := CreditContractABI.Pack(method, args...)
+	if err != nil {
+		return 0, fmt.Errorf("编码%s参数失败: %v", method, err)
+	}
+	to := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	gas, err := EthClient.EstimateGas(ctx, ethereum.CallMsg{From: from, To: &to, Data: data})
+	if err != nil {
+		return 0, fmt.Errorf("估算%s的gas失败: %v", method, err)
+	}
+	multiplier := GlobalConfig.Ethereum.GasMultiplier
+	// viper 会把 map 的键转为小写
+	if m, ok := GlobalConfig.Ethereum.GasMultipliers[strings.ToLower(method)]; ok && m > 0 {
+		multiplier = m
+	}
+	if multiplier < 1 {
+		multiplier = 1.2
+	}
+	return uint64(float64(gas) * multiplier), nil
+}
+
+func gweiToWei(gwei float64) *big.Int {
+	wei, _ := new(big.Float).Mul(big.NewFloat(gwei), big.NewFloat(1e9)).Int(nil)
+	return wei
+}
@@ -2,44 +2,77 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// jwtIssuer 本服务签发的 Token 的 iss，其他校园服务验证时应一并校验
+const jwtIssuer = "campus-credit"
+
 // CustomClaims JWT自定义声明
 type CustomClaims struct {
 	UserId   uint64 `json:"user_id"`
 	Username string `json:"username"`
 	Role     string `json:"role"`
+	Version  int    `json:"ver"` // 签发时的 users.token_version，改角色/改密码/全部登出后递增使旧 Token 失效
 	jwt.RegisteredClaims
 }
 
-// GenerateToken 生成JWT Token
-func GenerateToken(userId uint64, username, role string) (string, error) {
+// AccessTokenTTL 访问 Token 有效期（jwt.access_expire_minutes，默认 15 分钟）
+func AccessTokenTTL() time.Duration {
+	if m := GlobalConfig.JWT.AccessExpireMinutes; m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return 15 * time.Minute
+}
+
+// RefreshTokenTTL 刷新 Token 有效期（jwt.refresh_expire_hours，未配置时沿用 expire_hours，默认 7 天）
+func RefreshTokenTTL() time.Duration {
+	if h := GlobalConfig.JWT.RefreshExpireHours; h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	if h := GlobalConfig.JWT.ExpireHours; h > 0 {
+		return time.Duration(h) * time.Hour
+	}
+	return 7 * 24 * time.Hour
+}
+
+// GenerateToken 生成短期访问 JWT Token
+func GenerateToken(userId uint64, username, role string, version int) (string, error) {
 	claims := CustomClaims{
 		UserId:   userId,
 		Username: username,
 		Role:     role,
+		Version:  version,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(GlobalConfig.JWT.ExpireHours))),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "campus-credit",
+			Issuer:    jwtIssuer,
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(GlobalConfig.JWT.Secret))
+	if jwtSigningKey == nil {
+		return "", errors.New("JWT签名密钥未初始化")
+	}
+	token := jwt.NewWithClaims(jwtSigningKey.Method, claims)
+	token.Header["kid"] = jwtSigningKey.Kid
+	return token.SignedString(jwtSigningKey.Private)
 }
 
-// ParseToken 解析JWT Token
+// ParseToken 解析JWT Token（仅接受 RS256/EdDSA，按 kid 选公钥，校验签发者与过期时间）
 func ParseToken(tokenStr string) (*CustomClaims, error) {
 	token, err := jwt.ParseWithClaims(
 		tokenStr,
 		&CustomClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(GlobalConfig.JWT.Secret), nil
-		},
+		jwtKeyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(jwtIssuer),
+		jwt.WithExpirationRequired(),
 	)
 	if err != nil {
 		return nil, err
@@ -50,3 +83,19 @@ func ParseToken(tokenStr string) (*CustomClaims, error) {
 	}
 	return claims, nil
 }
+
+// NewRefreshToken 生成随机刷新 Token，返回原文（只下发给客户端）与其 SHA-256（入库）
+func NewRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	raw := base64.RawURLEncoding.EncodeToString(buf)
+	return raw, HashRefreshToken(raw), nil
+}
+
+// HashRefreshToken 刷新 Token 的入库摘要
+func HashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
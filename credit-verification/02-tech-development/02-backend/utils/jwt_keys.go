@@ -0,0 +1,182 @@
+// utils/jwt_keys.go JWT 非对称签名密钥：从目录加载多把密钥（按 kid 轮换），导出 JWKS
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey 一把签名/验签密钥；Private 为空表示仅用于验证（已退役的签名密钥）
+type JWTKey struct {
+	Kid     string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// JWK JSON Web Key（RFC 7517）公钥表示
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`   // RSA 模数
+	E   string `json:"e,omitempty"`   // RSA 公钥指数
+	Crv string `json:"crv,omitempty"` // OKP 曲线
+	X   string `json:"x,omitempty"`   // Ed25519 公钥
+}
+
+var (
+	jwtKeys       = map[string]*JWTKey{}
+	jwtSigningKey *JWTKey
+)
+
+// InitJWTKeys 从 jwt.key_dir 加载密钥：<kid>.pem 为私钥（RSA 或 Ed25519，可签名），<kid>.pub.pem 为仅验证的公钥；
+// jwt.active_kid 指定签名用的私钥。未配置目录时生成临时 Ed25519 密钥（重启后已签发 Token 全部失效，仅供开发）
+func InitJWTKeys() {
+	dir := GlobalConfig.JWT.KeyDir
+	if dir == "" {
+		key, err := newEphemeralJWTKey()
+		if err != nil {
+			log.Fatalf("生成临时JWT密钥失败: %v", err)
+		}
+		jwtKeys = map[string]*JWTKey{key.Kid: key}
+		jwtSigningKey = key
+		log.Printf("未配置 jwt.key_dir，使用临时 Ed25519 密钥（kid=%s），重启后需重新登录", key.Kid)
+		return
+	}
+
+	keys, err := LoadJWTKeys(dir)
+	if err != nil {
+		log.Fatalf("加载JWT密钥失败: %v", err)
+	}
+	active := keys[GlobalConfig.JWT.ActiveKid]
+	if active == nil || active.Private == nil {
+		log.Fatalf("jwt.active_kid=%q 在 %s 中没有对应的私钥文件", GlobalConfig.JWT.ActiveKid, dir)
+	}
+	jwtKeys = keys
+	jwtSigningKey = active
+	log.Printf("JWT密钥加载完成：签名 kid=%s（%s），共 %d 把验证密钥", active.Kid, active.Method.Alg(), len(keys))
+}
+
+// LoadJWTKeys 读取目录下全部 .pem 密钥
+func LoadJWTKeys(dir string) (map[string]*JWTKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*JWTKey)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := filepath.Base(file)
+		var key *JWTKey
+		if strings.HasSuffix(name, ".pub.pem") {
+			key, err = parseJWTPublicKey(strings.TrimSuffix(name, ".pub.pem"), data)
+		} else {
+			key, err = parseJWTPrivateKey(strings.TrimSuffix(name, ".pem"), data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		// 同一 kid 同时有私钥和公钥文件时以私钥为准
+		if existing := keys[key.Kid]; existing != nil && existing.Private != nil {
+			continue
+		}
+		keys[key.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("目录 %s 下没有 .pem 密钥", dir)
+	}
+	return keys, nil
+}
+
+func parseJWTPrivateKey(kid string, data []byte) (*JWTKey, error) {
+	if rsaKey, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		if rsaKey.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA 密钥长度不足 2048 位")
+		}
+		return &JWTKey{Kid: kid, Method: jwt.SigningMethodRS256, Private: rsaKey, Public: &rsaKey.PublicKey}, nil
+	}
+	edKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("不是 RSA 或 Ed25519 私钥")
+	}
+	signer := edKey.(ed25519.PrivateKey)
+	return &JWTKey{Kid: kid, Method: jwt.SigningMethodEdDSA, Private: signer, Public: signer.Public()}, nil
+}
+
+func parseJWTPublicKey(kid string, data []byte) (*JWTKey, error) {
+	if rsaPub, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &JWTKey{Kid: kid, Method: jwt.SigningMethodRS256, Public: rsaPub}, nil
+	}
+	edPub, err := jwt.ParseEdPublicKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("不是 RSA 或 Ed25519 公钥")
+	}
+	return &JWTKey{Kid: kid, Method: jwt.SigningMethodEdDSA, Public: edPub}, nil
+}
+
+func newEphemeralJWTKey() (*JWTKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	kid := "ephemeral-" + base64.RawURLEncoding.EncodeToString(pub[:6])
+	return &JWTKey{Kid: kid, Method: jwt.SigningMethodEdDSA, Private: priv, Public: pub}, nil
+}
+
+// jwtKeyFunc 按 kid 选验证密钥，并要求 Token 的 alg 与该密钥的算法一致
+func jwtKeyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key := jwtKeys[kid]
+	if key == nil {
+		return nil, fmt.Errorf("未知的密钥 kid: %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("算法 %s 与密钥 %s 不匹配", token.Method.Alg(), kid)
+	}
+	return key.Public, nil
+}
+
+// JWKS 全部验证公钥（按 kid 排序）
+func JWKS() []JWK {
+	kids := make([]string, 0, len(jwtKeys))
+	for kid := range jwtKeys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	list := make([]JWK, 0, len(kids))
+	for _, kid := range kids {
+		key := jwtKeys[kid]
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		list = append(list, jwk)
+	}
+	return list
+}
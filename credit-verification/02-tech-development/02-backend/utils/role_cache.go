@@ -0,0 +1,176 @@
+// utils/role_cache.go 链上角色缓存：地址归一化为 common.Address 作键，LRU 容量上限 + TTL 过期，带命中统计
+// 只缓存成功读到的链上角色（交易回执成功后或回源读取），RoleAssigned / RoleRevoked 事件使对应地址失效
+package utils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const (
+	defaultRoleCacheTTL  = 5 * time.Minute
+	defaultRoleCacheSize = 10000
+)
+
+// RoleCache 并发安全的 LRU + TTL 角色缓存
+type RoleCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[common.Address]*list.Element
+	lru     *list.List // 队首最近使用
+	now     func() time.Time
+
+	hits          uint64
+	misses        uint64
+	evictions     uint64
+	expirations   uint64
+	invalidations uint64
+}
+
+type roleCacheEntry struct {
+	addr      common.Address
+	role      string
+	expiresAt time.Time
+}
+
+// RoleCacheStats 缓存统计
+type RoleCacheStats struct {
+	Entries       int     `json:"entries"`
+	MaxEntries    int     `json:"max_entries"`
+	TTLSeconds    int64   `json:"ttl_seconds"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRate       float64 `json:"hit_rate"`
+	Evictions     uint64  `json:"evictions"`     // 超出容量淘汰
+	Expirations   uint64  `json:"expirations"`   // 读取时已过期
+	Invalidations uint64  `json:"invalidations"` // 事件或撤销触发的失效
+}
+
+// DefaultRoleCache 全局角色缓存，InitRoleCache 按配置重建
+var DefaultRoleCache = NewRoleCache(defaultRoleCacheTTL, defaultRoleCacheSize)
+
+// InitRoleCache 按配置 role_cache.ttl_seconds / max_entries 创建全局角色缓存（未配置用默认 5 分钟、10000 条）
+func InitRoleCache() {
+	ttl := defaultRoleCacheTTL
+	if s := GlobalConfig.RoleCache.TtlSeconds; s > 0 {
+		ttl = time.Duration(s) * time.Second
+	}
+	size := defaultRoleCacheSize
+	if n := GlobalConfig.RoleCache.MaxEntries; n > 0 {
+		size = n
+	}
+	DefaultRoleCache = NewRoleCache(ttl, size)
+}
+
+// NewRoleCache 创建角色缓存
+func NewRoleCache(ttl time.Duration, size int) *RoleCache {
+	if size <= 0 {
+		size = defaultRoleCacheSize
+	}
+	return &RoleCache{
+		ttl:     ttl,
+		size:    size,
+		entries: make(map[common.Address]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+// roleCacheKey 地址归一化：大小写/校验和写法得到同一键；非法地址返回 false
+func roleCacheKey(address string) (common.Address, bool) {
+	if !common.IsHexAddress(address) {
+		return common.Address{}, false
+	}
+	return common.HexToAddress(address), true
+}
+
+// Get 读取未过期的角色
+func (rc *RoleCache) Get(address string) (string, bool) {
+	key, ok := roleCacheKey(address)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if !ok {
+		rc.misses++
+		return "", false
+	}
+	el, ok := rc.entries[key]
+	if !ok {
+		rc.misses++
+		return "", false
+	}
+	entry := el.Value.(*roleCacheEntry)
+	if rc.ttl > 0 && !rc.now().Before(entry.expiresAt) {
+		rc.removeElement(el)
+		rc.expirations++
+		rc.misses++
+		return "", false
+	}
+	rc.lru.MoveToFront(el)
+	rc.hits++
+	return entry.role, true
+}
+
+// Set 写入角色并刷新过期时间，超出容量时淘汰最久未使用的条目
+func (rc *RoleCache) Set(address, role string) {
+	key, ok := roleCacheKey(address)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	expiresAt := rc.now().Add(rc.ttl)
+	if el, ok := rc.entries[key]; ok {
+		entry := el.Value.(*roleCacheEntry)
+		entry.role, entry.expiresAt = role, expiresAt
+		rc.lru.MoveToFront(el)
+		return
+	}
+	rc.entries[key] = rc.lru.PushFront(&roleCacheEntry{addr: key, role: role, expiresAt: expiresAt})
+	for rc.lru.Len() > rc.size {
+		rc.removeElement(rc.lru.Back())
+		rc.evictions++
+	}
+}
+
+// Invalidate 删除地址对应条目
+func (rc *RoleCache) Invalidate(address string) {
+	key, ok := roleCacheKey(address)
+	if !ok {
+		return
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if el, ok := rc.entries[key]; ok {
+		rc.removeElement(el)
+		rc.invalidations++
+	}
+}
+
+// Stats 当前统计
+func (rc *RoleCache) Stats() RoleCacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	stats := RoleCacheStats{
+		Entries:       rc.lru.Len(),
+		MaxEntries:    rc.size,
+		TTLSeconds:    int64(rc.ttl / time.Second),
+		Hits:          rc.hits,
+		Misses:        rc.misses,
+		Evictions:     rc.evictions,
+		Expirations:   rc.expirations,
+		Invalidations: rc.invalidations,
+	}
+	if total := rc.hits + rc.misses; total > 0 {
+		stats.HitRate = float64(rc.hits) / float64(total)
+	}
+	return stats
+}
+
+func (rc *RoleCache) removeElement(el *list.Element) {
+	rc.lru.Remove(el)
+	delete(rc.entries, el.Value.(*roleCacheEntry).addr)
+}
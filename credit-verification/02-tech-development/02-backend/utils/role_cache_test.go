@@ -0,0 +1,205 @@
+// utils/role_cache_test.go 链上角色缓存测试：注入 now 控制 TTL，覆盖 LRU 淘汰、地址归一化与失效统计
+package utils
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testCacheAddr 校验和写法的测试地址
+const testCacheAddr = "0x5FbDB2315678afecb367f032d93F642f64180aa3"
+
+// fakeClock 可手动推进的时钟，替换 RoleCache.now
+type fakeClock struct {
+	t time.Time
+}
+
+func (fc *fakeClock) Now() time.Time { return fc.t }
+
+func (fc *fakeClock) Advance(d time.Duration) { fc.t = fc.t.Add(d) }
+
+func newTestRoleCache(ttl time.Duration, size int) (*RoleCache, *fakeClock) {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	rc := NewRoleCache(ttl, size)
+	rc.now = clock.Now
+	return rc, clock
+}
+
+// cacheAddr 第 i 个测试地址（校验和写法）
+func cacheAddr(i int) string {
+	return common.BigToAddress(big.NewInt(int64(i + 1))).Hex()
+}
+
+func TestRoleCacheTTL(t *testing.T) {
+	const ttl = time.Minute
+	cases := []struct {
+		name        string
+		ttl         time.Duration
+		advance     time.Duration
+		refreshAt   time.Duration // >0 时在该时刻重新写入，过期时间从此刻重算
+		wantHit     bool
+		wantExpired uint64
+	}{
+		{"未到期命中", ttl, ttl - time.Nanosecond, 0, true, 0},
+		{"恰好到期失效", ttl, ttl, 0, false, 1},
+		{"超过到期失效", ttl, 2 * ttl, 0, false, 1},
+		{"重新写入刷新过期时间", ttl, ttl + time.Second, 30 * time.Second, true, 0},
+		{"ttl 为 0 不过期", 0, 24 * time.Hour, 0, true, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, clock := newTestRoleCache(tc.ttl, 10)
+			rc.Set(testCacheAddr, "teacher")
+			if tc.refreshAt > 0 {
+				clock.Advance(tc.refreshAt)
+				rc.Set(testCacheAddr, "teacher")
+				clock.Advance(tc.advance - tc.refreshAt)
+			} else {
+				clock.Advance(tc.advance)
+			}
+			role, ok := rc.Get(testCacheAddr)
+			if ok != tc.wantHit || (ok && role != "teacher") {
+				t.Fatalf("Get = (%q, %v)，期望命中 %v", role, ok, tc.wantHit)
+			}
+			stats := rc.Stats()
+			if stats.Expirations != tc.wantExpired {
+				t.Fatalf("expirations = %d，期望 %d", stats.Expirations, tc.wantExpired)
+			}
+			if !tc.wantHit && stats.Entries != 0 {
+				t.Fatalf("过期条目应在读取时移除，剩余 %d 条", stats.Entries)
+			}
+		})
+	}
+}
+
+func TestRoleCacheEviction(t *testing.T) {
+	cases := []struct {
+		name          string
+		size          int
+		ops           func(rc *RoleCache) // 写满 size 条后、再写入一条前的操作
+		wantEvicted   []int               // 被淘汰的地址序号
+		wantEvictions uint64
+	}{
+		{"淘汰最早写入", 2, func(rc *RoleCache) {}, []int{0}, 1},
+		{"读取后淘汰未访问者", 2, func(rc *RoleCache) { rc.Get(cacheAddr(0)) }, []int{1}, 1},
+		{"覆盖写入视为访问", 2, func(rc *RoleCache) { rc.Set(cacheAddr(0), "admin") }, []int{1}, 1},
+		{"容量 1 只保留最新", 1, func(rc *RoleCache) {}, []int{0}, 1},
+		{"失效腾出空间不淘汰", 2, func(rc *RoleCache) { rc.Invalidate(cacheAddr(1)) }, nil, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, _ := newTestRoleCache(time.Hour, tc.size)
+			for i := 0; i < tc.size; i++ {
+				rc.Set(cacheAddr(i), "student")
+			}
+			tc.ops(rc)
+			newest := cacheAddr(tc.size)
+			rc.Set(newest, "student")
+
+			stats := rc.Stats()
+			if stats.Evictions != tc.wantEvictions {
+				t.Fatalf("evictions = %d，期望 %d", stats.Evictions, tc.wantEvictions)
+			}
+			if stats.Entries > tc.size {
+				t.Fatalf("条目数 %d 超过容量 %d", stats.Entries, tc.size)
+			}
+			if _, ok := rc.Get(newest); !ok {
+				t.Fatal("最新写入的条目不应被淘汰")
+			}
+			for _, i := range tc.wantEvicted {
+				if _, ok := rc.Get(cacheAddr(i)); ok {
+					t.Fatalf("地址 %d 应已被淘汰", i)
+				}
+			}
+		})
+	}
+}
+
+func TestRoleCacheKeyNormalization(t *testing.T) {
+	cases := []struct {
+		name    string
+		address string
+		wantHit bool
+	}{
+		{"校验和写法", testCacheAddr, true},
+		{"全小写", strings.ToLower(testCacheAddr), true},
+		{"全大写十六进制", "0x" + strings.ToUpper(testCacheAddr[2:]), true},
+		{"无 0x 前缀", strings.ToLower(testCacheAddr[2:]), true},
+		{"其他地址", cacheAddr(0), false},
+		{"非法地址", "not-an-address", false},
+		{"长度不足", testCacheAddr[:40], false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, _ := newTestRoleCache(time.Hour, 10)
+			rc.Set(strings.ToLower(testCacheAddr), "admin")
+			role, ok := rc.Get(tc.address)
+			if ok != tc.wantHit || (ok && role != "admin") {
+				t.Fatalf("Get(%q) = (%q, %v)，期望命中 %v", tc.address, role, ok, tc.wantHit)
+			}
+			if stats := rc.Stats(); stats.Entries != 1 {
+				t.Fatalf("不同写法应归一为同一键，条目数 %d", stats.Entries)
+			}
+		})
+	}
+
+	rc, _ := newTestRoleCache(time.Hour, 10)
+	rc.Set("not-an-address", "admin")
+	if stats := rc.Stats(); stats.Entries != 0 {
+		t.Fatalf("非法地址不应写入，条目数 %d", stats.Entries)
+	}
+}
+
+func TestRoleCacheInvalidation(t *testing.T) {
+	cases := []struct {
+		name              string
+		address           string
+		wantInvalidations uint64
+		wantRemoved       bool
+	}{
+		{"校验和写法失效", testCacheAddr, 1, true},
+		{"小写写法失效校验和写入的条目", strings.ToLower(testCacheAddr), 1, true},
+		{"未缓存地址不计数", cacheAddr(0), 0, false},
+		{"非法地址不计数", "not-an-address", 0, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rc, _ := newTestRoleCache(time.Hour, 10)
+			rc.Set(testCacheAddr, "teacher")
+			rc.Invalidate(tc.address)
+			rc.Invalidate(tc.address) // 重复失效不重复计数
+
+			stats := rc.Stats()
+			if stats.Invalidations != tc.wantInvalidations {
+				t.Fatalf("invalidations = %d，期望 %d", stats.Invalidations, tc.wantInvalidations)
+			}
+			if _, ok := rc.Get(testCacheAddr); ok == tc.wantRemoved {
+				t.Fatalf("失效后命中 = %v，期望已删除 %v", ok, tc.wantRemoved)
+			}
+		})
+	}
+}
+
+func TestRoleCacheStats(t *testing.T) {
+	rc, clock := newTestRoleCache(time.Minute, 10)
+	rc.Set(testCacheAddr, "teacher")
+	rc.Get(testCacheAddr)                  // 命中
+	rc.Get(strings.ToLower(testCacheAddr)) // 命中
+	rc.Get(cacheAddr(0))                   // 未缓存
+	rc.Get("not-an-address")               // 非法地址
+	clock.Advance(time.Minute)
+	rc.Get(testCacheAddr) // 过期
+
+	stats := rc.Stats()
+	want := RoleCacheStats{
+		Entries: 0, MaxEntries: 10, TTLSeconds: 60,
+		Hits: 2, Misses: 3, HitRate: 0.4, Expirations: 1,
+	}
+	if stats != want {
+		t.Fatalf("统计 = %+v，期望 %+v", stats, want)
+	}
+}
@@ -0,0 +1,198 @@
+// utils/signer.go 后端交易签名方式：私钥 / keystore 文件 / 远程签名器（Clef 风格 JSON-RPC）/ 钱包模式（后端不签名）
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// 签名方式（ethereum.signer.type）
+const (
+	SignerPrivateKey = "private_key" // 配置中的明文十六进制私钥（默认）
+	SignerKeystore   = "keystore"    // go-ethereum 加密 keystore JSON，密码从环境变量读取
+	SignerRemote     = "remote"      // 外部 Clef 风格签名服务（account_signTransaction）
+	SignerWallet     = "wallet"      // 后端只构造未签名交易，由教师/管理员在自己的钱包签名后提交
+)
+
+// ErrWalletSignerMode 钱包模式下后端不持有私钥，无法代发交易
+var ErrWalletSignerMode = errors.New("当前为钱包签名模式，请在钱包中签名后提交交易")
+
+// Signer 交易签名器
+type Signer interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// BackendSigner 后端签名器（钱包模式下为 nil）
+var BackendSigner Signer
+
+// InitSigner 按配置初始化后端签名器
+func InitSigner() {
+	cfg := GlobalConfig.Ethereum.Signer
+	signer, err := NewSigner(cfg.Type)
+	if err != nil {
+		log.Fatalf("初始化签名器失败: %v", err)
+	}
+	BackendSigner = signer
+	if signer == nil {
+		log.Println("签名模式: wallet（后端不代签交易）")
+		return
+	}
+	log.Printf("签名模式: %s，签名地址: %s", signerType(cfg.Type), signer.Address().Hex())
+}
+
+// IsWalletSignerMode 是否为钱包签名模式
+func IsWalletSignerMode() bool {
+	return signerType(GlobalConfig.Ethereum.Signer.Type) == SignerWallet
+}
+
+func signerType(t string) string {
+	t = strings.ToLower(strings.TrimSpace(t))
+	if t == "" {
+		return SignerPrivateKey
+	}
+	return t
+}
+
+// NewSigner 按类型创建签名器；钱包模式返回 nil
+func NewSigner(t string) (Signer, error) {
+	cfg := GlobalConfig.Ethereum.Signer
+	switch signerType(t) {
+	case SignerPrivateKey:
+		key, err := crypto.HexToECDSA(strings.TrimPrefix(GlobalConfig.Ethereum.PrivateKey, "0x"))
+		if err != nil {
+			return nil, fmt.Errorf("解析私钥失败: %v", err)
+		}
+		return &keySigner{key: key}, nil
+	case SignerKeystore:
+		return newKeystoreSigner(cfg.KeystoreFile, cfg.PasswordEnv)
+	case SignerRemote:
+		return newRemoteSigner(cfg.RemoteUrl, cfg.RemoteAddress)
+	case SignerWallet:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("未知的签名方式: %s（可选 private_key/keystore/remote/wallet）", t)
+}
+
+// keySigner 本地私钥签名（私钥配置与 keystore 解密后共用）
+type keySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *keySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+func (s *keySigner) SignTx(_ context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), s.key)
+}
+
+// newKeystoreSigner 解密 keystore JSON；密码取自 passwordEnv 指定的环境变量（默认 CREDIT_KEYSTORE_PASSWORD）
+func newKeystoreSigner(file, passwordEnv string) (Signer, error) {
+	if file == "" {
+		return nil, fmt.Errorf("未配置 keystore_file")
+	}
+	if passwordEnv == "" {
+		passwordEnv = "CREDIT_KEYSTORE_PASSWORD"
+	}
+	keyJson, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取keystore失败: %v", err)
+	}
+	key, err := keystore.DecryptKey(keyJson, os.Getenv(passwordEnv))
+	if err != nil {
+		return nil, fmt.Errorf("解密keystore失败（检查环境变量 %s）: %v", passwordEnv, err)
+	}
+	return &keySigner{key: key.PrivateKey}, nil
+}
+
+// remoteSigner 通过 JSON-RPC 调用外部签名服务的 account_signTransaction（Clef 接口）
+type remoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+}
+
+func newRemoteSigner(url, address string) (Signer, error) {
+	if url == "" {
+		return nil, fmt.Errorf("未配置 remote_url")
+	}
+	if !common.IsHexAddress(address) {
+		return nil, fmt.Errorf("remote_address 无效: %s", address)
+	}
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("连接远程签名器失败: %v", err)
+	}
+	return &remoteSigner{client: client, address: common.HexToAddress(address)}, nil
+}
+
+func (s *remoteSigner) Address() common.Address {
+	return s.address
+}
+
+// signTxResult account_signTransaction 返回值
+type signTxResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
+
+func (s *remoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := map[string]interface{}{
+		"from":    s.address.Hex(),
+		"gas":     hexutil.Uint64(tx.Gas()),
+		"value":   (*hexutil.Big)(tx.Value()),
+		"nonce":   hexutil.Uint64(tx.Nonce()),
+		"input":   hexutil.Bytes(tx.Data()),
+		"chainId": (*hexutil.Big)(chainID),
+	}
+	if tx.To() != nil {
+		args["to"] = tx.To().Hex()
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	}
+
+	// 远程签名器可能需要人工确认，给足超时
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	var res signTxResult
+	if err := s.client.CallContext(ctx, &res, "account_signTransaction", args); err != nil {
+		return nil, fmt.Errorf("远程签名失败: %v", err)
+	}
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(res.Raw); err != nil {
+		return nil, fmt.Errorf("解析远程签名结果失败: %v", err)
+	}
+	if signed.Nonce() != tx.Nonce() || !sameRecipient(signed, tx) || !bytes.Equal(signed.Data(), tx.Data()) {
+		return nil, fmt.Errorf("远程签名结果与待签交易不一致")
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil || sender != s.address {
+		return nil, fmt.Errorf("远程签名地址与配置的 remote_address 不一致")
+	}
+	return signed, nil
+}
+
+func sameRecipient(a, b *types.Transaction) bool {
+	if a.To() == nil || b.To() == nil {
+		return a.To() == b.To()
+	}
+	return *a.To() == *b.To()
+}
@@ -0,0 +1,161 @@
+// utils/signer_test.go 远程签名器测试：httptest 模拟 Clef 的 account_signTransaction
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// clefArgs account_signTransaction 的交易参数（只解析测试用到的字段）
+type clefArgs struct {
+	From                 common.Address  `json:"from"`
+	To                   *common.Address `json:"to"`
+	Gas                  hexutil.Uint64  `json:"gas"`
+	MaxFeePerGas         *hexutil.Big    `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big    `json:"maxPriorityFeePerGas"`
+	Value                *hexutil.Big    `json:"value"`
+	Nonce                hexutil.Uint64  `json:"nonce"`
+	Input                hexutil.Bytes   `json:"input"`
+	ChainId              *hexutil.Big    `json:"chainId"`
+}
+
+// clefStub 模拟 Clef：用 key 按请求参数签名；tamper 非空时在签名前篡改交易，reject 为 true 时返回 JSON-RPC 错误
+type clefStub struct {
+	key    *ecdsa.PrivateKey
+	tamper func(*types.DynamicFeeTx)
+	reject bool
+	method string // 最近一次请求的方法名
+}
+
+func (s *clefStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Id     json.RawMessage `json:"id"`
+		Method string          `json:"method"`
+		Params []clefArgs      `json:"params"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Params) != 1 {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	s.method = req.Method
+	resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.Id}
+	if s.reject {
+		resp["error"] = map[string]interface{}{"code": -32000, "message": "Request denied"}
+	} else {
+		args := req.Params[0]
+		inner := &types.DynamicFeeTx{
+			ChainID:   args.ChainId.ToInt(),
+			Nonce:     uint64(args.Nonce),
+			GasTipCap: args.MaxPriorityFeePerGas.ToInt(),
+			GasFeeCap: args.MaxFeePerGas.ToInt(),
+			Gas:       uint64(args.Gas),
+			To:        args.To,
+			Value:     args.Value.ToInt(),
+			Data:      args.Input,
+		}
+		if s.tamper != nil {
+			s.tamper(inner)
+		}
+		signed, err := types.SignNewTx(s.key, types.LatestSignerForChainID(inner.ChainID), inner)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		raw, _ := signed.MarshalBinary()
+		resp["result"] = map[string]interface{}{"raw": hexutil.Bytes(raw), "tx": signed}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// newTestRemoteSigner 启动 Clef 桩并创建指向它的远程签名器，配置地址为 key 对应地址
+func newTestRemoteSigner(t *testing.T, stub *clefStub, key *ecdsa.PrivateKey) Signer {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+	signer, err := newRemoteSigner(server.URL, crypto.PubkeyToAddress(key.PublicKey).Hex())
+	if err != nil {
+		t.Fatalf("创建远程签名器失败: %v", err)
+	}
+	return signer
+}
+
+func testUnsignedTx() *types.Transaction {
+	to := common.HexToAddress("0x5FbDB2315678afecb367f032d93F642f64180aa3")
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(1337),
+		Nonce:     7,
+		GasTipCap: big.NewInt(1_000_000_000),
+		GasFeeCap: big.NewInt(2_000_000_000),
+		Gas:       200000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0xde, 0xad, 0xbe, 0xef},
+	})
+}
+
+func TestRemoteSignerSignTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	stub := &clefStub{key: key}
+	signer := newTestRemoteSigner(t, stub, key)
+
+	tx := testUnsignedTx()
+	signed, err := signer.SignTx(context.Background(), tx, big.NewInt(1337))
+	if err != nil {
+		t.Fatalf("签名失败: %v", err)
+	}
+	if stub.method != "account_signTransaction" {
+		t.Fatalf("调用方法不符: %s", stub.method)
+	}
+	if signed.Hash() == tx.Hash() || signed.Nonce() != tx.Nonce() || *signed.To() != *tx.To() {
+		t.Fatalf("签名交易不符: %+v", signed)
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(big.NewInt(1337)), signed)
+	if err != nil || sender != signer.Address() {
+		t.Fatalf("签名地址不符: %s, %v", sender.Hex(), err)
+	}
+}
+
+func TestRemoteSignerErrors(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	other, _ := crypto.GenerateKey()
+	cases := []struct {
+		name string
+		stub *clefStub
+		want string
+	}{
+		{"签名器拒绝", &clefStub{key: key, reject: true}, "远程签名失败"},
+		{"篡改 nonce", &clefStub{key: key, tamper: func(tx *types.DynamicFeeTx) { tx.Nonce++ }}, "不一致"},
+		{"篡改调用数据", &clefStub{key: key, tamper: func(tx *types.DynamicFeeTx) { tx.Data = []byte{0x01} }}, "不一致"},
+		{"签名地址不符", &clefStub{key: other}, "remote_address 不一致"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			signer := newTestRemoteSigner(t, tc.stub, key)
+			_, err := signer.SignTx(context.Background(), testUnsignedTx(), big.NewInt(1337))
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("期望包含 %q 的错误，得到 %v", tc.want, err)
+			}
+		})
+	}
+}
+
+func TestNewRemoteSignerConfig(t *testing.T) {
+	if _, err := newRemoteSigner("", "0x5FbDB2315678afecb367f032d93F642f64180aa3"); err == nil {
+		t.Fatal("未配置 remote_url 应报错")
+	}
+	if _, err := newRemoteSigner("http://127.0.0.1:8550", "not-an-address"); err == nil {
+		t.Fatal("remote_address 无效应报错")
+	}
+}
@@ -0,0 +1,189 @@
+// utils/siwe.go Sign-In with Ethereum（EIP-4361）消息构造、解析与签名校验
+package utils
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+// SiweMessage EIP-4361 消息字段
+type SiweMessage struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	Uri            string
+	Version        string
+	ChainId        int64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestId      string
+	Resources      []string
+}
+
+// String 按 EIP-4361 格式输出待签名文本（地址使用 EIP-55 校验和格式）
+func (m *SiweMessage) String() string {
+	var b strings.Builder
+	b.WriteString(m.Domain + siweHeaderSuffix + "\n")
+	b.WriteString(m.Address.Hex() + "\n\n")
+	if m.Statement != "" {
+		b.WriteString(m.Statement + "\n\n")
+	}
+	b.WriteString("URI: " + m.Uri + "\n")
+	b.WriteString("Version: " + m.Version + "\n")
+	b.WriteString(fmt.Sprintf("Chain ID: %d\n", m.ChainId))
+	b.WriteString("Nonce: " + m.Nonce + "\n")
+	b.WriteString("Issued At: " + m.IssuedAt.UTC().Format(time.RFC3339))
+	if m.ExpirationTime != nil {
+		b.WriteString("\nExpiration Time: " + m.ExpirationTime.UTC().Format(time.RFC3339))
+	}
+	if m.NotBefore != nil {
+		b.WriteString("\nNot Before: " + m.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if m.RequestId != "" {
+		b.WriteString("\nRequest ID: " + m.RequestId)
+	}
+	if len(m.Resources) > 0 {
+		b.WriteString("\nResources:")
+		for _, r := range m.Resources {
+			b.WriteString("\n- " + r)
+		}
+	}
+	return b.String()
+}
+
+// ParseSiweMessage 解析 EIP-4361 文本
+func ParseSiweMessage(text string) (*SiweMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if len(lines) < 7 || !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, fmt.Errorf("不是有效的 SIWE 消息")
+	}
+	m := &SiweMessage{Domain: strings.TrimSuffix(lines[0], siweHeaderSuffix)}
+	if !common.IsHexAddress(lines[1]) || !strings.HasPrefix(lines[1], "0x") {
+		return nil, fmt.Errorf("SIWE 消息地址无效")
+	}
+	m.Address = common.HexToAddress(lines[1])
+	if lines[2] != "" {
+		return nil, fmt.Errorf("SIWE 消息格式错误：地址后应为空行")
+	}
+	i := 3
+	// 可选的 statement 段（后跟空行）
+	if !strings.HasPrefix(lines[i], "URI: ") {
+		m.Statement = lines[i]
+		i++
+		if i >= len(lines) || lines[i] != "" {
+			return nil, fmt.Errorf("SIWE 消息格式错误：statement 后应为空行")
+		}
+		i++
+	}
+
+	var err error
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok && line == "Resources:" {
+			for i++; i < len(lines); i++ {
+				if !strings.HasPrefix(lines[i], "- ") {
+					return nil, fmt.Errorf("SIWE 消息 Resources 格式错误")
+				}
+				m.Resources = append(m.Resources, strings.TrimPrefix(lines[i], "- "))
+			}
+			break
+		}
+		switch key {
+		case "URI":
+			m.Uri = value
+		case "Version":
+			m.Version = value
+		case "Chain ID":
+			if _, err = fmt.Sscanf(value, "%d", &m.ChainId); err != nil {
+				return nil, fmt.Errorf("SIWE 消息 Chain ID 无效")
+			}
+		case "Nonce":
+			m.Nonce = value
+		case "Issued At":
+			m.IssuedAt, err = time.Parse(time.RFC3339, value)
+		case "Expiration Time":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			m.ExpirationTime = &t
+		case "Not Before":
+			var t time.Time
+			t, err = time.Parse(time.RFC3339, value)
+			m.NotBefore = &t
+		case "Request ID":
+			m.RequestId = value
+		default:
+			return nil, fmt.Errorf("SIWE 消息包含未知字段: %s", line)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SIWE 消息时间格式错误（%s）", key)
+		}
+	}
+	if m.Uri == "" || m.Version != "1" || m.ChainId == 0 || len(m.Nonce) < 8 || m.IssuedAt.IsZero() {
+		return nil, fmt.Errorf("SIWE 消息缺少必填字段（URI/Version/Chain ID/Nonce/Issued At）")
+	}
+	return m, nil
+}
+
+// Validate 校验 domain、URI、链ID 与有效期（nonce 由调用方核销）
+func (m *SiweMessage) Validate(domain, uri string, chainId int64, now time.Time) error {
+	if m.Domain != domain {
+		return fmt.Errorf("SIWE 消息 domain 不匹配: %s", m.Domain)
+	}
+	if uri != "" && m.Uri != uri {
+		return fmt.Errorf("SIWE 消息 URI 不匹配: %s", m.Uri)
+	}
+	if m.ChainId != chainId {
+		return fmt.Errorf("SIWE 消息链ID %d 与当前链 %d 不一致", m.ChainId, chainId)
+	}
+	if m.ExpirationTime != nil && !now.Before(*m.ExpirationTime) {
+		return fmt.Errorf("SIWE 消息已过期")
+	}
+	if m.NotBefore != nil && now.Before(*m.NotBefore) {
+		return fmt.Errorf("SIWE 消息尚未生效")
+	}
+	return nil
+}
+
+// RecoverPersonalSign 恢复 personal_sign（EIP-191）签名的地址
+func RecoverPersonalSign(message, signature string) (common.Address, error) {
+	sig, err := hexutil.Decode(strings.TrimSpace(signature))
+	if err != nil || len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("签名格式错误")
+	}
+	// 钱包返回的 v 为 27/28，SigToPub 需要 0/1
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash([]byte(message)), sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("恢复签名地址失败: %v", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// NewSiweNonce 生成随机字母数字 nonce（EIP-4361 要求至少 8 位）
+func NewSiweNonce() (string, error) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+	buf := make([]byte, 16)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = alphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
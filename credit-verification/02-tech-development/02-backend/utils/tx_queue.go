@@ -0,0 +1,382 @@
+// utils/tx_queue.go 后端签名账户的交易队列：进程内串行分配 nonce、记录发出的交易、卡住时提价重发
+package utils
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// 队列中交易的状态
+const (
+	TxStatusPending  = "pending"  // 已广播，等待打包
+	TxStatusMined    = "mined"    // 已打包且执行成功
+	TxStatusReplaced = "replaced" // 因卡住被同 nonce 的提价交易替换
+	TxStatusFailed   = "failed"   // 打包但执行失败（revert），或被替换交易抢先打包
+)
+
+// 队列最多保留的已结束交易条数（pending 不受限）
+const txQueueHistoryLimit = 200
+
+// QueuedTx 队列中的一笔交易
+type QueuedTx struct {
+	Hash        string        `json:"hash"`
+	Method      string        `json:"method"`
+	Args        []interface{} `json:"args"`
+	From        string        `json:"from"`
+	Nonce       uint64        `json:"nonce"`
+	GasLimit    uint64        `json:"gas_limit"`
+	GasPrice    string        `json:"gas_price"` // legacy 为 gasPrice，EIP-1559 为 maxFeePerGas（wei）
+	Status      string        `json:"status"`
+	ReplacedBy  string        `json:"replaced_by,omitempty"`
+	BlockNumber uint64        `json:"block_number,omitempty"`
+	GasUsed     uint64        `json:"gas_used,omitempty"`
+	External    bool          `json:"external"` // 用户钱包签名后提交的交易（后端无法提价重发）
+	Error       string        `json:"error,omitempty"`
+	SubmittedAt time.Time     `json:"submitted_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+
+	tx *types.Transaction
+}
+
+// TxQueue 交易队列（发送串行化 + 状态跟踪）
+type TxQueue struct {
+	sendLock sync.Mutex // 串行化「分配 nonce -> 签名 -> 广播」
+	nonces   map[common.Address]uint64
+
+	mu  sync.RWMutex // 保护 txs
+	txs map[string]*QueuedTx
+
+	observers []TxObserver
+}
+
+// TxObserver 交易状态变更回调（提交、替换、打包），如写入 chain_transactions；在队列锁外调用
+type TxObserver func(tx QueuedTx)
+
+// OnChange 注册状态变更回调，需在发送交易前（启动阶段）注册
+func (q *TxQueue) OnChange(fn TxObserver) {
+	q.observers = append(q.observers, fn)
+}
+
+func (q *TxQueue) notify(entries ...QueuedTx) {
+	for _, e := range entries {
+		for _, fn := range q.observers {
+			fn(e)
+		}
+	}
+}
+
+// DefaultTxQueue 后端全局交易队列
+var DefaultTxQueue = &TxQueue{
+	nonces: make(map[common.Address]uint64),
+	txs:    make(map[string]*QueuedTx),
+}
+
+// SendContractTx 经全局队列调用合约写方法
+func SendContractTx(method string, args ...interface{}) (*types.Transaction, error) {
+	return DefaultTxQueue.Send(context.Background(), method, args...)
+}
+
+// Send 分配 nonce 并发送合约交易；同一时刻只有一个请求在分配/广播，保证 nonce 不重复
+func (q *TxQueue) Send(ctx context.Context, method string, args ...interface{}) (*types.Transaction, error) {
+	if CreditContractInstance == nil {
+		return nil, fmt.Errorf("合约未初始化")
+	}
+	q.sendLock.Lock()
+	defer q.sendLock.Unlock()
+
+	transactOpts, err := GetTransactOpts()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易选项失败: %v", err)
+	}
+	nonce, err := q.nextNonce(ctx, transactOpts.From)
+	if err != nil {
+		return nil, err
+	}
+	gasLimit, err := EstimateGasLimit(ctx, transactOpts.From, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	transactOpts.Nonce = new(big.Int).SetUint64(nonce)
+	transactOpts.GasLimit = gasLimit
+	transactOpts.Context = ctx
+
+	tx, err := CreditContractInstance.Transact(transactOpts, method, args...)
+	if err != nil {
+		// nonce 可能已被外部交易占用，丢弃本地计数，下次重新从链上同步
+		if isNonceError(err) {
+			delete(q.nonces, transactOpts.From)
+		}
+		return nil, fmt.Errorf("调用%s失败: %v", method, err)
+	}
+	q.nonces[transactOpts.From] = nonce + 1
+	q.track(tx, method, args, transactOpts.From, false)
+	return tx, nil
+}
+
+// nextNonce 取本地计数与链上 pending nonce 的较大者（兼容同一账户在后端之外发出的交易）
+func (q *TxQueue) nextNonce(ctx context.Context, from common.Address) (uint64, error) {
+	pending, err := EthClient.PendingNonceAt(ctx, from)
+	if err != nil {
+		return 0, fmt.Errorf("获取Nonce失败: %v", err)
+	}
+	if local, ok := q.nonces[from]; ok && local > pending {
+		return local, nil
+	}
+	return pending, nil
+}
+
+func isNonceError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "nonce too high") ||
+		strings.Contains(msg, "replacement transaction underpriced") || strings.Contains(msg, "already known")
+}
+
+// TrackExternal 登记由用户钱包签名、经后端广播的交易，只跟踪状态不提价重发
+func (q *TxQueue) TrackExternal(tx *types.Transaction, method string, args []interface{}, from common.Address) {
+	q.track(tx, method, args, from, true)
+}
+
+func (q *TxQueue) track(tx *types.Transaction, method string, args []interface{}, from common.Address, external bool) {
+	now := time.Now()
+	entry := &QueuedTx{
+		External:    external,
+		Hash:        tx.Hash().Hex(),
+		Method:      method,
+		Args:        args,
+		From:        from.Hex(),
+		Nonce:       tx.Nonce(),
+		GasLimit:    tx.Gas(),
+		GasPrice:    txFeeCap(tx).String(),
+		Status:      TxStatusPending,
+		SubmittedAt: now,
+		UpdatedAt:   now,
+		tx:          tx,
+	}
+	q.mu.Lock()
+	q.txs[entry.Hash] = entry
+	q.pruneLocked()
+	snapshot := *entry
+	q.mu.Unlock()
+	q.notify(snapshot)
+}
+
+// pruneLocked 超出上限时丢弃最早结束的交易记录
+func (q *TxQueue) pruneLocked() {
+	var done []*QueuedTx
+	for _, e := range q.txs {
+		if e.Status != TxStatusPending {
+			done = append(done, e)
+		}
+	}
+	if len(done) <= txQueueHistoryLimit {
+		return
+	}
+	sort.Slice(done, func(i, j int) bool { return done[i].UpdatedAt.Before(done[j].UpdatedAt) })
+	for _, e := range done[:len(done)-txQueueHistoryLimit] {
+		delete(q.txs, e.Hash)
+	}
+}
+
+// Snapshot 队列当前状态（按提交时间倒序）
+func (q *TxQueue) Snapshot() []QueuedTx {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	list := make([]QueuedTx, 0, len(q.txs))
+	for _, e := range q.txs {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].SubmittedAt.After(list[j].SubmittedAt) })
+	return list
+}
+
+// Get 按哈希查询队列中的交易
+func (q *TxQueue) Get(hash string) (QueuedTx, bool) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	e, ok := q.txs[common.HexToHash(hash).Hex()]
+	if !ok {
+		return QueuedTx{}, false
+	}
+	return *e, true
+}
+
+// WaitMined 等待交易打包；若交易被提价替换则沿 ReplacedBy 继续等待最新的那笔
+func (q *TxQueue) WaitMined(ctx context.Context, hash string) (*types.Receipt, error) {
+	current := common.HexToHash(hash).Hex()
+	for {
+		receipt, err := EthClient.TransactionReceipt(ctx, common.HexToHash(current))
+		if err == nil && receipt != nil {
+			return receipt, nil
+		}
+		if e, ok := q.Get(current); ok && e.ReplacedBy != "" {
+			current = e.ReplacedBy
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待交易打包超时: %s", current)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// RunTxQueue 后台巡检：更新回执状态，对超过 tx_stuck_seconds 仍未打包的交易提价重发
+func RunTxQueue(ctx context.Context) {
+	interval := 3 * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			DefaultTxQueue.check(ctx)
+		}
+	}
+}
+
+func (q *TxQueue) check(ctx context.Context) {
+	stuckAfter := time.Duration(GlobalConfig.Ethereum.TxStuckSeconds) * time.Second
+	if stuckAfter <= 0 {
+		stuckAfter = 60 * time.Second
+	}
+
+	q.mu.RLock()
+	var watching []*QueuedTx
+	for _, e := range q.txs {
+		// replaced 也要查：原交易可能先于替换交易被打包
+		if e.Status == TxStatusPending || e.Status == TxStatusReplaced {
+			watching = append(watching, e)
+		}
+	}
+	q.mu.RUnlock()
+
+	for _, e := range watching {
+		receipt, err := EthClient.TransactionReceipt(ctx, common.HexToHash(e.Hash))
+		if err == nil && receipt != nil {
+			q.markMined(e.Hash, receipt)
+			continue
+		}
+		q.mu.RLock()
+		stuck := e.Status == TxStatusPending && !e.External && time.Since(e.SubmittedAt) > stuckAfter
+		q.mu.RUnlock()
+		if stuck {
+			if err := q.bump(ctx, e); err != nil {
+				log.Printf("[TxQueue] 提价重发 %s 失败: %v", e.Hash, err)
+			}
+		}
+	}
+}
+
+// markMined 根据回执更新状态；同 nonce 的其他交易（原交易或替换交易）随之判定为失败
+func (q *TxQueue) markMined(hash string, receipt *types.Receipt) {
+	q.mu.Lock()
+	e, ok := q.txs[hash]
+	if !ok {
+		q.mu.Unlock()
+		return
+	}
+	now := time.Now()
+	e.BlockNumber = receipt.BlockNumber.Uint64()
+	e.GasUsed = receipt.GasUsed
+	e.UpdatedAt = now
+	if receipt.Status == types.ReceiptStatusSuccessful {
+		e.Status = TxStatusMined
+	} else {
+		e.Status = TxStatusFailed
+		e.Error = "交易执行失败（reverted）"
+	}
+	changed := []QueuedTx{*e}
+	for _, other := range q.txs {
+		if other != e && other.From == e.From && other.Nonce == e.Nonce &&
+			(other.Status == TxStatusPending || other.Status == TxStatusReplaced) {
+			other.Status = TxStatusFailed
+			other.Error = "同 nonce 交易 " + hash + " 已被打包"
+			other.UpdatedAt = now
+			changed = append(changed, *other)
+		}
+	}
+	q.mu.Unlock()
+	q.notify(changed...)
+}
+
+// bump 以相同 nonce、更高手续费重新签名并广播
+func (q *TxQueue) bump(ctx context.Context, e *QueuedTx) error {
+	q.sendLock.Lock()
+	defer q.sendLock.Unlock()
+
+	transactOpts, err := GetTransactOpts()
+	if err != nil {
+		return err
+	}
+	replacement := bumpedTx(e.tx)
+	if err := CheckFeeCeiling(txFeeCap(replacement)); err != nil {
+		return err
+	}
+	newTx, err := transactOpts.Signer(transactOpts.From, replacement)
+	if err != nil {
+		return fmt.Errorf("签名失败: %v", err)
+	}
+	if err := EthClient.SendTransaction(ctx, newTx); err != nil {
+		return fmt.Errorf("广播失败: %v", err)
+	}
+	q.track(newTx, e.Method, e.Args, transactOpts.From, false)
+
+	q.mu.Lock()
+	e.Status = TxStatusReplaced
+	e.ReplacedBy = newTx.Hash().Hex()
+	e.UpdatedAt = time.Now()
+	snapshot := *e
+	q.mu.Unlock()
+	q.notify(snapshot)
+	log.Printf("[TxQueue] %s(nonce=%d) 卡住，已提价重发为 %s", e.Method, e.Nonce, newTx.Hash().Hex())
+	return nil
+}
+
+// bumpedTx 按 fee_bump_percent（至少 10%，节点替换交易的最低要求）提高手续费，其余字段不变
+func bumpedTx(old *types.Transaction) *types.Transaction {
+	percent := GlobalConfig.Ethereum.FeeBumpPercent
+	if percent < 10 {
+		percent = 10
+	}
+	bump := func(v *big.Int) *big.Int {
+		n := new(big.Int).Mul(v, big.NewInt(int64(100+percent)))
+		return n.Div(n, big.NewInt(100))
+	}
+	if old.Type() == types.DynamicFeeTxType {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:   old.ChainId(),
+			Nonce:     old.Nonce(),
+			GasTipCap: bump(old.GasTipCap()),
+			GasFeeCap: bump(old.GasFeeCap()),
+			Gas:       old.Gas(),
+			To:        old.To(),
+			Value:     old.Value(),
+			Data:      old.Data(),
+		})
+	}
+	return types.NewTx(&types.LegacyTx{
+		Nonce:    old.Nonce(),
+		GasPrice: bump(old.GasPrice()),
+		Gas:      old.Gas(),
+		To:       old.To(),
+		Value:    old.Value(),
+		Data:     old.Data(),
+	})
+}
+
+func txFeeCap(tx *types.Transaction) *big.Int {
+	if tx.Type() == types.DynamicFeeTxType {
+		return tx.GasFeeCap()
+	}
+	return tx.GasPrice()
+}
@@ -0,0 +1,144 @@
+// utils/wallet_tx.go 钱包签名模式：后端构造未签名交易，校验并广播用户在钱包中签好的交易
+package utils
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// UnsignedTx 供前端交给钱包（eth_sendTransaction / eth_signTransaction）签名的交易参数
+type UnsignedTx struct {
+	Method               string         `json:"method"`
+	From                 string         `json:"from"`
+	To                   string         `json:"to"`
+	Data                 hexutil.Bytes  `json:"data"`
+	Value                *hexutil.Big   `json:"value"`
+	Gas                  hexutil.Uint64 `json:"gas"`
+	Nonce                hexutil.Uint64 `json:"nonce"`
+	ChainId              *hexutil.Big   `json:"chainId"`
+	GasPrice             *hexutil.Big   `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas,omitempty"`
+}
+
+// SignedContractTx 解码后的已签名合约交易
+type SignedContractTx struct {
+	Tx     *types.Transaction
+	From   common.Address
+	Method string
+	Args   []interface{}
+}
+
+// BuildUnsignedTx 以 from 为发送方构造合约调用交易（gas 估算、nonce、手续费与后端发送时一致）
+func BuildUnsignedTx(ctx context.Context, from common.Address, method string, args ...interface{}) (*UnsignedTx, error) {
+	data, err := CreditContractABI.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("编码%s参数失败: %v", method, err)
+	}
+	gas, err := EstimateGasLimit(ctx, from, method, args...)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := EthClient.PendingNonceAt(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("获取Nonce失败: %v", err)
+	}
+	chainID, err := EthClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	strategy, err := NewFeeStrategy(GlobalConfig.Ethereum.FeeStrategy)
+	if err != nil {
+		return nil, err
+	}
+	fees := &bind.TransactOpts{}
+	if err := strategy.Apply(ctx, fees); err != nil {
+		return nil, err
+	}
+	return &UnsignedTx{
+		Method:               method,
+		From:                 from.Hex(),
+		To:                   GlobalConfig.Ethereum.CreditContractAddr,
+		Data:                 data,
+		Value:                (*hexutil.Big)(big.NewInt(0)),
+		Gas:                  hexutil.Uint64(gas),
+		Nonce:                hexutil.Uint64(nonce),
+		ChainId:              (*hexutil.Big)(chainID),
+		GasPrice:             (*hexutil.Big)(fees.GasPrice),
+		MaxFeePerGas:         (*hexutil.Big)(fees.GasFeeCap),
+		MaxPriorityFeePerGas: (*hexutil.Big)(fees.GasTipCap),
+	}, nil
+}
+
+// DecodeSignedTx 解码钱包签好的原始交易：校验链ID、目标合约，恢复签名地址并解析调用的合约方法与参数
+func DecodeSignedTx(ctx context.Context, rawTx string) (*SignedContractTx, error) {
+	raw, err := hexutil.Decode(strings.TrimSpace(rawTx))
+	if err != nil {
+		return nil, fmt.Errorf("原始交易格式错误: %v", err)
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("解析原始交易失败: %v", err)
+	}
+	chainID, err := EthClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	if tx.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("交易链ID %s 与当前链 %s 不一致", tx.ChainId(), chainID)
+	}
+	return decodeContractTx(tx, chainID)
+}
+
+// GetContractTxByHash 按哈希读取已广播的合约交易并解析（用户在钱包中直接发送后回传哈希的场景）
+func GetContractTxByHash(ctx context.Context, txHash string) (*SignedContractTx, error) {
+	tx, _, err := EthClient.TransactionByHash(ctx, common.HexToHash(txHash))
+	if err != nil {
+		return nil, fmt.Errorf("查询交易失败: %v", err)
+	}
+	chainID, err := EthClient.ChainID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取链ID失败: %v", err)
+	}
+	return decodeContractTx(tx, chainID)
+}
+
+func decodeContractTx(tx *types.Transaction, chainID *big.Int) (*SignedContractTx, error) {
+	contractAddr := common.HexToAddress(GlobalConfig.Ethereum.CreditContractAddr)
+	if tx.To() == nil || *tx.To() != contractAddr {
+		return nil, fmt.Errorf("交易目标不是学分合约")
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		return nil, fmt.Errorf("恢复签名地址失败: %v", err)
+	}
+	data := tx.Data()
+	if len(data) < 4 {
+		return nil, fmt.Errorf("交易不是合约方法调用")
+	}
+	method, err := CreditContractABI.MethodById(data[:4])
+	if err != nil {
+		return nil, fmt.Errorf("无法识别的合约方法: %v", err)
+	}
+	args, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("解析%s参数失败: %v", method.Name, err)
+	}
+	return &SignedContractTx{Tx: tx, From: from, Method: method.Name, Args: args}, nil
+}
+
+// SubmitSignedTx 广播钱包签好的交易，并登记到交易队列（进而写入交易台账）
+func SubmitSignedTx(ctx context.Context, signed *SignedContractTx) error {
+	if err := EthClient.SendTransaction(ctx, signed.Tx); err != nil {
+		return fmt.Errorf("广播交易失败: %v", err)
+	}
+	DefaultTxQueue.TrackExternal(signed.Tx, signed.Method, signed.Args, signed.From)
+	return nil
+}
@@ -1,14 +1,48 @@
 import request from './request'
 
-// 用户登录（钱包地址）
-export const login = (address) => {
+// 钱包登录第一步：获取 SIWE 待签名消息（含一次性 nonce）
+export const getLoginNonce = (address) => {
+  return request({
+    url: '/user/nonce',
+    method: 'get',
+    params: { address }
+  })
+}
+
+// 钱包登录第二步：提交 SIWE 消息与 personal_sign 签名
+export const login = (message, signature) => {
   return request({
     url: '/user/login',
     method: 'post',
-    data: { address }
+    data: { message, signature }
   })
 }
 
+// 登出当前会话（吊销刷新 Token）
+export const logout = (refresh_token) => {
+  return request({ url: '/user/logout', method: 'post', data: { refresh_token } })
+}
+
+// 登出所有会话
+export const logoutAll = () => {
+  return request({ url: '/user/logout-all', method: 'post' })
+}
+
+// 绑定钱包第一步：获取绑定用途的 SIWE 消息（address 为要绑定的钱包）
+export const getBindNonce = (address) => {
+  return request({ url: '/user/bind-nonce', method: 'get', params: { address } })
+}
+
+// 绑定钱包第二步：提交目标钱包的签名
+export const bindAddress = (message, signature) => {
+  return request({ url: '/user/bind-address', method: 'post', data: { message, signature } })
+}
+
+// 解绑当前账号的钱包地址
+export const unbindAddress = () => {
+  return request({ url: '/user/unbind-address', method: 'post' })
+}
+
 // 同步链上学分到后端
 export const syncCredit = () => {
   return request({
@@ -33,32 +67,235 @@ export const recordCredit = (data) => {
     method: 'post',
     data: {
       student_address: data.student_address,
-      course_name: data.course_name,
+      course_id: data.course_id,
+      term_id: data.term_id,
       score: data.score
     }
   })
 }
 
+// 教师自签录入第一步：获取 recordCredit 未签名交易（from 为教师绑定地址）
+export const prepareRecordCredit = (data) => {
+  return request({ url: '/credit/record/prepare', method: 'post', data })
+}
+
+// 教师自签录入第二步：提交钱包签名的原始交易或已发送交易哈希（data: { raw_tx, term_id } 或 { tx_hash, term_id }）
+export const submitRecordCredit = (data) => {
+  return request({ url: '/credit/record/submit', method: 'post', data })
+}
+
+// 查询异步录入任务进度（status: submitted / linked / failed）
+export const getCreditJob = (jobId) => {
+  return request({ url: `/credit/job/${jobId}`, method: 'get' })
+}
+
 // 管理员：待审核学分列表
 export const getCreditPending = () => {
   return request({ url: '/credit/pending', method: 'get' })
 }
 
 // 管理员：审核通过（credit_id 为数据库主键 id）
-export const approveCredit = (creditId) => {
-  return request({ url: '/credit/approve', method: 'post', data: { credit_id: creditId } })
+export const approveCredit = (creditId, comment) => {
+  return request({ url: '/credit/approve', method: 'post', data: { credit_id: creditId, comment } })
+}
+
+// 管理员：驳回（reason 必填，链上记录其 keccak256；comment 可选，不填以原因记入时间线）
+export const rejectCredit = (creditId, reason, comment) => {
+  return request({ url: '/credit/reject', method: 'post', data: { credit_id: creditId, reason, comment } })
 }
 
-// 管理员：驳回
-export const rejectCredit = (creditId) => {
-  return request({ url: '/credit/reject', method: 'post', data: { credit_id: creditId } })
+// 管理员：批量审核（comment 必填，整批共用），返回逐条结果
+export const approveCreditBatch = (creditIds, comment) => {
+  return request({ url: '/credit/approve/batch', method: 'post', data: { credit_ids: creditIds, comment } })
 }
 
-// 管理员：分配角色（后端调合约）
+// 管理员：批量驳回（reason 必填，整批共用），返回逐条结果
+export const rejectCreditBatch = (creditIds, reason, comment) => {
+  return request({ url: '/credit/reject/batch', method: 'post', data: { credit_ids: creditIds, reason, comment } })
+}
+
+// 管理员：撤销已审核通过的学分（reason 必填，链上记录其 keccak256）
+export const revokeCredit = (creditId, reason, comment) => {
+  return request({ url: '/credit/revoke', method: 'post', data: { credit_id: creditId, reason, comment } })
+}
+
+// 教师：对本人录入的学分提出更正（data: { credit_id, course_id?, score?, reason }）
+export const createCreditCorrection = (data) => {
+  return request({ url: '/credit/correction', method: 'post', data })
+}
+
+// 更正申请列表（params: status）
+export const getCreditCorrections = (params) => {
+  return request({ url: '/credit/corrections', method: 'get', params })
+}
+
+// 管理员：同意更正（comment 必填），上链生成取代原记录的新记录
+export const approveCreditCorrection = (id, comment) => {
+  return request({ url: `/credit/correction/${id}/approve`, method: 'post', data: { comment } })
+}
+
+// 管理员：拒绝更正（comment 必填）
+export const rejectCreditCorrection = (id, comment) => {
+  return request({ url: `/credit/correction/${id}/reject`, method: 'post', data: { comment } })
+}
+
+// 学分时间线（录入、审核、驳回等每次流转，versions 为更正形成的全部版本）
+export const getCreditTimeline = (creditId) => {
+  return request({ url: `/credit/timeline/${creditId}`, method: 'get' })
+}
+
+// 管理员：分配角色（后端调合约，返回 change_id，打包后生效）
 export const assignRole = (user_address, role) => {
   return request({
     url: '/role/assign',
     method: 'post',
     data: { user_address, role }
   })
-}
\ No newline at end of file
+}
+
+// 管理员：撤销链上角色（role: teacher / admin，reason 必填）
+export const revokeRole = (user_address, role, reason) => {
+  return request({ url: '/role/revoke', method: 'post', data: { user_address, role, reason } })
+}
+
+// 角色分配/撤销记录（params: user_address, limit）
+export const getRoleChanges = (params) => {
+  return request({ url: '/role/changes', method: 'get', params })
+}
+
+// 查询角色变更进度（status: submitted / applied / failed）
+export const getRoleChange = (changeId) => {
+  return request({ url: `/role/change/${changeId}`, method: 'get' })
+}
+
+// 角色漂移报告：本地角色与链上不一致的用户
+export const getRoleDrift = () => {
+  return request({ url: '/role/drift', method: 'get' })
+}
+
+// 修复单个用户的角色漂移（source: chain 以链上为准 / db 以数据库为准）
+export const repairRoleDrift = (userId, source) => {
+  return request({ url: `/role/drift/${userId}/repair`, method: 'post', data: { source } })
+}
+// 查询后端发出的链上交易状态（submitted / mined / reverted / replaced / failed，需 tx:read）
+export const getTx = (hash) => {
+  return request({ url: `/tx/${hash}`, method: 'get' })
+}
+
+// 钱包签名模式：提交钱包签好的原始交易（data: { raw_tx }，revokeRole / rejectCredit / revokeCredit 另需 reason，approveCredit 另需 comment）
+export const submitSignedTx = (data) => {
+  return request({ url: '/tx/submit', method: 'post', data })
+}
+
+// 管理员：链上交易台账列表（params: status, page, size）
+export const getTxList = (params) => {
+  return request({ url: '/tx/list', method: 'get', params })
+}
+
+// 课程目录（params: keyword, department），录入学分时选择
+export const getCourses = (params) => {
+  return request({ url: '/courses', method: 'get', params })
+}
+
+// 课程目录维护（需 course:manage；课程代码创建后不可修改）
+export const createCourse = (data) => {
+  return request({ url: '/courses', method: 'post', data })
+}
+
+export const updateCourse = (id, data) => {
+  return request({ url: `/courses/${id}`, method: 'put', data })
+}
+
+export const deleteCourse = (id) => {
+  return request({ url: `/courses/${id}`, method: 'delete' })
+}
+
+// 学期列表（params: status），录入学分时选择
+export const getTerms = (params) => {
+  return request({ url: '/terms', method: 'get', params })
+}
+
+// 学期维护（需 term:manage；日期格式 YYYY-MM-DD）
+export const createTerm = (data) => {
+  return request({ url: '/terms', method: 'post', data })
+}
+
+export const updateTerm = (id, data) => {
+  return request({ url: `/terms/${id}`, method: 'put', data })
+}
+
+// 关闭学期：该学期学分全部锁定，不可重新开启
+export const closeTerm = (id) => {
+  return request({ url: `/terms/${id}/close`, method: 'post' })
+}
+
+export const getTermOverrides = (id) => {
+  return request({ url: `/terms/${id}/overrides`, method: 'get' })
+}
+
+// 为教师开通窗口外补录（data: { teacher_id, reason, hours }）
+export const createTermOverride = (id, data) => {
+  return request({ url: `/terms/${id}/overrides`, method: 'post', data })
+}
+
+export const revokeTermOverride = (id, overrideId) => {
+  return request({ url: `/terms/${id}/overrides/${overrideId}`, method: 'delete' })
+}
+
+// 批量导入选课（rows: [{ term_code, course_code, student_address }]），返回逐行结果（需 enrollment:manage）
+export const importEnrollments = (rows) => {
+  return request({ url: '/enrollments/import', method: 'post', data: { rows } })
+}
+
+// 选课名单（params: term_id, course_id）
+export const getEnrollments = (params) => {
+  return request({ url: '/enrollments', method: 'get', params })
+}
+
+export const deleteEnrollment = (id) => {
+  return request({ url: `/enrollments/${id}`, method: 'delete' })
+}
+
+// 批量导入任课安排（rows: [{ term_code, course_code, teacher_username }]），返回逐行结果（需 enrollment:manage）
+export const importTeachingAssignments = (rows) => {
+  return request({ url: '/teaching-assignments/import', method: 'post', data: { rows } })
+}
+
+// 任课安排（params: term_id, course_id）
+export const getTeachingAssignments = (params) => {
+  return request({ url: '/teaching-assignments', method: 'get', params })
+}
+
+export const deleteTeachingAssignment = (id) => {
+  return request({ url: `/teaching-assignments/${id}`, method: 'delete' })
+}
+
+// 角色定义与权限管理（需 role:manage）
+export const getPermissions = () => {
+  return request({ url: '/permissions', method: 'get' })
+}
+
+export const getRoleDefs = () => {
+  return request({ url: '/roles', method: 'get' })
+}
+
+export const createRoleDef = (data) => {
+  return request({ url: '/roles', method: 'post', data })
+}
+
+export const updateRoleDef = (name, data) => {
+  return request({ url: `/roles/${name}`, method: 'put', data })
+}
+
+export const deleteRoleDef = (name) => {
+  return request({ url: `/roles/${name}`, method: 'delete' })
+}
+
+// 为其他用户设置本地角色（需 role:manage，原因必填）
+export const setUserRole = (id, data) => {
+  return request({ url: `/users/${id}/role`, method: 'post', data })
+}
+
+export const getUserRoleLogs = (id) => {
+  return request({ url: `/users/${id}/role-logs`, method: 'get' })
+}
@@ -35,9 +35,28 @@ service.interceptors.response.use(
     }
     return res
   },
-  (error) => {
+  async (error) => {
     const status = error.response?.status
     const data = error.response?.data
+    // 访问 Token 过期：用刷新 Token 换新后重试一次，失败则回到登录页
+    const original = error.config
+    if (status === 401 && original && !original._retried && !original.url.startsWith('/user/refresh')) {
+      const userStore = useUserStore()
+      if (userStore.refreshToken) {
+        original._retried = true
+        try {
+          const res = await axios.post('/api/user/refresh', { refresh_token: userStore.refreshToken })
+          if (res.data?.code === 200) {
+            userStore.setTokens(res.data.data.token, res.data.data.refresh_token)
+            original.headers['Authorization'] = `Bearer ${res.data.data.token}`
+            return service(original)
+          }
+        } catch (_) {}
+        userStore.logout()
+        window.location.href = '/login'
+        return Promise.reject(error)
+      }
+    }
     // 500 时把完整响应打到控制台，便于排查
     if (status === 500) {
       console.error('500 响应 body：', typeof data === 'object' ? JSON.stringify(data) : data)
@@ -53,6 +53,15 @@ const routes = [
   }
 ]
 
+// 按权限选择前端门户：可审核/查看全部 → 管理端，可录入 → 教师端，其余 → 学生端
+export const portalOf = (permissions = [], role = '') => {
+  const has = (p) => permissions.includes('*') || permissions.includes(p)
+  if (has('credit:approve') || has('credit:read_all') || has('role:manage')) return 'admin'
+  if (has('credit:record')) return 'teacher'
+  if (permissions.length === 0 && ['admin', 'teacher'].includes(role)) return role
+  return 'student'
+}
+
 // 创建路由实例
 const router = createRouter({
   history: createWebHistory(),
@@ -69,10 +78,11 @@ router.beforeEach((to, from, next) => {
     if (!userStore.isLogin) {
       next('/login')
     } else {
-      if (userStore.role === to.meta.role) {
+      const portal = portalOf(userStore.permissions, userStore.role)
+      if (portal === to.meta.role) {
         next()
       } else {
-        next(`/${userStore.role}`)
+        next(`/${portal}`)
       }
     }
   } else {
@@ -4,33 +4,48 @@ import { defineStore } from 'pinia'
 export const useUserStore = defineStore('user', {
   state: () => ({
     address: '', // 钱包地址
-    role: '', // student/teacher/admin
+    role: '', // 角色名（student/teacher/admin/teacher_admin/super_admin/auditor/credit_reviewer 或自定义）
+    permissions: [], // 角色权限点，决定进入哪个门户
     isLogin: false,
-    jwtToken: ''
+    jwtToken: '',
+    refreshToken: ''
   }),
   actions: {
     login(userInfo) {
       this.address = userInfo.address
       this.role = userInfo.role
+      this.permissions = userInfo.permissions || []
       this.isLogin = true
       this.jwtToken = userInfo.jwtToken
+      this.refreshToken = userInfo.refreshToken || ''
       localStorage.setItem('userInfo', JSON.stringify(userInfo))
     },
     logout() {
       this.address = ''
       this.role = ''
+      this.permissions = []
       this.isLogin = false
       this.jwtToken = ''
+      this.refreshToken = ''
       localStorage.removeItem('userInfo')
     },
+    // 刷新 Token 后更新（刷新 Token 每次轮换）
+    setTokens(jwtToken, refreshToken) {
+      this.jwtToken = jwtToken
+      this.refreshToken = refreshToken
+      const info = JSON.parse(localStorage.getItem('userInfo') || '{}')
+      localStorage.setItem('userInfo', JSON.stringify({ ...info, jwtToken, refreshToken }))
+    },
     restoreUserInfo() {
       const userInfo = localStorage.getItem('userInfo')
       if (userInfo) {
         const info = JSON.parse(userInfo)
         this.address = info.address
         this.role = info.role
+        this.permissions = info.permissions || []
         this.isLogin = true
         this.jwtToken = info.jwtToken
+        this.refreshToken = info.refreshToken || ''
       }
     }
   }